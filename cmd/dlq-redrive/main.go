@@ -0,0 +1,111 @@
+// dlq-redrive возвращает сообщения из dead-letter топика в основной топик заказов.
+//
+//	go run ./cmd/dlq-redrive -limit 100
+//	go run ./cmd/dlq-redrive -dry-run
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/Avito-courses/course-go-avito-israpilovsha/internal/kafka"
+	"github.com/Avito-courses/course-go-avito-israpilovsha/internal/server/config"
+	"github.com/Avito-courses/course-go-avito-israpilovsha/internal/worker"
+	"github.com/Avito-courses/course-go-avito-israpilovsha/pkg/logger"
+	"github.com/IBM/sarama"
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	_ = godotenv.Load()
+
+	log := logger.New()
+	defer func() { _ = log.Sync() }()
+
+	// подключение (SASL, TLS, версия, client id) берётся из тех же env, что и у сервиса
+	kafkaEnv := config.LoadKafka()
+
+	var defaultTopic string
+	if len(kafkaEnv.Topics) > 0 {
+		defaultTopic = kafkaEnv.Topics[0]
+	}
+
+	var (
+		brokers = flag.String("brokers", os.Getenv("KAFKA_BROKERS"), "comma separated Kafka brokers")
+		dlq     = flag.String("dlq-topic", os.Getenv("KAFKA_DLQ_TOPIC"), "dead-letter topic to read from")
+		topic   = flag.String("topic", defaultTopic, "topic to publish messages back to")
+		group   = flag.String("group", os.Getenv("KAFKA_GROUP_ID")+"-dlq-redrive", "consumer group for redrive progress")
+		limit   = flag.Int("limit", 0, "max messages to redrive, 0 — all")
+		idle    = flag.Duration("idle", 10*time.Second, "stop after no new messages for this long")
+		dryRun  = flag.Bool("dry-run", false, "only print messages, do not publish or commit")
+	)
+	flag.Parse()
+
+	if *brokers == "" || *dlq == "" || *topic == "" {
+		log.Fatal("brokers, dlq-topic and topic are required")
+	}
+	if *dlq == *topic {
+		log.Fatal("dlq-topic must differ from topic")
+	}
+
+	kafkaCfg, err := kafka.NewConfig(kafkaEnv)
+	if err != nil {
+		log.Fatalf("invalid Kafka config: %v", err)
+	}
+	kafkaCfg.Consumer.Offsets.Initial = sarama.OffsetOldest
+	kafkaCfg.Producer.Return.Successes = true
+	kafkaCfg.Producer.RequiredAcks = sarama.WaitForAll
+
+	brokerList := strings.Split(*brokers, ",")
+
+	producer, err := sarama.NewSyncProducer(brokerList, kafkaCfg)
+	if err != nil {
+		log.Fatalf("producer init failed: %v", err)
+	}
+	defer func() { _ = producer.Close() }()
+
+	consumerGroup, err := sarama.NewConsumerGroup(brokerList, *group, kafkaCfg)
+	if err != nil {
+		log.Fatalf("consumer group init failed: %v", err)
+	}
+	defer func() { _ = consumerGroup.Close() }()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	redriver := worker.NewDLQRedriver(producer, *topic, *limit, *dryRun, stop, log)
+
+	go func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if redriver.IdleFor() >= *idle {
+					log.Infow("dlq is drained", "idle", *idle)
+					stop()
+					return
+				}
+			}
+		}
+	}()
+
+	for ctx.Err() == nil {
+		err := consumerGroup.Consume(ctx, []string{*dlq}, redriver)
+		if err != nil && !errors.Is(err, sarama.ErrClosedConsumerGroup) {
+			log.Errorw("dlq consume failed", "err", err)
+			stop()
+		}
+	}
+
+	log.Infow("dlq redrive finished", "redriven", redriver.Redriven(), "dry_run", *dryRun)
+}
@@ -0,0 +1,126 @@
+// fake-order-service — order-сервис в памяти для локальной разработки и e2e-тестов.
+//
+//	go run ./cmd/fake-order-service -addr :8081 -seed 10
+//	go run ./cmd/fake-order-service -error-rate 0.3 -latency 200ms -kafka
+//
+// Заказы создаются и меняют статус через /admin (см. README), сбои настраиваются флагами
+// или на лету через PUT /admin/chaos.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/joho/godotenv"
+
+	"github.com/Avito-courses/course-go-avito-israpilovsha/internal/fakeorder"
+	"github.com/Avito-courses/course-go-avito-israpilovsha/internal/kafka"
+	"github.com/Avito-courses/course-go-avito-israpilovsha/internal/server/config"
+	"github.com/Avito-courses/course-go-avito-israpilovsha/pkg/logger"
+)
+
+func main() {
+	_ = godotenv.Load()
+
+	log := logger.New()
+	defer func() { _ = log.Sync() }()
+
+	kafkaEnv := config.LoadKafka()
+
+	var defaultTopic string
+	if len(kafkaEnv.Topics) > 0 {
+		defaultTopic = kafkaEnv.Topics[0]
+	}
+
+	var (
+		addr       = flag.String("addr", envOr("FAKE_ORDER_ADDR", ":8081"), "listen address")
+		seed       = flag.Int("seed", 0, "orders to create on start")
+		noBatch    = flag.Bool("no-batch", false, "disable batch status endpoint")
+		latency    = flag.Duration("latency", 0, "delay of every public API response")
+		jitter     = flag.Duration("jitter", 0, "random extra delay up to this value")
+		errorRate  = flag.Float64("error-rate", 0, "share of public API requests failing with -error-code, 0..1")
+		errorCode  = flag.Int("error-code", http.StatusServiceUnavailable, "HTTP status of injected failures")
+		retryAfter = flag.Duration("retry-after", 0, "Retry-After of injected failures, 0 — none")
+		withKafka  = flag.Bool("kafka", false, "publish order events to Kafka")
+		brokers    = flag.String("brokers", os.Getenv("KAFKA_BROKERS"), "comma separated Kafka brokers")
+		topic      = flag.String("topic", defaultTopic, "topic for order events")
+		precision  = flag.Duration("created-at-precision", time.Second,
+			"round order created_at down to this value so orders share timestamps, 0 — no rounding")
+	)
+	flag.Parse()
+
+	opts := []fakeorder.Option{fakeorder.WithChaos(fakeorder.Chaos{
+		Latency:    *latency,
+		Jitter:     *jitter,
+		ErrorRate:  *errorRate,
+		ErrorCode:  *errorCode,
+		RetryAfter: *retryAfter,
+	})}
+	if *noBatch {
+		opts = append(opts, fakeorder.WithoutBatch())
+	}
+
+	if *withKafka {
+		if *brokers == "" || *topic == "" {
+			log.Fatal("brokers and topic are required with -kafka")
+		}
+
+		kafkaCfg, err := kafka.NewConfig(kafkaEnv)
+		if err != nil {
+			log.Fatalf("invalid Kafka config: %v", err)
+		}
+		kafkaCfg.Producer.Return.Successes = true
+		kafkaCfg.Producer.RequiredAcks = sarama.WaitForAll
+
+		producer, err := sarama.NewSyncProducer(strings.Split(*brokers, ","), kafkaCfg)
+		if err != nil {
+			log.Fatalf("producer init failed: %v", err)
+		}
+		defer func() { _ = producer.Close() }()
+
+		opts = append(opts, fakeorder.WithEvents(fakeorder.NewKafkaPublisher(producer, *topic)))
+	}
+
+	store := fakeorder.NewStore(fakeorder.WithCreatedAtPrecision(*precision))
+	for range *seed {
+		if _, err := store.Create(""); err != nil {
+			log.Fatalf("seed orders: %v", err)
+		}
+	}
+
+	srv := &http.Server{
+		Addr:              *addr,
+		Handler:           fakeorder.NewServer(store, log, opts...).Handler(),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+
+	log.Infow("fake order service started", "addr", *addr, "seeded", *seed, "kafka", *withKafka)
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatalf("listen: %v", err)
+	}
+}
+
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
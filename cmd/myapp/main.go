@@ -2,10 +2,13 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"net"
 	"net/http"
 	_ "net/http/pprof"
+	"os"
 	"os/signal"
 	"syscall"
 	"time"
@@ -14,16 +17,21 @@ import (
 	courierRepo "github.com/Avito-courses/course-go-avito-israpilovsha/internal/courier/repository"
 	courierUsecase "github.com/Avito-courses/course-go-avito-israpilovsha/internal/courier/usecase"
 	"github.com/Avito-courses/course-go-avito-israpilovsha/internal/gateway/order"
+	"github.com/Avito-courses/course-go-avito-israpilovsha/internal/kafka"
 	"github.com/Avito-courses/course-go-avito-israpilovsha/internal/metrics"
 	"github.com/Avito-courses/course-go-avito-israpilovsha/internal/middleware"
 	"github.com/IBM/sarama"
 	"github.com/gorilla/mux"
 	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
 
 	"github.com/Avito-courses/course-go-avito-israpilovsha/internal/db"
 	deliveryHandler "github.com/Avito-courses/course-go-avito-israpilovsha/internal/delivery/handler"
 	deliveryRepo "github.com/Avito-courses/course-go-avito-israpilovsha/internal/delivery/repository"
 	deliveryUsecase "github.com/Avito-courses/course-go-avito-israpilovsha/internal/delivery/usecase"
+	outboxRepo "github.com/Avito-courses/course-go-avito-israpilovsha/internal/outbox/repository"
 	"github.com/Avito-courses/course-go-avito-israpilovsha/internal/server/config"
 	"github.com/Avito-courses/course-go-avito-israpilovsha/internal/worker"
 	"github.com/Avito-courses/course-go-avito-israpilovsha/pkg/logger"
@@ -42,21 +50,62 @@ func main() {
 
 	courierRepository := courierRepo.NewCourierRepository(database)
 	deliveryRepository := deliveryRepo.NewDeliveryRepository(database)
+	pendingRepository := deliveryRepo.NewPendingOrderRepository(database)
 
-	courierService := courierUsecase.NewCourierService(courierRepository)
+	deadlinePolicyService := deliveryUsecase.NewDeadlinePolicyService(
+		deliveryRepo.NewDeadlinePolicyRepository(database),
+		log,
+	)
+
+	strategy, err := deliveryUsecase.NewAssignmentStrategy(cfg.Delivery.AssignmentStrategy)
+	if err != nil {
+		log.Fatalf("invalid assignment strategy: %v", err)
+	}
+
+	deliveryOpts := []deliveryUsecase.Option{
+		deliveryUsecase.WithPendingQueue(pendingRepository),
+		deliveryUsecase.WithStrategy(strategy),
+		deliveryUsecase.WithLoadWindow(cfg.Delivery.LoadWindow),
+		deliveryUsecase.WithDeadlines(deadlinePolicyService),
+		deliveryUsecase.WithExpiredReassign(cfg.Delivery.ReassignExpired),
+		deliveryUsecase.WithLogger(log),
+	}
+	var (
+		courierOpts  []courierUsecase.Option
+		completeOpts []deliveryUsecase.CompleteOption
+	)
+
+	// события пишутся в outbox, только если задан топик для их публикации
+	var outboxRepository outboxRepo.OutboxRepository
+	if cfg.Outbox.Topic != "" {
+		outboxRepository = outboxRepo.NewOutboxRepository(database)
+
+		deliveryOpts = append(deliveryOpts, deliveryUsecase.WithOutbox(outboxRepository))
+		courierOpts = append(courierOpts, courierUsecase.WithOutbox(outboxRepository))
+		completeOpts = append(completeOpts, deliveryUsecase.CompleteWithOutbox(outboxRepository))
+	}
 
 	deliveryService := deliveryUsecase.NewDeliveryService(
 		courierRepository,
 		deliveryRepository,
+		deliveryOpts...,
+	)
+
+	// освобождение курьера будит разбор очереди ожидающих заказов
+	courierService := courierUsecase.NewCourierService(
+		courierRepository,
+		append(courierOpts, courierUsecase.WithNotifiers(deliveryService))...,
 	)
 
 	completeService := deliveryUsecase.NewCompleteService(
 		deliveryRepository,
 		courierRepository,
+		append(completeOpts, deliveryUsecase.CompleteNotifies(deliveryService))...,
 	)
 
 	courierH := courierHandler.NewHandler(courierService, log)
-	deliveryH := deliveryHandler.NewHandler(deliveryService, log)
+	deliveryH := deliveryHandler.NewHandler(deliveryService, completeService, log)
+	deadlinePolicyH := deliveryHandler.NewDeadlinePolicyHandler(deadlinePolicyService, log)
 
 	metrics.Register()
 
@@ -71,16 +120,77 @@ func main() {
 	courierHandler.RegisterCourierRoutes(r, courierH)
 	deliveryHandler.RegisterDeliveryRoutes(r, deliveryH)
 
+	if cfg.AdminToken == "" {
+		log.Warn("ADMIN_TOKEN is not set, admin endpoints reject all requests")
+	}
+	admin := r.PathPrefix("/admin").Subrouter()
+	admin.Use(middleware.AdminAuth(cfg.AdminToken, log))
+	deliveryHandler.RegisterDeadlinePolicyRoutes(admin, deadlinePolicyH)
+
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
 	go deliveryService.StartAutoRelease(ctx, cfg.Delivery.TickerInterval)
+	go deliveryService.StartQueueDrainer(ctx, cfg.Delivery.QueueDrainInterval)
+	go deadlinePolicyService.StartReload(ctx, cfg.Delivery.DeadlinePolicyReloadInterval)
+
+	// общий producer для outbox и dead-letter топика
+	var producer sarama.SyncProducer
+	if outboxRepository != nil || (cfg.Kafka.Enabled && cfg.Kafka.DLQTopic != "") {
+		producer, err = newSyncProducer(cfg.Kafka)
+		if err != nil {
+			log.Fatalf("Kafka producer init failed: %v", err)
+		}
+		defer func() { _ = producer.Close() }()
+	}
+
+	if outboxRepository != nil {
+		relay := worker.NewOutboxRelay(outboxRepository, producer, worker.OutboxRelayConfig{
+			Topic:        cfg.Outbox.Topic,
+			BatchSize:    cfg.Outbox.BatchSize,
+			PollInterval: cfg.Outbox.PollInterval,
+			Retention:    cfg.Outbox.Retention,
+		}, log)
+
+		go relay.Run(ctx)
+		log.Info("Outbox relay started", zap.String("topic", cfg.Outbox.Topic))
+	}
+
+	orderGateway, closeGateway, err := newOrderGateway(cfg, log)
+	if err != nil {
+		log.Fatalf("order gateway init failed: %v", err)
+	}
+	defer closeGateway()
+	// метрики снимаются с самих вызовов order-сервиса, без отказов breaker и попаданий в кэш
+	orderGateway = order.NewInstrumentedGateway(orderGateway)
+	if cfg.OrderBreaker.Enabled {
+		orderGateway = order.NewCircuitBreaker(orderGateway, order.BreakerConfig{
+			FailureRatio:     cfg.OrderBreaker.FailureRatio,
+			MinRequests:      cfg.OrderBreaker.MinRequests,
+			Window:           cfg.OrderBreaker.Window,
+			CoolDown:         cfg.OrderBreaker.CoolDown,
+			HalfOpenRequests: cfg.OrderBreaker.HalfOpenRequests,
+		}, log)
+	}
+	if cfg.OrderCache.Enabled {
+		// кэш снаружи breaker: закэшированные статусы отдаются и при недоступном order-сервисе
+		// общий вызов не отменяется вместе с первым вызвавшим, поэтому ограничен бюджетом повторов
+		orderGateway = order.NewCachingGateway(orderGateway, order.CacheConfig{
+			StatusTTL:   cfg.OrderCache.StatusTTL,
+			NotFoundTTL: cfg.OrderCache.NotFoundTTL,
+			OrdersTTL:   cfg.OrderCache.OrdersTTL,
+			MaxEntries:  cfg.OrderCache.MaxEntries,
+			CallTimeout: cfg.OrderClient.RetryBudget,
+		})
+	}
 
 	// Kafka consumer
+	var consumer *worker.KafkaConsumer
 	if cfg.Kafka.Enabled {
-		kafkaCfg := sarama.NewConfig()
-		kafkaCfg.Version = sarama.V2_6_0_0
-		kafkaCfg.Consumer.Offsets.Initial = sarama.OffsetNewest
+		kafkaCfg, err := kafka.NewConfig(cfg.Kafka)
+		if err != nil {
+			log.Fatalf("invalid Kafka config: %v", err)
+		}
 
 		group, err := sarama.NewConsumerGroup(
 			cfg.Kafka.Brokers,
@@ -91,20 +201,40 @@ func main() {
 			log.Error("Kafka consumer group init failed", zap.Error(err))
 			stop()
 		} else {
-			orderGateway := order.WithLogger(order.NewHTTPGateway(cfg.OrderServiceHost), log)
+			eventMode, err := worker.ParseEventMode(cfg.Kafka.EventMode)
+			if err != nil {
+				log.Fatalf("invalid order event mode: %v", err)
+			}
 
 			processor := worker.NewOrderEventProcessor(
 				deliveryService,
 				deliveryService,
 				completeService,
 				orderGateway,
+				worker.WithProcessedEvents(deliveryRepo.NewProcessedEventRepository(database)),
+				worker.WithEventMode(eventMode),
+				worker.WithOrderVersions(deliveryRepo.NewOrderVersionRepository(database)),
+				worker.WithProcessorLogger(log),
 			)
+			go processor.StartCleanup(ctx, cfg.Kafka.ProcessedRetention)
+
+			consumerOpts := []worker.ConsumerOption{
+				worker.WithRetry(cfg.Kafka.MaxAttempts, cfg.Kafka.RetryBackoff, cfg.Kafka.MaxRetryBackoff),
+				worker.WithConcurrency(cfg.Kafka.Concurrency),
+				worker.WithCircuitPause(cfg.OrderBreaker.CoolDown),
+			}
+
+			if cfg.Kafka.DLQTopic != "" {
+				consumerOpts = append(consumerOpts,
+					worker.WithDeadLetter(worker.NewKafkaDeadLetter(producer, cfg.Kafka.DLQTopic)),
+				)
+			}
 
-			handler := worker.NewOrderConsumer(processor, log)
+			handler := worker.NewOrderConsumer(processor, log, consumerOpts...)
 
-			consumer := worker.NewKafkaConsumer(
+			consumer = worker.NewKafkaConsumer(
 				group,
-				cfg.Kafka.Topic,
+				cfg.Kafka.Topics,
 				handler,
 				log,
 			)
@@ -114,6 +244,24 @@ func main() {
 		}
 	}
 
+	// опрос order-сервиса — запасной путь, когда Kafka выключена или недоступна
+	if cfg.OrderFetcher.Mode != config.FetcherOff {
+		fetcherOpts := []worker.FetcherOption{
+			worker.WithFetchPeriod(cfg.OrderFetcher.Period),
+			worker.WithFetchCursor(deliveryRepo.NewFetchCursorRepository(database)),
+		}
+
+		if cfg.OrderFetcher.Mode == config.FetcherFallback && consumer != nil {
+			fetcherOpts = append(fetcherOpts, worker.WithFetchCondition(func() bool {
+				return consumer.UnhealthyFor() > cfg.OrderFetcher.FallbackAfter
+			}, cfg.OrderFetcher.FallbackAfter))
+		}
+
+		fetcher := worker.NewOrderFetcher(orderGateway, deliveryService, log, fetcherOpts...)
+		go fetcher.Run(ctx)
+		log.Info("Order fetcher started", zap.String("mode", cfg.OrderFetcher.Mode))
+	}
+
 	srv := &http.Server{
 		Addr:    fmt.Sprintf(":%s", cfg.Port),
 		Handler: r,
@@ -155,3 +303,96 @@ func main() {
 
 	_ = srv.Shutdown(shutdownCtx)
 }
+
+// newSyncProducer создаёт идемпотентный producer: ретраи не дублируют и не переставляют сообщения
+func newSyncProducer(cfg config.KafkaConfig) (sarama.SyncProducer, error) {
+	producerCfg, err := kafka.NewConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	producerCfg.Producer.Return.Successes = true
+	producerCfg.Producer.RequiredAcks = sarama.WaitForAll
+	producerCfg.Producer.Idempotent = true
+	producerCfg.Net.MaxOpenRequests = 1
+
+	return sarama.NewSyncProducer(cfg.Brokers, producerCfg)
+}
+
+// newOrderGateway создаёт клиент order-сервиса по выбранному протоколу; cleanup закрывает соединение
+func newOrderGateway(cfg *config.Config, log *zap.SugaredLogger) (order.Gateway, func(), error) {
+	opts := []order.Option{
+		order.WithTimeout(cfg.OrderClient.Timeout),
+		order.WithRetryPolicy(order.RetryPolicy{
+			MaxAttempts: cfg.OrderClient.MaxAttempts,
+			BaseDelay:   cfg.OrderClient.BaseDelay,
+			MaxDelay:    cfg.OrderClient.MaxDelay,
+			Jitter:      cfg.OrderClient.Jitter,
+			Budget:      cfg.OrderClient.RetryBudget,
+		}),
+		order.WithPageSize(cfg.OrderClient.PageSize),
+		order.WithBatch(cfg.OrderClient.BatchSize, cfg.OrderClient.Concurrency),
+	}
+	if signer := newOrderSigner(cfg.OrderClient.Auth); signer != nil {
+		opts = append(opts, order.WithSigner(signer))
+	}
+
+	if cfg.OrderClient.Transport != config.OrderTransportGRPC {
+		return order.WithLogger(order.NewHTTPGateway(cfg.OrderServiceHost, opts...), log), func() {}, nil
+	}
+
+	creds := insecure.NewCredentials()
+	if cfg.OrderClient.GRPCTLS {
+		tlsCfg, err := newOrderTLSConfig(cfg.OrderClient.GRPCCAFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		creds = credentials.NewTLS(tlsCfg)
+	}
+
+	conn, err := grpc.NewClient(cfg.OrderClient.GRPCAddr, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, nil, err
+	}
+	return order.WithLogger(order.NewGRPCGateway(conn, opts...), log), func() { _ = conn.Close() }, nil
+}
+
+// newOrderTLSConfig — TLS до order-сервиса; caFile пуст — системные корневые сертификаты
+func newOrderTLSConfig(caFile string) (*tls.Config, error) {
+	tlsCfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if caFile == "" {
+		return tlsCfg, nil
+	}
+
+	pem, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("read order service CA: %w", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("order service CA %s: no certificates found", caFile)
+	}
+	tlsCfg.RootCAs = pool
+	return tlsCfg, nil
+}
+
+// newOrderSigner выбирает аутентификацию вызовов order-сервиса; nil — без авторизации
+func newOrderSigner(cfg config.OrderAuthConfig) order.Signer {
+	switch cfg.Mode {
+	case config.OrderAuthBearer:
+		return order.BearerToken(cfg.Token)
+	case config.OrderAuthOAuth2:
+		return order.NewClientCredentials(order.ClientCredentialsConfig{
+			TokenURL:     cfg.TokenURL,
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			Scopes:       cfg.Scopes,
+		})
+	case config.OrderAuthHMAC:
+		return order.NewHMACSigner(cfg.HMACKeyID, []byte(cfg.HMACSecret))
+	case config.OrderAuthBypass:
+		return order.DevBypass{}
+	default:
+		return nil
+	}
+}
@@ -30,12 +30,14 @@ func NewHandler(s CourierService, log *zap.SugaredLogger) *Handler {
 }
 
 const (
-	ErrInvalidID        = "invalid id"
-	ErrInvalidBody      = "invalid request body"
-	ErrNotFound         = "not found"
-	ErrInternal         = "internal error"
-	ErrConflict         = "resource conflict"
-	ErrJSONEncodeFailed = "failed to encode json"
+	ErrInvalidID         = "invalid id"
+	ErrInvalidBody       = "invalid request body"
+	ErrNotFound          = "not found"
+	ErrInternal          = "internal error"
+	ErrConflict          = "resource conflict"
+	ErrInvalidStatus     = "invalid courier status"
+	ErrInvalidTransition = "invalid courier status transition"
+	ErrJSONEncodeFailed  = "failed to encode json"
 )
 
 // отправка статус кода, ответа и установку заголовков в отдельную функцию, дабы избежать дублирования
@@ -110,12 +112,14 @@ func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		h.log.Warnf("Create failed: %v", err)
 
-		if errors.Is(err, repository.ErrConflict) {
+		switch {
+		case errors.Is(err, model.ErrInvalidStatus):
+			respondError(w, http.StatusBadRequest, ErrInvalidStatus)
+		case errors.Is(err, repository.ErrConflict):
 			respondError(w, http.StatusConflict, ErrConflict)
-			return
+		default:
+			respondError(w, http.StatusInternalServerError, ErrInternal)
 		}
-
-		respondError(w, http.StatusInternalServerError, ErrInternal)
 		return
 	}
 
@@ -133,7 +137,14 @@ func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
 	}
 	if err := h.service.Update(r.Context(), &c); err != nil {
 		h.log.Warnf("Update failed for ID %d: %v", c.ID, err)
-		respondError(w, http.StatusNotFound, ErrNotFound)
+		switch {
+		case errors.Is(err, model.ErrInvalidStatus):
+			respondError(w, http.StatusBadRequest, ErrInvalidStatus)
+		case errors.Is(err, model.ErrInvalidTransition):
+			respondError(w, http.StatusConflict, ErrInvalidTransition)
+		default:
+			respondError(w, http.StatusNotFound, ErrNotFound)
+		}
 		return
 	}
 	h.log.Infof("Courier updated: ID=%d", c.ID)
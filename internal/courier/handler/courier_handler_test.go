@@ -12,6 +12,8 @@ import (
 	"github.com/Avito-courses/course-go-avito-israpilovsha/internal/courier/handler"
 	"github.com/Avito-courses/course-go-avito-israpilovsha/internal/courier/model"
 	"github.com/Avito-courses/course-go-avito-israpilovsha/internal/courier/repository"
+	"github.com/Avito-courses/course-go-avito-israpilovsha/internal/courier/usecase"
+	"github.com/golang/mock/gomock"
 	"github.com/gorilla/mux"
 	"go.uber.org/zap"
 )
@@ -138,6 +140,18 @@ func TestCourierHandler_Create(t *testing.T) {
 			},
 			wantStatus: http.StatusConflict,
 		},
+		{
+			name: "invalid status",
+			body: `{"name":"A","phone":"123","status":"sleeping","transport_type":"car"}`,
+			prepareSvc: func() *mockCourierService {
+				return &mockCourierService{
+					CreateFn: func(ctx context.Context, c *model.Courier) error {
+						return model.ErrInvalidStatus
+					},
+				}
+			},
+			wantStatus: http.StatusBadRequest,
+		},
 	}
 
 	for _, tc := range tests {
@@ -262,6 +276,18 @@ func TestCourierHandler_Update(t *testing.T) {
 			prepareSvc: func() *mockCourierService { return &mockCourierService{} },
 			wantStatus: http.StatusBadRequest,
 		},
+		{
+			name: "leaving busy",
+			body: `{"id":7,"status":"available"}`,
+			prepareSvc: func() *mockCourierService {
+				return &mockCourierService{
+					UpdateFn: func(ctx context.Context, c *model.Courier) error {
+						return model.ErrInvalidStatus
+					},
+				}
+			},
+			wantStatus: http.StatusBadRequest,
+		},
 	}
 
 	for _, tc := range tests {
@@ -282,6 +308,28 @@ func TestCourierHandler_Update(t *testing.T) {
 	}
 }
 
+// TestCourierHandler_UpdateRejectsBusy — busy нельзя выставить через PUT,
+// иначе курьер выпадет из назначений без доставки
+func TestCourierHandler_UpdateRejectsBusy(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// репозиторий не должен вызываться
+	repo := repository.NewMockCourierRepository(ctrl)
+	h := handler.NewHandler(usecase.NewCourierService(repo), zap.NewExample().Sugar())
+
+	req := httptest.NewRequest("PUT", "/courier", bytes.NewBufferString(`{"id":7,"status":"busy"}`))
+	w := httptest.NewRecorder()
+
+	h.Update(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
 func TestPing(t *testing.T) {
 	t.Parallel()
 	svc := &mockCourierService{}
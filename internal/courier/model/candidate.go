@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+// Candidate — свободный курьер со статистикой загрузки, по которой стратегия выбирает исполнителя
+type Candidate struct {
+	Courier *Courier
+	// RecentDeliveries — число доставок, назначенных курьеру с начала окна
+	RecentDeliveries int
+	// TotalDeliveries — число доставок курьера за всё время
+	TotalDeliveries int
+	// LastAssignedAt — время последнего назначения; nil, если заказов ещё не было
+	LastAssignedAt *time.Time
+}
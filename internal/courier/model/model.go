@@ -3,11 +3,11 @@ package model
 import "time"
 
 type Courier struct {
-	ID            int64     `json:"id"`
-	Name          string    `json:"name"`
-	Phone         string    `json:"phone"`
-	Status        string    `json:"status"`
-	TransportType string    `json:"transport_type"`
-	CreatedAt     time.Time `json:"created_at,omitempty"`
-	UpdatedAt     time.Time `json:"updated_at,omitempty"`
+	ID            int64         `json:"id"`
+	Name          string        `json:"name"`
+	Phone         string        `json:"phone"`
+	Status        CourierStatus `json:"status"`
+	TransportType string        `json:"transport_type"`
+	CreatedAt     time.Time     `json:"created_at,omitempty"`
+	UpdatedAt     time.Time     `json:"updated_at,omitempty"`
 }
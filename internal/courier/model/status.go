@@ -0,0 +1,101 @@
+package model
+
+import (
+	"errors"
+	"strings"
+)
+
+type CourierStatus string
+
+const (
+	CourierStatusAvailable   CourierStatus = "available"
+	CourierStatusBusy        CourierStatus = "busy"
+	CourierStatusPaused      CourierStatus = "paused"
+	CourierStatusOffline     CourierStatus = "offline"
+	CourierStatusDeactivated CourierStatus = "deactivated"
+)
+
+var (
+	ErrInvalidStatus     = errors.New("invalid courier status")
+	ErrInvalidTransition = errors.New("invalid courier status transition")
+)
+
+// transitions — допустимые переходы статуса курьера.
+// busy выставляется только назначением заказа и снимается только его завершением/отменой,
+// поэтому повторный переход busy -> busy запрещён (это было бы двойное назначение).
+var transitions = map[CourierStatus][]CourierStatus{
+	CourierStatusAvailable: {
+		CourierStatusAvailable,
+		CourierStatusBusy,
+		CourierStatusPaused,
+		CourierStatusOffline,
+		CourierStatusDeactivated,
+	},
+	CourierStatusBusy: {
+		CourierStatusAvailable,
+	},
+	CourierStatusPaused: {
+		CourierStatusPaused,
+		CourierStatusAvailable,
+		CourierStatusOffline,
+		CourierStatusDeactivated,
+	},
+	CourierStatusOffline: {
+		CourierStatusOffline,
+		CourierStatusAvailable,
+		CourierStatusPaused,
+		CourierStatusDeactivated,
+	},
+	CourierStatusDeactivated: {
+		CourierStatusDeactivated,
+	},
+}
+
+func ParseCourierStatus(raw string) (CourierStatus, error) {
+	s := CourierStatus(strings.ToLower(strings.TrimSpace(raw)))
+	if !s.IsValid() {
+		return "", ErrInvalidStatus
+	}
+	return s, nil
+}
+
+func (s CourierStatus) IsValid() bool {
+	_, ok := transitions[s]
+	return ok
+}
+
+func (s CourierStatus) CanTransitionTo(next CourierStatus) bool {
+	for _, allowed := range transitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowedFrom возвращает статусы, из которых можно перейти в s.
+// Используется репозиторием, чтобы проверять переход атомарно в UPDATE.
+func (s CourierStatus) AllowedFrom() []string {
+	from := make([]string, 0, len(transitions))
+	for prev, next := range transitions {
+		for _, n := range next {
+			if n == s {
+				from = append(from, string(prev))
+				break
+			}
+		}
+	}
+	return from
+}
+
+// ManualAllowedFrom — как AllowedFrom, но для смены статуса через API: busy ставится и снимается
+// только назначением и завершением заказа, поэтому из busy вручную выйти нельзя
+func (s CourierStatus) ManualAllowedFrom() []string {
+	from := make([]string, 0, len(transitions))
+	for _, prev := range s.AllowedFrom() {
+		if prev != string(CourierStatusBusy) {
+			from = append(from, prev)
+		}
+	}
+	return from
+}
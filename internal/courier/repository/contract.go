@@ -10,13 +10,17 @@ import (
 )
 
 type CourierRepository interface {
+	WithTx(ctx context.Context, fn func(txCtx context.Context) error) error
+
 	Create(ctx context.Context, c *model.Courier) error
 	GetByID(ctx context.Context, id int64) (*model.Courier, error)
+	LockByID(ctx context.Context, id int64) (*model.Courier, error)
 	GetAll(ctx context.Context) ([]*model.Courier, error)
 	Update(ctx context.Context, c *model.Courier) error
-	FindAvailable(ctx context.Context) (*model.Courier, error)
-	UpdateStatus(ctx context.Context, id int64, status string) error
-	ReleaseExpired(ctx context.Context, now time.Time) (int64, error)
+	ListAvailable(ctx context.Context, since time.Time) ([]*model.Candidate, error)
+	ClaimByID(ctx context.Context, id int64) (*model.Courier, error)
+	ReleaseByID(ctx context.Context, id int64) (bool, error)
+	UpdateStatus(ctx context.Context, id int64, status model.CourierStatus) error
 }
 
 var (
@@ -2,18 +2,15 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"github.com/Avito-courses/course-go-avito-israpilovsha/internal/courier/model"
 	"github.com/Avito-courses/course-go-avito-israpilovsha/internal/db"
-	"github.com/jackc/pgconn"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 )
 
-type txKeyType string
-
-const txKey txKeyType = "tx"
-
 type postgresCourierRepository struct {
 	db *db.Database
 }
@@ -24,9 +21,14 @@ func NewCourierRepository(dbConn *db.Database) CourierRepository {
 	return &postgresCourierRepository{db: dbConn}
 }
 
+var errNoTx = errors.New("courier repository: transaction required")
+
 func getTx(ctx context.Context) (pgx.Tx, bool) {
-	tx, ok := ctx.Value(txKey).(pgx.Tx)
-	return tx, ok
+	return db.TxFromContext(ctx)
+}
+
+func (r *postgresCourierRepository) WithTx(ctx context.Context, fn func(txCtx context.Context) error) error {
+	return r.db.WithTx(ctx, fn)
 }
 
 func (r *postgresCourierRepository) Create(ctx context.Context, c *model.Courier) error {
@@ -72,6 +74,33 @@ func (r *postgresCourierRepository) GetByID(ctx context.Context, id int64) (*mod
 	return c, nil
 }
 
+// LockByID возвращает курьера и блокирует строку до конца транзакции
+func (r *postgresCourierRepository) LockByID(ctx context.Context, id int64) (*model.Courier, error) {
+	const query = `
+		SELECT id, name, phone, status, transport_type, created_at, updated_at
+		FROM couriers WHERE id=$1
+		FOR UPDATE;
+	`
+
+	tx, ok := getTx(ctx)
+	if !ok {
+		return nil, errNoTx
+	}
+
+	c := &model.Courier{}
+	err := tx.QueryRow(ctx, query, id).Scan(
+		&c.ID, &c.Name, &c.Phone, &c.Status, &c.TransportType, &c.CreatedAt, &c.UpdatedAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return c, nil
+}
+
 func (r *postgresCourierRepository) GetAll(ctx context.Context) ([]*model.Courier, error) {
 	query := `
 		SELECT id, name, phone, status, transport_type
@@ -97,6 +126,7 @@ func (r *postgresCourierRepository) GetAll(ctx context.Context) ([]*model.Courie
 	return list, nil
 }
 
+// Update меняет поля курьера; смена статуса проверяется как ручная — busy через неё не снять (ErrInvalidStatus)
 func (r *postgresCourierRepository) Update(ctx context.Context, c *model.Courier) error {
 	query := `
 		UPDATE couriers SET 
@@ -105,38 +135,135 @@ func (r *postgresCourierRepository) Update(ctx context.Context, c *model.Courier
 			status = COALESCE($4, status),
 			transport_type = COALESCE($5, transport_type),
 			updated_at = now()
-		WHERE id = $1;
+		WHERE id = $1
+		  AND ($4::text IS NULL OR status = ANY($6));
 	`
 
-	cmd, err := r.db.Pool.Exec(ctx, query,
-		c.ID, c.Name, c.Phone, c.Status, c.TransportType,
+	var status *string
+	if c.Status != "" {
+		s := string(c.Status)
+		status = &s
+	}
+
+	args := []any{c.ID, c.Name, c.Phone, status, c.TransportType, c.Status.ManualAllowedFrom()}
+
+	var (
+		cmd pgconn.CommandTag
+		err error
 	)
+	if tx, ok := getTx(ctx); ok {
+		cmd, err = tx.Exec(ctx, query, args...)
+	} else {
+		cmd, err = r.db.Pool.Exec(ctx, query, args...)
+	}
 	if err != nil {
 		return err
 	}
 
 	if cmd.RowsAffected() == 0 {
-		return ErrNotFound
+		return r.manualTransitionError(ctx, c.ID)
 	}
 
 	return nil
 }
 
-func (r *postgresCourierRepository) FindAvailable(ctx context.Context) (*model.Courier, error) {
-	query := `
-		SELECT c.id, c.name, c.phone, c.status, c.transport_type
+// manualTransitionError — как transitionError, но для смены статуса через API:
+// занятого курьера нельзя перевести вручную, это ошибка статуса, как и попытка выставить busy
+func (r *postgresCourierRepository) manualTransitionError(ctx context.Context, id int64) error {
+	const query = `SELECT status FROM couriers WHERE id=$1;`
+
+	var status model.CourierStatus
+	var err error
+	if tx, ok := getTx(ctx); ok {
+		err = tx.QueryRow(ctx, query, id).Scan(&status)
+	} else {
+		err = r.db.Pool.QueryRow(ctx, query, id).Scan(&status)
+	}
+	if err == pgx.ErrNoRows {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if status == model.CourierStatusBusy {
+		return model.ErrInvalidStatus
+	}
+	return model.ErrInvalidTransition
+}
+
+// ListAvailable возвращает свободных курьеров со статистикой доставок.
+// RecentDeliveries считается по доставкам, назначенным не раньше since.
+func (r *postgresCourierRepository) ListAvailable(ctx context.Context, since time.Time) ([]*model.Candidate, error) {
+	const query = `
+		SELECT c.id, c.name, c.phone, c.status, c.transport_type,
+		       COUNT(d.id) FILTER (WHERE d.assigned_at >= $2) AS recent,
+		       COUNT(d.id) AS total,
+		       MAX(d.assigned_at) AS last_assigned_at
 		FROM couriers c
 		LEFT JOIN delivery d ON d.courier_id = c.id
-		WHERE c.status = 'available'
-		GROUP BY c.id, c.name, c.phone, c.status, c.transport_type
-		ORDER BY COUNT(d.id) ASC, c.id ASC
-		LIMIT 1;
+		WHERE c.status = $1
+		GROUP BY c.id
+		ORDER BY c.id ASC;
+	`
+
+	var (
+		rows pgx.Rows
+		err  error
+	)
+	if tx, ok := getTx(ctx); ok {
+		rows, err = tx.Query(ctx, query, model.CourierStatusAvailable, since)
+	} else {
+		rows, err = r.db.Pool.Query(ctx, query, model.CourierStatusAvailable, since)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := make([]*model.Candidate, 0)
+	for rows.Next() {
+		c := &model.Candidate{Courier: &model.Courier{}}
+		err := rows.Scan(
+			&c.Courier.ID, &c.Courier.Name, &c.Courier.Phone, &c.Courier.Status, &c.Courier.TransportType,
+			&c.RecentDeliveries, &c.TotalDeliveries, &c.LastAssignedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, c)
+	}
+
+	return list, rows.Err()
+}
+
+// ClaimByID атомарно переводит свободного курьера в busy.
+// Строка, заблокированная параллельным назначением, пропускается (SKIP LOCKED),
+// поэтому один курьер не может быть выдан двум заказам одновременно.
+// Если курьер уже занят или заблокирован, возвращается nil, nil.
+func (r *postgresCourierRepository) ClaimByID(ctx context.Context, id int64) (*model.Courier, error) {
+	const query = `
+		UPDATE couriers SET status = $3, updated_at = now()
+		WHERE id = (
+			SELECT c.id
+			FROM couriers c
+			WHERE c.id = $1 AND c.status = $2
+			FOR UPDATE SKIP LOCKED
+		)
+		  AND status = $2
+		RETURNING id, name, phone, status, transport_type;
 	`
 
 	c := &model.Courier{}
 
-	err := r.db.Pool.QueryRow(ctx, query).
-		Scan(&c.ID, &c.Name, &c.Phone, &c.Status, &c.TransportType)
+	var err error
+	if tx, ok := getTx(ctx); ok {
+		err = tx.QueryRow(ctx, query, id, model.CourierStatusAvailable, model.CourierStatusBusy).
+			Scan(&c.ID, &c.Name, &c.Phone, &c.Status, &c.TransportType)
+	} else {
+		err = r.db.Pool.QueryRow(ctx, query, id, model.CourierStatusAvailable, model.CourierStatusBusy).
+			Scan(&c.ID, &c.Name, &c.Phone, &c.Status, &c.TransportType)
+	}
 
 	if err != nil {
 		if err == pgx.ErrNoRows {
@@ -148,32 +275,74 @@ func (r *postgresCourierRepository) FindAvailable(ctx context.Context) (*model.C
 	return c, nil
 }
 
-func (r *postgresCourierRepository) UpdateStatus(ctx context.Context, id int64, status string) error {
-	query := `UPDATE couriers SET status=$2 WHERE id=$1;`
+// ReleaseByID переводит занятого курьера в available.
+// Курьер в любом другом статусе не меняется, тогда возвращается false.
+func (r *postgresCourierRepository) ReleaseByID(ctx context.Context, id int64) (bool, error) {
+	const query = `
+		UPDATE couriers SET status = $3, updated_at = now()
+		WHERE id = $1 AND status = $2;
+	`
 
+	var (
+		cmd pgconn.CommandTag
+		err error
+	)
 	if tx, ok := getTx(ctx); ok {
-		_, err := tx.Exec(ctx, query, id, status)
-		return err
+		cmd, err = tx.Exec(ctx, query, id, model.CourierStatusBusy, model.CourierStatusAvailable)
+	} else {
+		cmd, err = r.db.Pool.Exec(ctx, query, id, model.CourierStatusBusy, model.CourierStatusAvailable)
+	}
+	if err != nil {
+		return false, err
 	}
 
-	_, err := r.db.Pool.Exec(ctx, query, id, status)
-	return err
+	return cmd.RowsAffected() > 0, nil
 }
 
-func (r *postgresCourierRepository) ReleaseExpired(ctx context.Context, now time.Time) (int64, error) {
-	const query = `
-		UPDATE couriers c
-		SET status = 'available'
-		FROM delivery d
-		WHERE c.id = d.courier_id
-		  AND c.status = 'busy'
-		  AND d.deadline < $1;
+// UpdateStatus меняет статус курьера, только если переход допустим из текущего статуса
+func (r *postgresCourierRepository) UpdateStatus(ctx context.Context, id int64, status model.CourierStatus) error {
+	query := `
+		UPDATE couriers SET status=$2, updated_at = now()
+		WHERE id=$1 AND status = ANY($3);
 	`
 
-	cmd, err := r.db.Pool.Exec(ctx, query, now)
+	var (
+		cmd pgconn.CommandTag
+		err error
+	)
+	if tx, ok := getTx(ctx); ok {
+		cmd, err = tx.Exec(ctx, query, id, status, status.AllowedFrom())
+	} else {
+		cmd, err = r.db.Pool.Exec(ctx, query, id, status, status.AllowedFrom())
+	}
 	if err != nil {
-		return 0, err
+		return err
+	}
+
+	if cmd.RowsAffected() == 0 {
+		return r.transitionError(ctx, id)
 	}
 
-	return cmd.RowsAffected(), nil
+	return nil
+}
+
+// transitionError различает отсутствующего курьера и недопустимый переход статуса
+func (r *postgresCourierRepository) transitionError(ctx context.Context, id int64) error {
+	const query = `SELECT EXISTS(SELECT 1 FROM couriers WHERE id=$1);`
+
+	var exists bool
+	var err error
+	if tx, ok := getTx(ctx); ok {
+		err = tx.QueryRow(ctx, query, id).Scan(&exists)
+	} else {
+		err = r.db.Pool.QueryRow(ctx, query, id).Scan(&exists)
+	}
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		return ErrNotFound
+	}
+	return model.ErrInvalidTransition
 }
@@ -6,6 +6,7 @@ package repository_test
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/Avito-courses/course-go-avito-israpilovsha/internal/courier/model"
 	"github.com/Avito-courses/course-go-avito-israpilovsha/internal/courier/repository"
@@ -53,6 +54,12 @@ func TestCourierRepositoryIntegration(t *testing.T) {
 			created_at TIMESTAMP DEFAULT NOW(),
 			updated_at TIMESTAMP DEFAULT NOW()
 		);
+
+		CREATE TABLE delivery (
+			id BIGSERIAL PRIMARY KEY,
+			courier_id BIGINT NOT NULL REFERENCES couriers(id),
+			assigned_at TIMESTAMP NOT NULL DEFAULT NOW()
+		);
 	`
 
 	_, err = database.Pool.Exec(ctx, schema)
@@ -99,11 +106,11 @@ func TestCourierRepositoryIntegration(t *testing.T) {
 		t.Fatalf("expected status busy, got %s", got.Status)
 	}
 
-	available, err := repo.FindAvailable(ctx)
+	available, err := repo.ListAvailable(ctx, time.Time{})
 	if err != nil {
-		t.Fatalf("FindAvailable failed: %v", err)
+		t.Fatalf("ListAvailable failed: %v", err)
 	}
-	if available != nil {
+	if len(available) != 0 {
 		t.Fatalf("expected no available couriers, got %+v", available)
 	}
 
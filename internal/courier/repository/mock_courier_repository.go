@@ -36,6 +36,21 @@ func (m *MockCourierRepository) EXPECT() *MockCourierRepositoryMockRecorder {
 	return m.recorder
 }
 
+// ClaimByID mocks base method.
+func (m *MockCourierRepository) ClaimByID(ctx context.Context, id int64) (*model.Courier, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimByID", ctx, id)
+	ret0, _ := ret[0].(*model.Courier)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClaimByID indicates an expected call of ClaimByID.
+func (mr *MockCourierRepositoryMockRecorder) ClaimByID(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimByID", reflect.TypeOf((*MockCourierRepository)(nil).ClaimByID), ctx, id)
+}
+
 // Create mocks base method.
 func (m *MockCourierRepository) Create(ctx context.Context, c *model.Courier) error {
 	m.ctrl.T.Helper()
@@ -50,21 +65,6 @@ func (mr *MockCourierRepositoryMockRecorder) Create(ctx, c interface{}) *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockCourierRepository)(nil).Create), ctx, c)
 }
 
-// FindAvailable mocks base method.
-func (m *MockCourierRepository) FindAvailable(ctx context.Context) (*model.Courier, error) {
-	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "FindAvailable", ctx)
-	ret0, _ := ret[0].(*model.Courier)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
-}
-
-// FindAvailable indicates an expected call of FindAvailable.
-func (mr *MockCourierRepositoryMockRecorder) FindAvailable(ctx interface{}) *gomock.Call {
-	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindAvailable", reflect.TypeOf((*MockCourierRepository)(nil).FindAvailable), ctx)
-}
-
 // GetAll mocks base method.
 func (m *MockCourierRepository) GetAll(ctx context.Context) ([]*model.Courier, error) {
 	m.ctrl.T.Helper()
@@ -95,19 +95,49 @@ func (mr *MockCourierRepositoryMockRecorder) GetByID(ctx, id interface{}) *gomoc
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByID", reflect.TypeOf((*MockCourierRepository)(nil).GetByID), ctx, id)
 }
 
-// ReleaseExpired mocks base method.
-func (m *MockCourierRepository) ReleaseExpired(ctx context.Context, now time.Time) (int64, error) {
+// ListAvailable mocks base method.
+func (m *MockCourierRepository) ListAvailable(ctx context.Context, since time.Time) ([]*model.Candidate, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ReleaseExpired", ctx, now)
-	ret0, _ := ret[0].(int64)
+	ret := m.ctrl.Call(m, "ListAvailable", ctx, since)
+	ret0, _ := ret[0].([]*model.Candidate)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// ReleaseExpired indicates an expected call of ReleaseExpired.
-func (mr *MockCourierRepositoryMockRecorder) ReleaseExpired(ctx, now interface{}) *gomock.Call {
+// ListAvailable indicates an expected call of ListAvailable.
+func (mr *MockCourierRepositoryMockRecorder) ListAvailable(ctx, since interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleaseExpired", reflect.TypeOf((*MockCourierRepository)(nil).ReleaseExpired), ctx, now)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAvailable", reflect.TypeOf((*MockCourierRepository)(nil).ListAvailable), ctx, since)
+}
+
+// LockByID mocks base method.
+func (m *MockCourierRepository) LockByID(ctx context.Context, id int64) (*model.Courier, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LockByID", ctx, id)
+	ret0, _ := ret[0].(*model.Courier)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LockByID indicates an expected call of LockByID.
+func (mr *MockCourierRepositoryMockRecorder) LockByID(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LockByID", reflect.TypeOf((*MockCourierRepository)(nil).LockByID), ctx, id)
+}
+
+// ReleaseByID mocks base method.
+func (m *MockCourierRepository) ReleaseByID(ctx context.Context, id int64) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReleaseByID", ctx, id)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReleaseByID indicates an expected call of ReleaseByID.
+func (mr *MockCourierRepositoryMockRecorder) ReleaseByID(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleaseByID", reflect.TypeOf((*MockCourierRepository)(nil).ReleaseByID), ctx, id)
 }
 
 // Update mocks base method.
@@ -125,7 +155,7 @@ func (mr *MockCourierRepositoryMockRecorder) Update(ctx, c interface{}) *gomock.
 }
 
 // UpdateStatus mocks base method.
-func (m *MockCourierRepository) UpdateStatus(ctx context.Context, id int64, status string) error {
+func (m *MockCourierRepository) UpdateStatus(ctx context.Context, id int64, status model.CourierStatus) error {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "UpdateStatus", ctx, id, status)
 	ret0, _ := ret[0].(error)
@@ -137,3 +167,17 @@ func (mr *MockCourierRepositoryMockRecorder) UpdateStatus(ctx, id, status interf
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateStatus", reflect.TypeOf((*MockCourierRepository)(nil).UpdateStatus), ctx, id, status)
 }
+
+// WithTx mocks base method.
+func (m *MockCourierRepository) WithTx(ctx context.Context, fn func(context.Context) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WithTx", ctx, fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WithTx indicates an expected call of WithTx.
+func (mr *MockCourierRepositoryMockRecorder) WithTx(ctx, fn interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WithTx", reflect.TypeOf((*MockCourierRepository)(nil).WithTx), ctx, fn)
+}
@@ -2,21 +2,72 @@ package usecase
 
 import (
 	"context"
+	"time"
 
 	"github.com/Avito-courses/course-go-avito-israpilovsha/internal/courier/model"
 	"github.com/Avito-courses/course-go-avito-israpilovsha/internal/courier/repository"
+	outboxModel "github.com/Avito-courses/course-go-avito-israpilovsha/internal/outbox/model"
+	outboxRepo "github.com/Avito-courses/course-go-avito-israpilovsha/internal/outbox/repository"
 )
 
+// AvailabilityNotifier получает сигнал, что курьер стал доступен (например, чтобы разобрать очередь заказов)
+type AvailabilityNotifier interface {
+	NotifyCourierAvailable()
+}
+
 type CourierService struct {
-	repo repository.CourierRepository
+	repo      repository.CourierRepository
+	notifiers []AvailabilityNotifier
+	outbox    outboxRepo.OutboxWriter
+	nowFunc   func() time.Time
+}
+
+type Option func(*CourierService)
+
+// WithNotifiers подписывает получателей сигнала о том, что курьер стал доступен
+func WithNotifiers(notifiers ...AvailabilityNotifier) Option {
+	return func(s *CourierService) {
+		s.notifiers = append(s.notifiers, notifiers...)
+	}
 }
 
-func NewCourierService(repo repository.CourierRepository) *CourierService {
-	return &CourierService{repo: repo}
+// WithOutbox включает публикацию courier.status_changed при смене статуса через API
+func WithOutbox(w outboxRepo.OutboxWriter) Option {
+	return func(s *CourierService) {
+		s.outbox = w
+	}
 }
 
+func NewCourierService(repo repository.CourierRepository, opts ...Option) *CourierService {
+	s := &CourierService{repo: repo, nowFunc: time.Now}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Create создаёт курьера; без статуса курьер считается доступным.
+// busy выставляется только при назначении заказа, поэтому создать занятого курьера нельзя.
 func (s *CourierService) Create(ctx context.Context, c *model.Courier) error {
-	return s.repo.Create(ctx, c)
+	if c.Status == "" {
+		c.Status = model.CourierStatusAvailable
+	}
+
+	status, err := model.ParseCourierStatus(string(c.Status))
+	if err != nil {
+		return err
+	}
+	if status == model.CourierStatusBusy {
+		return model.ErrInvalidStatus
+	}
+	c.Status = status
+
+	if err := s.repo.Create(ctx, c); err != nil {
+		return err
+	}
+
+	s.notifyIfAvailable(c.Status)
+	return nil
 }
 
 func (s *CourierService) GetByID(ctx context.Context, id int64) (*model.Courier, error) {
@@ -27,6 +78,71 @@ func (s *CourierService) GetAll(ctx context.Context) ([]*model.Courier, error) {
 	return s.repo.GetAll(ctx)
 }
 
+// Update обновляет курьера; допустимость смены статуса проверяет репозиторий.
+// Как и в Create, выставить busy вручную нельзя, а из busy курьера выводит только завершение заказа:
+// в обоих случаях возвращается model.ErrInvalidStatus.
 func (s *CourierService) Update(ctx context.Context, c *model.Courier) error {
-	return s.repo.Update(ctx, c)
+	if c.Status != "" {
+		status, err := model.ParseCourierStatus(string(c.Status))
+		if err != nil {
+			return err
+		}
+		if status == model.CourierStatusBusy {
+			return model.ErrInvalidStatus
+		}
+		c.Status = status
+	}
+
+	if s.outbox == nil || c.Status == "" {
+		if err := s.repo.Update(ctx, c); err != nil {
+			return err
+		}
+	} else if err := s.updateWithEvent(ctx, c); err != nil {
+		return err
+	}
+
+	s.notifyIfAvailable(c.Status)
+	return nil
+}
+
+// updateWithEvent обновляет курьера и пишет смену статуса в outbox в одной транзакции
+func (s *CourierService) updateWithEvent(ctx context.Context, c *model.Courier) error {
+	return s.repo.WithTx(ctx, func(txCtx context.Context) error {
+		prev, err := s.repo.LockByID(txCtx, c.ID)
+		if err != nil {
+			return err
+		}
+
+		if err := s.repo.Update(txCtx, c); err != nil {
+			return err
+		}
+
+		if prev.Status == c.Status {
+			return nil
+		}
+
+		e, err := outboxModel.NewEvent(
+			outboxModel.EventCourierStatusChanged,
+			outboxModel.CourierKey(c.ID),
+			outboxModel.CourierStatusPayload{
+				CourierID: c.ID,
+				From:      string(prev.Status),
+				To:        string(c.Status),
+			},
+			s.nowFunc(),
+		)
+		if err != nil {
+			return err
+		}
+		return s.outbox.Add(txCtx, e)
+	})
+}
+
+func (s *CourierService) notifyIfAvailable(status model.CourierStatus) {
+	if status != model.CourierStatusAvailable {
+		return
+	}
+	for _, n := range s.notifiers {
+		n.NotifyCourierAvailable()
+	}
 }
@@ -2,7 +2,9 @@ package usecase_test
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"slices"
 	"testing"
 
 	"github.com/golang/mock/gomock"
@@ -10,6 +12,7 @@ import (
 	model "github.com/Avito-courses/course-go-avito-israpilovsha/internal/courier/model"
 	repoMock "github.com/Avito-courses/course-go-avito-israpilovsha/internal/courier/repository"
 	"github.com/Avito-courses/course-go-avito-israpilovsha/internal/courier/usecase"
+	outboxModel "github.com/Avito-courses/course-go-avito-israpilovsha/internal/outbox/model"
 )
 
 func TestCreate_Success(t *testing.T) {
@@ -161,3 +164,201 @@ func TestUpdate_Error(t *testing.T) {
 		t.Fatalf("expected error, got nil")
 	}
 }
+
+func TestCreate_DefaultsToAvailable(t *testing.T) {
+	t.Parallel()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := repoMock.NewMockCourierRepository(ctrl)
+
+	c := &model.Courier{Name: "Ivan"}
+
+	repo.EXPECT().
+		Create(gomock.Any(), c).
+		Return(nil)
+
+	svc := usecase.NewCourierService(repo)
+
+	if err := svc.Create(context.Background(), c); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if c.Status != model.CourierStatusAvailable {
+		t.Fatalf("expected status available, got %s", c.Status)
+	}
+}
+
+func TestCreate_InvalidStatus(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		status model.CourierStatus
+	}{
+		{"unknown", "sleeping"},
+		{"busy", model.CourierStatusBusy},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			repo := repoMock.NewMockCourierRepository(ctrl)
+			svc := usecase.NewCourierService(repo)
+
+			err := svc.Create(context.Background(), &model.Courier{Name: "Ivan", Status: tt.status})
+			if !errors.Is(err, model.ErrInvalidStatus) {
+				t.Fatalf("expected ErrInvalidStatus, got %v", err)
+			}
+		})
+	}
+}
+
+func TestUpdate_InvalidStatus(t *testing.T) {
+	t.Parallel()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := repoMock.NewMockCourierRepository(ctrl)
+	svc := usecase.NewCourierService(repo)
+
+	err := svc.Update(context.Background(), &model.Courier{ID: 5, Status: "sleeping"})
+	if !errors.Is(err, model.ErrInvalidStatus) {
+		t.Fatalf("expected ErrInvalidStatus, got %v", err)
+	}
+}
+
+func TestUpdate_RejectsBusy(t *testing.T) {
+	t.Parallel()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := repoMock.NewMockCourierRepository(ctrl)
+	svc := usecase.NewCourierService(repo)
+
+	err := svc.Update(context.Background(), &model.Courier{ID: 5, Status: model.CourierStatusBusy})
+	if !errors.Is(err, model.ErrInvalidStatus) {
+		t.Fatalf("expected ErrInvalidStatus, got %v", err)
+	}
+}
+
+func TestCourierStatus_ManualAllowedFrom(t *testing.T) {
+	t.Parallel()
+
+	from := model.CourierStatusAvailable.ManualAllowedFrom()
+	if slices.Contains(from, string(model.CourierStatusBusy)) {
+		t.Fatalf("busy must not be left manually, got %v", from)
+	}
+	if !slices.Contains(from, string(model.CourierStatusPaused)) {
+		t.Fatalf("expected paused -> available to stay allowed, got %v", from)
+	}
+}
+
+func TestCourierStatus_CanTransitionTo(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		from, to model.CourierStatus
+		want     bool
+	}{
+		{model.CourierStatusAvailable, model.CourierStatusBusy, true},
+		{model.CourierStatusBusy, model.CourierStatusAvailable, true},
+		{model.CourierStatusBusy, model.CourierStatusBusy, false},
+		{model.CourierStatusPaused, model.CourierStatusBusy, false},
+		{model.CourierStatusOffline, model.CourierStatusBusy, false},
+		{model.CourierStatusBusy, model.CourierStatusPaused, false},
+		{model.CourierStatusDeactivated, model.CourierStatusAvailable, false},
+		{model.CourierStatusPaused, model.CourierStatusAvailable, true},
+	}
+
+	for _, tt := range tests {
+		if got := tt.from.CanTransitionTo(tt.to); got != tt.want {
+			t.Fatalf("%s -> %s: expected %v, got %v", tt.from, tt.to, tt.want, got)
+		}
+	}
+}
+
+type countingNotifier struct{ calls int }
+
+func (n *countingNotifier) NotifyCourierAvailable() { n.calls++ }
+
+func TestUpdate_NotifiesWhenAvailable(t *testing.T) {
+	t.Parallel()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := repoMock.NewMockCourierRepository(ctrl)
+	repo.EXPECT().Update(gomock.Any(), gomock.Any()).Times(2).Return(nil)
+
+	n := &countingNotifier{}
+	svc := usecase.NewCourierService(repo, usecase.WithNotifiers(n))
+
+	if err := svc.Update(context.Background(), &model.Courier{ID: 1, Status: model.CourierStatusPaused}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := svc.Update(context.Background(), &model.Courier{ID: 1, Status: model.CourierStatusAvailable}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if n.calls != 1 {
+		t.Fatalf("expected 1 notification, got %d", n.calls)
+	}
+}
+
+type recordingOutbox struct{ events []*outboxModel.Event }
+
+func (o *recordingOutbox) Add(_ context.Context, e *outboxModel.Event) error {
+	o.events = append(o.events, e)
+	return nil
+}
+
+func TestUpdate_WritesStatusChangeToOutbox(t *testing.T) {
+	t.Parallel()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := repoMock.NewMockCourierRepository(ctrl)
+	repo.EXPECT().WithTx(gomock.Any(), gomock.Any()).Times(2).
+		DoAndReturn(func(ctx context.Context, fn func(context.Context) error) error {
+			return fn(ctx)
+		})
+	gomock.InOrder(
+		repo.EXPECT().LockByID(gomock.Any(), int64(1)).
+			Return(&model.Courier{ID: 1, Status: model.CourierStatusAvailable}, nil),
+		repo.EXPECT().Update(gomock.Any(), gomock.Any()).Return(nil),
+		repo.EXPECT().LockByID(gomock.Any(), int64(1)).
+			Return(&model.Courier{ID: 1, Status: model.CourierStatusPaused}, nil),
+		repo.EXPECT().Update(gomock.Any(), gomock.Any()).Return(nil),
+	)
+
+	outbox := &recordingOutbox{}
+	svc := usecase.NewCourierService(repo, usecase.WithOutbox(outbox))
+
+	if err := svc.Update(context.Background(), &model.Courier{ID: 1, Status: model.CourierStatusPaused}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// статус не изменился — события нет
+	if err := svc.Update(context.Background(), &model.Courier{ID: 1, Status: model.CourierStatusPaused}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(outbox.events) != 1 {
+		t.Fatalf("expected 1 outbox event, got %d", len(outbox.events))
+	}
+
+	e := outbox.events[0]
+	if e.Type != outboxModel.EventCourierStatusChanged || e.Key != outboxModel.CourierKey(1) {
+		t.Fatalf("unexpected event %+v", e)
+	}
+
+	var payload outboxModel.CourierStatusPayload
+	if err := json.Unmarshal(e.Payload, &payload); err != nil {
+		t.Fatalf("payload: %v", err)
+	}
+	if payload.From != string(model.CourierStatusAvailable) || payload.To != string(model.CourierStatusPaused) {
+		t.Fatalf("unexpected payload %+v", payload)
+	}
+}
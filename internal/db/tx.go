@@ -0,0 +1,109 @@
+package db
+
+import (
+	"context"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type txKey struct{}
+
+type hooksKey struct{}
+
+// commitHooks — функции, которые выполнятся после коммита транзакции
+type commitHooks struct {
+	mu  sync.Mutex
+	fns []func()
+}
+
+func (h *commitHooks) add(fns ...func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.fns = append(h.fns, fns...)
+}
+
+func (h *commitHooks) take() []func() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	fns := h.fns
+	h.fns = nil
+	return fns
+}
+
+// ContextWithTx кладёт транзакцию в контекст, чтобы все репозитории работали в ней
+func ContextWithTx(ctx context.Context, tx pgx.Tx) context.Context {
+	return context.WithValue(ctx, txKey{}, tx)
+}
+
+// TxFromContext достаёт транзакцию, открытую через WithTx любого репозитория
+func TxFromContext(ctx context.Context) (pgx.Tx, bool) {
+	tx, ok := ctx.Value(txKey{}).(pgx.Tx)
+	return tx, ok
+}
+
+// AfterCommit выполняет fn после коммита самой внешней транзакции из ctx.
+// Если транзакция или точка сохранения, в которой зарегистрирован fn, откатится, fn не выполнится.
+// Без транзакции в ctx fn выполняется сразу.
+// Нужен для сигналов другим горутинам: изменения, сделанные в транзакции, не видны им
+// до коммита внешней транзакции, и разбуженный раньше обработчик их не найдёт.
+func AfterCommit(ctx context.Context, fn func()) {
+	if h, ok := ctx.Value(hooksKey{}).(*commitHooks); ok {
+		h.add(fn)
+		return
+	}
+	fn()
+}
+
+// WithTx выполняет fn в транзакции и коммитит её, если fn не вернула ошибку.
+// Если в контексте уже есть транзакция, fn выполняется в точке сохранения внутри неё:
+// ошибка fn откатывает только точку сохранения, и внешняя транзакция остаётся рабочей,
+// а коммитом всей транзакции управляет тот, кто её открыл.
+func (db *Database) WithTx(ctx context.Context, fn func(txCtx context.Context) error) error {
+	if outer, ok := TxFromContext(ctx); ok {
+		return withSavepoint(ctx, outer, fn)
+	}
+
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	hooks := &commitHooks{}
+	if err := fn(context.WithValue(ContextWithTx(ctx, tx), hooksKey{}, hooks)); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	for _, h := range hooks.take() {
+		h()
+	}
+	return nil
+}
+
+func withSavepoint(ctx context.Context, outer pgx.Tx, fn func(txCtx context.Context) error) error {
+	sp, err := outer.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = sp.Rollback(ctx) }()
+
+	// хуки точки сохранения переходят во внешнюю транзакцию, только если точка сохранения не откатилась
+	hooks := &commitHooks{}
+	if err := fn(context.WithValue(ContextWithTx(ctx, sp), hooksKey{}, hooks)); err != nil {
+		return err
+	}
+
+	if err := sp.Commit(ctx); err != nil {
+		return err
+	}
+
+	for _, h := range hooks.take() {
+		AfterCommit(ctx, h)
+	}
+	return nil
+}
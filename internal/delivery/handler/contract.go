@@ -8,6 +8,19 @@ import (
 )
 
 type deliveryService interface {
-	Assign(ctx context.Context, orderID string) (*deliveryModel.Delivery, *courierModel.Courier, error)
+	AssignOrder(ctx context.Context, req deliveryModel.AssignRequest) (*deliveryModel.Delivery, *courierModel.Courier, error)
 	Unassign(ctx context.Context, orderID string) (*deliveryModel.Delivery, error)
+	GetByOrderID(ctx context.Context, orderID string) (*deliveryModel.Delivery, []*deliveryModel.DeliveryEvent, error)
+	List(ctx context.Context, f deliveryModel.DeliveryFilter) (*deliveryModel.DeliveryPage, error)
+}
+
+type completeService interface {
+	PickUp(ctx context.Context, orderID string) error
+	Complete(ctx context.Context, orderID string) error
+}
+
+type deadlinePolicyService interface {
+	Current() *deliveryModel.DeadlinePolicy
+	Update(ctx context.Context, p *deliveryModel.DeadlinePolicy) error
+	Reload(ctx context.Context) error
 }
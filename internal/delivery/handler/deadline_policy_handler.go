@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	deliveryModel "github.com/Avito-courses/course-go-avito-israpilovsha/internal/delivery/model"
+	"go.uber.org/zap"
+)
+
+// DeadlinePolicyHandler — админские ручки для просмотра и изменения политики дедлайнов
+type DeadlinePolicyHandler struct {
+	svc deadlinePolicyService
+	log *zap.SugaredLogger
+}
+
+func NewDeadlinePolicyHandler(s deadlinePolicyService, log *zap.SugaredLogger) *DeadlinePolicyHandler {
+	return &DeadlinePolicyHandler{svc: s, log: log}
+}
+
+func (h *DeadlinePolicyHandler) Get(w http.ResponseWriter, _ *http.Request) {
+	respond(w, http.StatusOK, h.svc.Current())
+}
+
+func (h *DeadlinePolicyHandler) Put(w http.ResponseWriter, r *http.Request) {
+	var p deliveryModel.DeadlinePolicy
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		respond(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		return
+	}
+
+	err := h.svc.Update(r.Context(), &p)
+	if errors.Is(err, deliveryModel.ErrInvalidDeadlinePolicy) {
+		respond(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		h.log.Errorf("Deadline policy update failed: %v", err)
+		respond(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+		return
+	}
+
+	respond(w, http.StatusOK, &p)
+}
+
+func (h *DeadlinePolicyHandler) Reload(w http.ResponseWriter, r *http.Request) {
+	if err := h.svc.Reload(r.Context()); err != nil {
+		h.log.Errorf("Deadline policy reload failed: %v", err)
+		respond(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+
+	respond(w, http.StatusOK, h.svc.Current())
+}
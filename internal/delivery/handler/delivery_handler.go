@@ -2,28 +2,46 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strconv"
+	"time"
 
+	deliveryModel "github.com/Avito-courses/course-go-avito-israpilovsha/internal/delivery/model"
+	deliveryRepo "github.com/Avito-courses/course-go-avito-israpilovsha/internal/delivery/repository"
+	"github.com/gorilla/mux"
 	"go.uber.org/zap"
 )
 
 type Handler struct {
-	svc deliveryService
-	log *zap.SugaredLogger
+	svc      deliveryService
+	complete completeService
+	log      *zap.SugaredLogger
 }
 
-func NewHandler(s deliveryService, log *zap.SugaredLogger) *Handler {
-	return &Handler{svc: s, log: log}
+func NewHandler(s deliveryService, c completeService, log *zap.SugaredLogger) *Handler {
+	return &Handler{svc: s, complete: c, log: log}
 }
 
 type assignReq struct {
-	OrderID string `json:"order_id"`
+	OrderID  string `json:"order_id"`
+	Priority int    `json:"priority,omitempty"`
+	Size     string `json:"size,omitempty"`
+	Zone     string `json:"zone,omitempty"`
 }
 
 type unassignReq struct {
 	OrderID string `json:"order_id"`
 }
 
+type pickUpReq struct {
+	OrderID string `json:"order_id"`
+}
+
+type completeReq struct {
+	OrderID string `json:"order_id"`
+}
+
 func respond(w http.ResponseWriter, code int, body any) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(code)
@@ -39,7 +57,19 @@ func (h *Handler) Assign(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	delivery, courier, err := h.svc.Assign(r.Context(), req.OrderID)
+	delivery, courier, err := h.svc.AssignOrder(r.Context(), deliveryModel.AssignRequest{
+		OrderID:  req.OrderID,
+		Priority: req.Priority,
+		Size:     deliveryModel.ParseOrderSize(req.Size),
+		Zone:     req.Zone,
+	})
+	if errors.Is(err, deliveryModel.ErrOrderQueued) {
+		respond(w, http.StatusAccepted, map[string]any{
+			"order_id": req.OrderID,
+			"status":   "queued",
+		})
+		return
+	}
 	if err != nil {
 		h.log.Warnf("Assign failed: %v", err)
 		respond(w, http.StatusConflict, map[string]string{"error": err.Error()})
@@ -64,6 +94,13 @@ func (h *Handler) Unassign(w http.ResponseWriter, r *http.Request) {
 	}
 
 	delivery, err := h.svc.Unassign(r.Context(), req.OrderID)
+	if errors.Is(err, deliveryModel.ErrOrderDequeued) {
+		respond(w, http.StatusOK, map[string]any{
+			"order_id": req.OrderID,
+			"status":   "dequeued",
+		})
+		return
+	}
 	if err != nil {
 		h.log.Warnf("Unassign failed: %v", err)
 		respond(w, http.StatusNotFound, map[string]string{"error": err.Error()})
@@ -78,3 +115,159 @@ func (h *Handler) Unassign(w http.ResponseWriter, r *http.Request) {
 
 	respond(w, http.StatusOK, resp)
 }
+
+func (h *Handler) PickUp(w http.ResponseWriter, r *http.Request) {
+	var req pickUpReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.OrderID == "" {
+		respond(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		return
+	}
+
+	if err := h.complete.PickUp(r.Context(), req.OrderID); err != nil {
+		h.log.Warnf("PickUp failed: %v", err)
+		switch {
+		case errors.Is(err, deliveryRepo.ErrNotFound):
+			respond(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		case errors.Is(err, deliveryModel.ErrInvalidTransition):
+			respond(w, http.StatusConflict, map[string]string{"error": err.Error()})
+		default:
+			respond(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+		}
+		return
+	}
+
+	resp := map[string]any{
+		"order_id": req.OrderID,
+		"status":   deliveryModel.DeliveryStatusPickedUp,
+	}
+
+	respond(w, http.StatusOK, resp)
+}
+
+func (h *Handler) Complete(w http.ResponseWriter, r *http.Request) {
+	var req completeReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.OrderID == "" {
+		respond(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		return
+	}
+
+	if err := h.complete.Complete(r.Context(), req.OrderID); err != nil {
+		h.log.Warnf("Complete failed: %v", err)
+		switch {
+		case errors.Is(err, deliveryRepo.ErrNotFound):
+			respond(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		case errors.Is(err, deliveryModel.ErrInvalidTransition):
+			respond(w, http.StatusConflict, map[string]string{"error": err.Error()})
+		default:
+			respond(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+		}
+		return
+	}
+
+	resp := map[string]any{
+		"order_id": req.OrderID,
+		"status":   deliveryModel.DeliveryStatusDelivered,
+	}
+
+	respond(w, http.StatusOK, resp)
+}
+
+// Get возвращает последнюю доставку заказа и историю её статусов
+func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
+	orderID := mux.Vars(r)["order_id"]
+	if orderID == "" {
+		respond(w, http.StatusBadRequest, map[string]string{"error": "invalid order id"})
+		return
+	}
+
+	delivery, events, err := h.svc.GetByOrderID(r.Context(), orderID)
+	if err != nil {
+		if errors.Is(err, deliveryRepo.ErrNotFound) {
+			respond(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+			return
+		}
+		h.log.Errorf("Get delivery failed for order %s: %v", orderID, err)
+		respond(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+		return
+	}
+
+	resp := map[string]any{
+		"delivery": delivery,
+		"events":   events,
+	}
+
+	respond(w, http.StatusOK, resp)
+}
+
+// List возвращает доставки с фильтрами courier_id, status, from, to и курсорной пагинацией
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	f, err := parseDeliveryFilter(r)
+	if err != nil {
+		respond(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	page, err := h.svc.List(r.Context(), f)
+	if err != nil {
+		h.log.Errorf("List deliveries failed: %v", err)
+		respond(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+		return
+	}
+
+	respond(w, http.StatusOK, page)
+}
+
+func parseDeliveryFilter(r *http.Request) (deliveryModel.DeliveryFilter, error) {
+	var f deliveryModel.DeliveryFilter
+	q := r.URL.Query()
+
+	if raw := q.Get("courier_id"); raw != "" {
+		id, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || id <= 0 {
+			return f, errors.New("invalid courier_id")
+		}
+		f.CourierID = id
+	}
+
+	if raw := q.Get("status"); raw != "" {
+		status, err := deliveryModel.ParseDeliveryStatus(raw)
+		if err != nil {
+			return f, err
+		}
+		f.Status = status
+	}
+
+	if raw := q.Get("from"); raw != "" {
+		from, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return f, errors.New("invalid from, expected RFC3339")
+		}
+		f.From = from
+	}
+
+	if raw := q.Get("to"); raw != "" {
+		to, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return f, errors.New("invalid to, expected RFC3339")
+		}
+		f.To = to
+	}
+
+	if raw := q.Get("cursor"); raw != "" {
+		cursor, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || cursor <= 0 {
+			return f, errors.New("invalid cursor")
+		}
+		f.BeforeID = cursor
+	}
+
+	if raw := q.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit <= 0 {
+			return f, errors.New("invalid limit")
+		}
+		f.Limit = limit
+	}
+
+	return f, nil
+}
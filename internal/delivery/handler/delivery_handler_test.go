@@ -13,20 +13,45 @@ import (
 	"github.com/Avito-courses/course-go-avito-israpilovsha/internal/delivery/handler"
 	deliveryModel "github.com/Avito-courses/course-go-avito-israpilovsha/internal/delivery/model"
 
+	deliveryRepo "github.com/Avito-courses/course-go-avito-israpilovsha/internal/delivery/repository"
+	"github.com/gorilla/mux"
+
 	"go.uber.org/zap"
 )
 
 type mockDeliveryService struct {
 	AssignFn   func(orderID string) (*deliveryModel.Delivery, *courierModel.Courier, error)
 	UnassignFn func(orderID string) (*deliveryModel.Delivery, error)
+	PickUpFn   func(orderID string) error
+	CompleteFn func(orderID string) error
+	GetFn      func(orderID string) (*deliveryModel.Delivery, []*deliveryModel.DeliveryEvent, error)
+	ListFn     func(f deliveryModel.DeliveryFilter) (*deliveryModel.DeliveryPage, error)
 }
 
-func (m *mockDeliveryService) Assign(_ context.Context, orderID string) (*deliveryModel.Delivery, *courierModel.Courier, error) {
-	return m.AssignFn(orderID)
+func (m *mockDeliveryService) AssignOrder(
+	_ context.Context,
+	req deliveryModel.AssignRequest,
+) (*deliveryModel.Delivery, *courierModel.Courier, error) {
+	return m.AssignFn(req.OrderID)
 }
 func (m *mockDeliveryService) Unassign(_ context.Context, orderID string) (*deliveryModel.Delivery, error) {
 	return m.UnassignFn(orderID)
 }
+func (m *mockDeliveryService) PickUp(_ context.Context, orderID string) error {
+	return m.PickUpFn(orderID)
+}
+func (m *mockDeliveryService) Complete(_ context.Context, orderID string) error {
+	return m.CompleteFn(orderID)
+}
+func (m *mockDeliveryService) GetByOrderID(
+	_ context.Context,
+	orderID string,
+) (*deliveryModel.Delivery, []*deliveryModel.DeliveryEvent, error) {
+	return m.GetFn(orderID)
+}
+func (m *mockDeliveryService) List(_ context.Context, f deliveryModel.DeliveryFilter) (*deliveryModel.DeliveryPage, error) {
+	return m.ListFn(f)
+}
 
 // TestAssignHandlerSuccess - успешное назначение курьера
 func TestAssignHandlerSuccess(t *testing.T) {
@@ -44,7 +69,7 @@ func TestAssignHandlerSuccess(t *testing.T) {
 	}
 
 	log := zap.NewExample().Sugar()
-	h := handler.NewHandler(svc, log)
+	h := handler.NewHandler(svc, svc, log)
 
 	body := []byte(`{"order_id":"abc"}`)
 	req := httptest.NewRequest(http.MethodPost, "/delivery/assign", bytes.NewBuffer(body))
@@ -70,7 +95,7 @@ func TestAssignHandlerBadRequest(t *testing.T) {
 	svc := &mockDeliveryService{}
 
 	log := zap.NewExample().Sugar()
-	h := handler.NewHandler(svc, log)
+	h := handler.NewHandler(svc, svc, log)
 
 	req := httptest.NewRequest("POST", "/delivery/assign", bytes.NewBuffer([]byte(`{bad json}`)))
 	w := httptest.NewRecorder()
@@ -92,7 +117,7 @@ func TestAssignHandlerConflict(t *testing.T) {
 	}
 
 	log := zap.NewExample().Sugar()
-	h := handler.NewHandler(svc, log)
+	h := handler.NewHandler(svc, svc, log)
 
 	req := httptest.NewRequest("POST", "/delivery/assign", bytes.NewBuffer([]byte(`{"order_id":"x"}`)))
 	w := httptest.NewRecorder()
@@ -104,6 +129,35 @@ func TestAssignHandlerConflict(t *testing.T) {
 	}
 }
 
+// TestAssignHandlerQueued - свободных курьеров нет, заказ в очереди
+func TestAssignHandlerQueued(t *testing.T) {
+	t.Parallel()
+	svc := &mockDeliveryService{
+		AssignFn: func(orderID string) (*deliveryModel.Delivery, *courierModel.Courier, error) {
+			return nil, nil, deliveryModel.ErrOrderQueued
+		},
+	}
+
+	log := zap.NewExample().Sugar()
+	h := handler.NewHandler(svc, svc, log)
+
+	req := httptest.NewRequest(http.MethodPost, "/delivery/assign", bytes.NewBuffer([]byte(`{"order_id":"q","priority":5}`)))
+	w := httptest.NewRecorder()
+
+	h.Assign(w, req)
+
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d", w.Code)
+	}
+
+	var resp map[string]any
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+
+	if resp["status"] != "queued" {
+		t.Fatalf("expected status queued, got %v", resp["status"])
+	}
+}
+
 // - TestUnassignHandlerSuccess - успешное снятие курьера
 func TestUnassignHandlerSuccess(t *testing.T) {
 	t.Parallel()
@@ -119,7 +173,7 @@ func TestUnassignHandlerSuccess(t *testing.T) {
 	}
 
 	log := zap.NewExample().Sugar()
-	h := handler.NewHandler(svc, log)
+	h := handler.NewHandler(svc, svc, log)
 
 	req := httptest.NewRequest(http.MethodPost, "/delivery/unassign",
 		bytes.NewBuffer([]byte(`{"order_id":"xyz"}`)))
@@ -142,13 +196,44 @@ func TestUnassignHandlerSuccess(t *testing.T) {
 	}
 }
 
+// TestUnassignHandlerDequeued - заказ ждал курьера в очереди и снят с неё
+func TestUnassignHandlerDequeued(t *testing.T) {
+	t.Parallel()
+
+	svc := &mockDeliveryService{
+		UnassignFn: func(orderID string) (*deliveryModel.Delivery, error) {
+			return nil, deliveryModel.ErrOrderDequeued
+		},
+	}
+
+	log := zap.NewExample().Sugar()
+	h := handler.NewHandler(svc, svc, log)
+
+	req := httptest.NewRequest(http.MethodPost, "/delivery/unassign",
+		bytes.NewBuffer([]byte(`{"order_id":"q"}`)))
+	w := httptest.NewRecorder()
+
+	h.Unassign(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+
+	var resp map[string]any
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+
+	if resp["status"] != "dequeued" {
+		t.Fatalf("expected status dequeued, got %v", resp["status"])
+	}
+}
+
 // TestUnassignHandlerBadRequest - некорректный JSON
 func TestUnassignHandlerBadRequest(t *testing.T) {
 	t.Parallel()
 
 	svc := &mockDeliveryService{}
 	log := zap.NewExample().Sugar()
-	h := handler.NewHandler(svc, log)
+	h := handler.NewHandler(svc, svc, log)
 
 	req := httptest.NewRequest(http.MethodPost, "/delivery/unassign",
 		bytes.NewBuffer([]byte(`{invalid json}`)))
@@ -172,7 +257,7 @@ func TestUnassignHandlerNotFound(t *testing.T) {
 	}
 
 	log := zap.NewExample().Sugar()
-	h := handler.NewHandler(svc, log)
+	h := handler.NewHandler(svc, svc, log)
 
 	req := httptest.NewRequest(http.MethodPost, "/delivery/unassign",
 		bytes.NewBuffer([]byte(`{"order_id":"123"}`)))
@@ -191,7 +276,7 @@ func TestAssignHandlerMissingField(t *testing.T) {
 
 	svc := &mockDeliveryService{}
 	log := zap.NewExample().Sugar()
-	h := handler.NewHandler(svc, log)
+	h := handler.NewHandler(svc, svc, log)
 
 	req := httptest.NewRequest(http.MethodPost, "/delivery/assign",
 		bytes.NewBuffer([]byte(`{"wrong":"field"}`)))
@@ -210,7 +295,7 @@ func TestAssignHandlerEmptyBody(t *testing.T) {
 
 	svc := &mockDeliveryService{}
 	log := zap.NewExample().Sugar()
-	h := handler.NewHandler(svc, log)
+	h := handler.NewHandler(svc, svc, log)
 
 	req := httptest.NewRequest(http.MethodPost, "/delivery/assign", nil)
 	w := httptest.NewRecorder()
@@ -221,3 +306,239 @@ func TestAssignHandlerEmptyBody(t *testing.T) {
 		t.Fatalf("expected 400, got %d", w.Code)
 	}
 }
+
+// TestPickUpHandler - курьер забрал заказ
+func TestPickUpHandler(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		body       string
+		err        error
+		wantStatus int
+	}{
+		{"success", `{"order_id":"abc"}`, nil, http.StatusOK},
+		{"bad request", `{bad json}`, nil, http.StatusBadRequest},
+		{"not found", `{"order_id":"abc"}`, deliveryRepo.ErrNotFound, http.StatusNotFound},
+		{"already picked up", `{"order_id":"abc"}`, deliveryModel.ErrInvalidTransition, http.StatusConflict},
+		{"internal", `{"order_id":"abc"}`, errors.New("db down"), http.StatusInternalServerError},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			svc := &mockDeliveryService{
+				PickUpFn: func(orderID string) error { return tc.err },
+			}
+			h := handler.NewHandler(svc, svc, zap.NewExample().Sugar())
+
+			r := mux.NewRouter()
+			handler.RegisterDeliveryRoutes(r, h)
+
+			req := httptest.NewRequest(http.MethodPost, "/delivery/pickup", bytes.NewBufferString(tc.body))
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			if w.Code != tc.wantStatus {
+				t.Fatalf("expected %d, got %d", tc.wantStatus, w.Code)
+			}
+		})
+	}
+}
+
+// TestCompleteHandler - завершение доставки через HTTP
+func TestCompleteHandler(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		body       string
+		err        error
+		wantStatus int
+	}{
+		{"success", `{"order_id":"abc"}`, nil, http.StatusOK},
+		{"bad request", `{bad json}`, nil, http.StatusBadRequest},
+		{"not found", `{"order_id":"abc"}`, deliveryRepo.ErrNotFound, http.StatusNotFound},
+		{"invalid transition", `{"order_id":"abc"}`, deliveryModel.ErrInvalidTransition, http.StatusConflict},
+		{"internal", `{"order_id":"abc"}`, errors.New("db down"), http.StatusInternalServerError},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			svc := &mockDeliveryService{
+				CompleteFn: func(orderID string) error { return tc.err },
+			}
+			h := handler.NewHandler(svc, svc, zap.NewExample().Sugar())
+
+			req := httptest.NewRequest(http.MethodPost, "/delivery/complete", bytes.NewBufferString(tc.body))
+			w := httptest.NewRecorder()
+
+			h.Complete(w, req)
+
+			if w.Code != tc.wantStatus {
+				t.Fatalf("expected %d, got %d", tc.wantStatus, w.Code)
+			}
+		})
+	}
+}
+
+// TestGetHandler - доставка с историей по order_id
+func TestGetHandler(t *testing.T) {
+	t.Parallel()
+
+	svc := &mockDeliveryService{
+		GetFn: func(orderID string) (*deliveryModel.Delivery, []*deliveryModel.DeliveryEvent, error) {
+			if orderID != "abc" {
+				return nil, nil, deliveryRepo.ErrNotFound
+			}
+			return &deliveryModel.Delivery{ID: 1, OrderID: orderID, Status: deliveryModel.DeliveryStatusDelivered},
+				[]*deliveryModel.DeliveryEvent{
+					{ToStatus: deliveryModel.DeliveryStatusAssigned},
+					{FromStatus: deliveryModel.DeliveryStatusAssigned, ToStatus: deliveryModel.DeliveryStatusDelivered},
+				},
+				nil
+		},
+	}
+	h := handler.NewHandler(svc, svc, zap.NewExample().Sugar())
+
+	req := httptest.NewRequest(http.MethodGet, "/delivery/abc", nil)
+	req = mux.SetURLVars(req, map[string]string{"order_id": "abc"})
+	w := httptest.NewRecorder()
+
+	h.Get(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+
+	var resp struct {
+		Delivery deliveryModel.Delivery        `json:"delivery"`
+		Events   []deliveryModel.DeliveryEvent `json:"events"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+
+	if resp.Delivery.Status != deliveryModel.DeliveryStatusDelivered || len(resp.Events) != 2 {
+		t.Fatalf("unexpected response %+v", resp)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/delivery/missing", nil)
+	req = mux.SetURLVars(req, map[string]string{"order_id": "missing"})
+	w = httptest.NewRecorder()
+
+	h.Get(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", w.Code)
+	}
+}
+
+// TestListHandler - фильтры и курсор передаются в сервис
+func TestListHandler(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		check      func(t *testing.T, f deliveryModel.DeliveryFilter)
+	}{
+		{
+			name:       "all filters",
+			query:      "?courier_id=7&status=delivered&from=2026-01-01T00:00:00Z&to=2026-01-02T00:00:00Z&cursor=100&limit=10",
+			wantStatus: http.StatusOK,
+			check: func(t *testing.T, f deliveryModel.DeliveryFilter) {
+				if f.CourierID != 7 || f.Status != deliveryModel.DeliveryStatusDelivered ||
+					f.BeforeID != 100 || f.Limit != 10 || f.From.IsZero() || f.To.IsZero() {
+					t.Errorf("unexpected filter %+v", f)
+				}
+			},
+		},
+		{name: "no filters", query: "", wantStatus: http.StatusOK},
+		{name: "bad status", query: "?status=lost", wantStatus: http.StatusBadRequest},
+		{name: "bad courier", query: "?courier_id=x", wantStatus: http.StatusBadRequest},
+		{name: "bad from", query: "?from=yesterday", wantStatus: http.StatusBadRequest},
+		{name: "bad cursor", query: "?cursor=-1", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			svc := &mockDeliveryService{
+				ListFn: func(f deliveryModel.DeliveryFilter) (*deliveryModel.DeliveryPage, error) {
+					if tc.check != nil {
+						tc.check(t, f)
+					}
+					return &deliveryModel.DeliveryPage{
+						Items:      []*deliveryModel.Delivery{{ID: 99}},
+						NextCursor: "99",
+					}, nil
+				},
+			}
+			h := handler.NewHandler(svc, svc, zap.NewExample().Sugar())
+
+			req := httptest.NewRequest(http.MethodGet, "/deliveries"+tc.query, nil)
+			w := httptest.NewRecorder()
+
+			h.List(w, req)
+
+			if w.Code != tc.wantStatus {
+				t.Fatalf("expected %d, got %d", tc.wantStatus, w.Code)
+			}
+		})
+	}
+}
+
+type mockDeadlinePolicyService struct {
+	current  *deliveryModel.DeadlinePolicy
+	UpdateFn func(p *deliveryModel.DeadlinePolicy) error
+}
+
+func (m *mockDeadlinePolicyService) Current() *deliveryModel.DeadlinePolicy { return m.current }
+func (m *mockDeadlinePolicyService) Update(_ context.Context, p *deliveryModel.DeadlinePolicy) error {
+	return m.UpdateFn(p)
+}
+func (m *mockDeadlinePolicyService) Reload(_ context.Context) error { return nil }
+
+func TestDeadlinePolicyHandlerPut(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		body     string
+		err      error
+		wantCode int
+	}{
+		{"ok", `{"transports": {"car": "10m"}, "default": "40m"}`, nil, http.StatusOK},
+		{"bad duration", `{"default": "soon"}`, nil, http.StatusBadRequest},
+		{"invalid policy", `{"default": "0s"}`, deliveryModel.ErrInvalidDeadlinePolicy, http.StatusBadRequest},
+		{"storage error", `{"default": "40m"}`, errors.New("db down"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			svc := &mockDeadlinePolicyService{
+				UpdateFn: func(p *deliveryModel.DeadlinePolicy) error { return tt.err },
+			}
+			h := handler.NewDeadlinePolicyHandler(svc, zap.NewNop().Sugar())
+
+			req := httptest.NewRequest(http.MethodPut, "/admin/deadline-policy", bytes.NewBufferString(tt.body))
+			w := httptest.NewRecorder()
+
+			h.Put(w, req)
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("expected %d, got %d", tt.wantCode, w.Code)
+			}
+		})
+	}
+}
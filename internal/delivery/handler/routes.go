@@ -5,4 +5,15 @@ import "github.com/gorilla/mux"
 func RegisterDeliveryRoutes(r *mux.Router, h *Handler) {
 	r.HandleFunc("/delivery/assign", h.Assign).Methods("POST")
 	r.HandleFunc("/delivery/unassign", h.Unassign).Methods("POST")
+	r.HandleFunc("/delivery/pickup", h.PickUp).Methods("POST")
+	r.HandleFunc("/delivery/complete", h.Complete).Methods("POST")
+	r.HandleFunc("/delivery/{order_id}", h.Get).Methods("GET")
+	r.HandleFunc("/deliveries", h.List).Methods("GET")
+}
+
+// RegisterDeadlinePolicyRoutes регистрирует ручки в admin — подроутере /admin с проверкой доступа
+func RegisterDeadlinePolicyRoutes(admin *mux.Router, h *DeadlinePolicyHandler) {
+	admin.HandleFunc("/deadline-policy", h.Get).Methods("GET")
+	admin.HandleFunc("/deadline-policy", h.Put).Methods("PUT")
+	admin.HandleFunc("/deadline-policy/reload", h.Reload).Methods("POST")
 }
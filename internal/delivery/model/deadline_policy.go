@@ -0,0 +1,213 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// TransportType — вид транспорта курьера
+type TransportType string
+
+const (
+	TransportCar     TransportType = "car"
+	TransportScooter TransportType = "scooter"
+	TransportOnFoot  TransportType = "on_foot"
+)
+
+var ErrInvalidDeadlinePolicy = errors.New("invalid deadline policy")
+
+// Duration — time.Duration, который в JSON записывается строкой вида "15m"
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var raw string
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+
+	parsed, err := time.ParseDuration(raw)
+	if err != nil {
+		return err
+	}
+
+	*d = Duration(parsed)
+	return nil
+}
+
+// RushHour — интервал времени суток, в который дедлайн умножается на Multiplier.
+// From и To задаются как "HH:MM"; интервал может переходить через полночь.
+type RushHour struct {
+	From       string  `json:"from"`
+	To         string  `json:"to"`
+	Multiplier float64 `json:"multiplier"`
+}
+
+// ZoneOverride — переопределение дедлайнов для зоны доставки
+type ZoneOverride struct {
+	Transports map[TransportType]Duration `json:"transports,omitempty"`
+	Default    Duration                   `json:"default,omitempty"`
+	// Multiplier применяется поверх часов пик; 0 — без изменений
+	Multiplier float64 `json:"multiplier,omitempty"`
+}
+
+// DeadlinePolicy — правила расчёта дедлайна доставки
+type DeadlinePolicy struct {
+	Transports map[TransportType]Duration `json:"transports"`
+	// Default — дедлайн для транспорта, которого нет в Transports
+	Default   Duration   `json:"default"`
+	RushHours []RushHour `json:"rush_hours,omitempty"`
+	// TimeZone — IANA-зона, в которой заданы часы пик (например "Europe/Moscow"); пусто — UTC
+	TimeZone  string                  `json:"time_zone,omitempty"`
+	Zones     map[string]ZoneOverride `json:"zones,omitempty"`
+	UpdatedAt time.Time               `json:"updated_at,omitempty"`
+
+	// loc — загруженная TimeZone, заполняется в Validate
+	loc *time.Location
+}
+
+// DefaultDeadlinePolicy — дедлайны, действующие, пока политика не загружена из БД
+func DefaultDeadlinePolicy() *DeadlinePolicy {
+	return &DeadlinePolicy{
+		Transports: map[TransportType]Duration{
+			TransportCar:     Duration(5 * time.Minute),
+			TransportScooter: Duration(15 * time.Minute),
+		},
+		Default: Duration(30 * time.Minute),
+	}
+}
+
+// Validate проверяет, что политика даёт положительный дедлайн для любого транспорта
+func (p *DeadlinePolicy) Validate() error {
+	if p.Default <= 0 {
+		return fmt.Errorf("%w: default must be positive", ErrInvalidDeadlinePolicy)
+	}
+	for t, d := range p.Transports {
+		if d <= 0 {
+			return fmt.Errorf("%w: deadline for %q must be positive", ErrInvalidDeadlinePolicy, t)
+		}
+	}
+	loc, err := loadLocation(p.TimeZone)
+	if err != nil {
+		return fmt.Errorf("%w: time_zone: %v", ErrInvalidDeadlinePolicy, err)
+	}
+	p.loc = loc
+
+	for i, rh := range p.RushHours {
+		if _, err := parseClock(rh.From); err != nil {
+			return fmt.Errorf("%w: rush_hours[%d].from: %v", ErrInvalidDeadlinePolicy, i, err)
+		}
+		if _, err := parseClock(rh.To); err != nil {
+			return fmt.Errorf("%w: rush_hours[%d].to: %v", ErrInvalidDeadlinePolicy, i, err)
+		}
+		if rh.Multiplier <= 0 {
+			return fmt.Errorf("%w: rush_hours[%d].multiplier must be positive", ErrInvalidDeadlinePolicy, i)
+		}
+	}
+	for name, z := range p.Zones {
+		if z.Default < 0 || z.Multiplier < 0 {
+			return fmt.Errorf("%w: zone %q has negative values", ErrInvalidDeadlinePolicy, name)
+		}
+		for t, d := range z.Transports {
+			if d <= 0 {
+				return fmt.Errorf("%w: zone %q deadline for %q must be positive", ErrInvalidDeadlinePolicy, name, t)
+			}
+		}
+	}
+	return nil
+}
+
+// Deadline считает дедлайн для транспорта и зоны.
+// Порядок выбора базы: зона+транспорт, транспорт, дефолт зоны, общий дефолт.
+// Затем применяется множитель первого подходящего часа пик и множитель зоны.
+func (p *DeadlinePolicy) Deadline(transport TransportType, zone string, now time.Time) time.Time {
+	z, hasZone := p.Zones[zone]
+
+	base, ok := time.Duration(0), false
+	if hasZone {
+		var d Duration
+		d, ok = z.Transports[transport]
+		base = time.Duration(d)
+	}
+	if !ok {
+		var d Duration
+		d, ok = p.Transports[transport]
+		base = time.Duration(d)
+	}
+	if !ok {
+		base = time.Duration(p.Default)
+		if hasZone && z.Default > 0 {
+			base = time.Duration(z.Default)
+		}
+	}
+
+	multiplier := p.rushMultiplier(now)
+	if hasZone && z.Multiplier > 0 {
+		multiplier *= z.Multiplier
+	}
+
+	return now.Add(time.Duration(float64(base) * multiplier))
+}
+
+func (p *DeadlinePolicy) rushMultiplier(now time.Time) float64 {
+	local := now.In(p.location())
+	minute := local.Hour()*60 + local.Minute()
+
+	for _, rh := range p.RushHours {
+		from, err := parseClock(rh.From)
+		if err != nil {
+			continue
+		}
+		to, err := parseClock(rh.To)
+		if err != nil {
+			continue
+		}
+
+		in := from <= minute && minute < to
+		if from > to {
+			in = minute >= from || minute < to
+		}
+		if in {
+			return rh.Multiplier
+		}
+	}
+
+	return 1
+}
+
+// location возвращает зону часов пик; политика без Validate загружает её на каждый вызов
+func (p *DeadlinePolicy) location() *time.Location {
+	if p.loc != nil {
+		return p.loc
+	}
+	loc, err := loadLocation(p.TimeZone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+func loadLocation(name string) (*time.Location, error) {
+	switch name {
+	case "":
+		return time.UTC, nil
+	case "Local":
+		// зона процесса зависит от окружения, часы пик в ней не воспроизводимы
+		return nil, errors.New(`"Local" is not allowed, use an IANA name`)
+	}
+	return time.LoadLocation(name)
+}
+
+// parseClock переводит "HH:MM" в минуты от начала суток
+func parseClock(raw string) (int, error) {
+	t, err := time.Parse("15:04", raw)
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
@@ -3,9 +3,44 @@ package model
 import "time"
 
 type Delivery struct {
-	ID         int64     `json:"id"`
-	CourierID  int64     `json:"courier_id"`
-	OrderID    string    `json:"order_id"`
-	AssignedAt time.Time `json:"assigned_at"`
-	Deadline   time.Time `json:"deadline"`
+	ID          int64          `json:"id"`
+	CourierID   int64          `json:"courier_id"`
+	OrderID     string         `json:"order_id"`
+	Status      DeliveryStatus `json:"status"`
+	AssignedAt  time.Time      `json:"assigned_at"`
+	Deadline    time.Time      `json:"deadline"`
+	CompletedAt *time.Time     `json:"completed_at,omitempty"`
+	CancelledAt *time.Time     `json:"cancelled_at,omitempty"`
+	// Priority, Size и Zone — параметры заказа из запроса на назначение
+	Priority int       `json:"priority"`
+	Size     OrderSize `json:"size"`
+	Zone     string    `json:"zone,omitempty"`
+}
+
+// DeliveryEvent — запись истории переходов статуса доставки
+type DeliveryEvent struct {
+	ID         int64          `json:"id"`
+	DeliveryID int64          `json:"delivery_id"`
+	OrderID    string         `json:"order_id"`
+	CourierID  int64          `json:"courier_id"`
+	FromStatus DeliveryStatus `json:"from_status,omitempty"`
+	ToStatus   DeliveryStatus `json:"to_status"`
+	Reason     string         `json:"reason,omitempty"`
+	CreatedAt  time.Time      `json:"created_at"`
+}
+
+// DeliveryFilter — параметры выборки доставок; нулевые значения не ограничивают выборку
+type DeliveryFilter struct {
+	CourierID int64
+	Status    DeliveryStatus
+	From      time.Time
+	To        time.Time
+	// BeforeID — курсор: берутся доставки с id меньше указанного
+	BeforeID int64
+	Limit    int
+}
+
+type DeliveryPage struct {
+	Items      []*Delivery `json:"items"`
+	NextCursor string      `json:"next_cursor,omitempty"`
 }
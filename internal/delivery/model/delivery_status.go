@@ -0,0 +1,84 @@
+package model
+
+import (
+	"errors"
+	"strings"
+)
+
+type DeliveryStatus string
+
+const (
+	DeliveryStatusAssigned  DeliveryStatus = "assigned"
+	DeliveryStatusPickedUp  DeliveryStatus = "picked_up"
+	DeliveryStatusDelivered DeliveryStatus = "delivered"
+	DeliveryStatusCancelled DeliveryStatus = "cancelled"
+	DeliveryStatusExpired   DeliveryStatus = "expired"
+)
+
+var (
+	ErrInvalidStatus     = errors.New("invalid delivery status")
+	ErrInvalidTransition = errors.New("invalid delivery status transition")
+)
+
+var deliveryTransitions = map[DeliveryStatus][]DeliveryStatus{
+	DeliveryStatusAssigned: {
+		DeliveryStatusPickedUp,
+		DeliveryStatusDelivered,
+		DeliveryStatusCancelled,
+		DeliveryStatusExpired,
+	},
+	DeliveryStatusPickedUp: {
+		DeliveryStatusDelivered,
+		DeliveryStatusCancelled,
+		DeliveryStatusExpired,
+	},
+	DeliveryStatusDelivered: {},
+	DeliveryStatusCancelled: {},
+	DeliveryStatusExpired:   {},
+}
+
+// ActiveDeliveryStatuses — статусы, в которых за заказом закреплён курьер
+func ActiveDeliveryStatuses() []string {
+	return []string{string(DeliveryStatusAssigned), string(DeliveryStatusPickedUp)}
+}
+
+func ParseDeliveryStatus(raw string) (DeliveryStatus, error) {
+	s := DeliveryStatus(strings.ToLower(strings.TrimSpace(raw)))
+	if !s.IsValid() {
+		return "", ErrInvalidStatus
+	}
+	return s, nil
+}
+
+func (s DeliveryStatus) IsValid() bool {
+	_, ok := deliveryTransitions[s]
+	return ok
+}
+
+func (s DeliveryStatus) IsTerminal() bool {
+	next, ok := deliveryTransitions[s]
+	return ok && len(next) == 0
+}
+
+func (s DeliveryStatus) CanTransitionTo(next DeliveryStatus) bool {
+	for _, allowed := range deliveryTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowedFrom возвращает статусы, из которых можно перейти в s
+func (s DeliveryStatus) AllowedFrom() []string {
+	from := make([]string, 0, len(deliveryTransitions))
+	for prev, next := range deliveryTransitions {
+		for _, n := range next {
+			if n == s {
+				from = append(from, string(prev))
+				break
+			}
+		}
+	}
+	return from
+}
@@ -0,0 +1,32 @@
+package model
+
+import (
+	"slices"
+	"time"
+)
+
+// FetchCursor — позиция опроса order-сервиса: время создания последних обработанных заказов
+// и их id, чтобы заказы с тем же временем не обработались повторно
+type FetchCursor struct {
+	CreatedAt time.Time `json:"created_at"`
+	OrderIDs  []string  `json:"order_ids"`
+}
+
+// Seen сообщает, обработан ли уже заказ, созданный в createdAt
+func (c *FetchCursor) Seen(orderID string, createdAt time.Time) bool {
+	if createdAt.Before(c.CreatedAt) {
+		return true
+	}
+	return createdAt.Equal(c.CreatedAt) && slices.Contains(c.OrderIDs, orderID)
+}
+
+// Advance сдвигает курсор на обработанный заказ
+func (c *FetchCursor) Advance(orderID string, createdAt time.Time) {
+	switch {
+	case createdAt.After(c.CreatedAt):
+		c.CreatedAt = createdAt
+		c.OrderIDs = []string{orderID}
+	case createdAt.Equal(c.CreatedAt) && !slices.Contains(c.OrderIDs, orderID):
+		c.OrderIDs = append(c.OrderIDs, orderID)
+	}
+}
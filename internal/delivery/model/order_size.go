@@ -0,0 +1,24 @@
+package model
+
+import "strings"
+
+// OrderSize — габарит заказа, влияет на выбор транспорта курьера
+type OrderSize string
+
+const (
+	OrderSizeSmall  OrderSize = "small"
+	OrderSizeMedium OrderSize = "medium"
+	OrderSizeLarge  OrderSize = "large"
+)
+
+// ParseOrderSize разбирает габарит заказа; пустое или неизвестное значение считается medium
+func ParseOrderSize(raw string) OrderSize {
+	switch strings.ToLower(strings.TrimSpace(raw)) {
+	case "small":
+		return OrderSizeSmall
+	case "large":
+		return OrderSizeLarge
+	default:
+		return OrderSizeMedium
+	}
+}
@@ -0,0 +1,16 @@
+package model
+
+import "time"
+
+// OrderVersion — версия заказа из события. Номер версии и время изменения — разные схемы:
+// каждая сравнивается только сама с собой, поэтому номер 5 не проигрывает времени в микросекундах.
+type OrderVersion struct {
+	// Number монотонно растёт в пределах заказа; 0 — не передан
+	Number int64
+	// ChangedAt — время изменения заказа; сравнивается, только если Number не передан
+	ChangedAt time.Time
+}
+
+func (v OrderVersion) IsZero() bool {
+	return v.Number <= 0 && v.ChangedAt.IsZero()
+}
@@ -0,0 +1,36 @@
+package model
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrOrderQueued — свободных курьеров нет, заказ поставлен в очередь ожидания
+var ErrOrderQueued = errors.New("no available courier, order queued")
+
+// ErrOrderDequeued — курьер заказу ещё не был назначен, заказ снят с очереди ожидания
+var ErrOrderDequeued = errors.New("order removed from pending queue")
+
+// AssignRequest — параметры назначения заказа
+type AssignRequest struct {
+	OrderID string
+	// Priority — заказы с большим приоритетом забираются из очереди раньше
+	Priority int
+	// Size — габарит заказа для стратегии transport_by_size
+	Size OrderSize
+	// Zone — зона доставки для переопределений политики дедлайнов
+	Zone string
+	// ExcludeCourierIDs — курьеры, которым этот заказ назначать нельзя (например, просрочившие его)
+	ExcludeCourierIDs []int64
+}
+
+// PendingOrder — заказ, ожидающий свободного курьера
+type PendingOrder struct {
+	OrderID  string    `json:"order_id"`
+	Priority int       `json:"priority"`
+	Size     OrderSize `json:"size"`
+	Zone     string    `json:"zone,omitempty"`
+	// ExcludeCourierIDs — курьеры, которым заказ назначать нельзя
+	ExcludeCourierIDs []int64   `json:"exclude_courier_ids,omitempty"`
+	EnqueuedAt        time.Time `json:"enqueued_at"`
+}
@@ -4,6 +4,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/Avito-courses/course-go-avito-israpilovsha/internal/delivery/model"
 )
@@ -12,12 +13,57 @@ type DeliveryRepository interface {
 	WithTx(ctx context.Context, fn func(txCtx context.Context) error) error
 
 	Create(ctx context.Context, d *model.Delivery) error
-	DeleteByOrderID(ctx context.Context, orderID string) (*model.Delivery, error)
 	GetByOrderID(ctx context.Context, orderID string) (*model.Delivery, error)
+	GetActiveByOrderID(ctx context.Context, orderID string) (*model.Delivery, error)
+	UpdateStatus(ctx context.Context, id int64, status model.DeliveryStatus, at time.Time) error
+	ListOverdue(ctx context.Context, now time.Time, limit int) ([]*model.Delivery, error)
+	AddEvent(ctx context.Context, e *model.DeliveryEvent) error
+	List(ctx context.Context, f model.DeliveryFilter) ([]*model.Delivery, error)
+	ListEvents(ctx context.Context, deliveryID int64) ([]*model.DeliveryEvent, error)
+}
+
+// PendingOrderRepository — очередь заказов, ожидающих свободного курьера
+type PendingOrderRepository interface {
+	Enqueue(ctx context.Context, p *model.PendingOrder) error
+	Next(ctx context.Context, skip []string) (*model.PendingOrder, error)
+	Remove(ctx context.Context, orderID string) (bool, error)
+	Depth(ctx context.Context) (int64, error)
+}
+
+// DeadlinePolicyRepository хранит действующую политику дедлайнов
+type DeadlinePolicyRepository interface {
+	Get(ctx context.Context) (*model.DeadlinePolicy, error)
+	Save(ctx context.Context, p *model.DeadlinePolicy) error
+}
+
+// ProcessedEventRepository хранит ключи обработанных событий заказов,
+// чтобы повторная доставка из Kafka не применялась дважды
+type ProcessedEventRepository interface {
+	WithTx(ctx context.Context, fn func(txCtx context.Context) error) error
+
+	// MarkProcessed запоминает ключ и возвращает false, если он уже был обработан
+	MarkProcessed(ctx context.Context, key string, at time.Time) (bool, error)
+	DeleteBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+// OrderVersionRepository хранит последнюю применённую версию заказа,
+// чтобы событие, пришедшее не по порядку, не откатило статус доставки
+type OrderVersionRepository interface {
+	WithTx(ctx context.Context, fn func(txCtx context.Context) error) error
+
+	// Advance сохраняет версию, если она новее сохранённой в той же схеме, и возвращает false для устаревшей
+	Advance(ctx context.Context, orderID string, version model.OrderVersion, at time.Time) (bool, error)
+}
+
+// FetchCursorRepository хранит позицию опроса order-сервиса между перезапусками
+type FetchCursorRepository interface {
+	Get(ctx context.Context, name string) (*model.FetchCursor, error)
+	Save(ctx context.Context, name string, c *model.FetchCursor) error
 }
 
 var (
 	ErrNotFound = errorNew("delivery not found")
+	ErrConflict = errorNew("order already has an active delivery")
 )
 
 type customError struct{ msg string }
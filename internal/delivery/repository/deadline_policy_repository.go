@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/Avito-courses/course-go-avito-israpilovsha/internal/db"
+	"github.com/Avito-courses/course-go-avito-israpilovsha/internal/delivery/model"
+	"github.com/jackc/pgx/v5"
+)
+
+type DeadlinePolicyPostgresRepository struct {
+	DB *db.Database
+}
+
+func NewDeadlinePolicyRepository(database *db.Database) *DeadlinePolicyPostgresRepository {
+	return &DeadlinePolicyPostgresRepository{DB: database}
+}
+
+// Get возвращает сохранённую политику; если её нет, ErrNotFound
+func (r *DeadlinePolicyPostgresRepository) Get(ctx context.Context) (*model.DeadlinePolicy, error) {
+	const query = `SELECT policy, updated_at FROM deadline_policy WHERE id = 1;`
+
+	var (
+		raw []byte
+		p   model.DeadlinePolicy
+	)
+	err := dbQueryRow(ctx, r.DB, query).Scan(&raw, &p.UpdatedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	if err := json.Unmarshal(raw, &p); err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
+
+// Save заменяет политику целиком
+func (r *DeadlinePolicyPostgresRepository) Save(ctx context.Context, p *model.DeadlinePolicy) error {
+	const query = `
+        INSERT INTO deadline_policy (id, policy, updated_at)
+        VALUES (1, $1, now())
+        ON CONFLICT (id) DO UPDATE
+            SET policy = EXCLUDED.policy, updated_at = EXCLUDED.updated_at
+        RETURNING updated_at;
+    `
+
+	raw, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+
+	return dbQueryRow(ctx, r.DB, query, raw).Scan(&p.UpdatedAt)
+}
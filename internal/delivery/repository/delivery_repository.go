@@ -2,15 +2,21 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
 
 	"github.com/Avito-courses/course-go-avito-israpilovsha/internal/db"
 	"github.com/Avito-courses/course-go-avito-israpilovsha/internal/delivery/model"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 )
 
-type txKeyType string
+const deliveryColumns = `id, courier_id, order_id, status, assigned_at, deadline, completed_at, cancelled_at, priority, size, zone`
 
-const txKey txKeyType = "tx"
+// activeOrderIndex — уникальный индекс: у заказа не больше одной активной доставки
+const activeOrderIndex = "ux_delivery_active_order_id"
 
 type DeliveryPostgresRepository struct {
 	DB *db.Database
@@ -21,103 +27,267 @@ func NewDeliveryRepository(db *db.Database) *DeliveryPostgresRepository {
 }
 
 func getTx(ctx context.Context) (pgx.Tx, bool) {
-	tx, ok := ctx.Value(txKey).(pgx.Tx)
-	return tx, ok
+	return db.TxFromContext(ctx)
 }
 
-func (r *DeliveryPostgresRepository) WithTx(ctx context.Context, fn func(txCtx context.Context) error) error {
-	tx, err := r.DB.Pool.Begin(ctx)
-	if err != nil {
-		return err
+// dbQueryRow, dbQuery и dbExec выполняют запрос в транзакции из контекста, если она есть
+func dbQueryRow(ctx context.Context, database *db.Database, sql string, args ...any) pgx.Row {
+	if tx, ok := getTx(ctx); ok {
+		return tx.QueryRow(ctx, sql, args...)
 	}
-	defer func() { _ = tx.Rollback(ctx) }()
+	return database.Pool.QueryRow(ctx, sql, args...)
+}
 
-	txCtx := context.WithValue(ctx, txKey, tx)
+func dbQuery(ctx context.Context, database *db.Database, sql string, args ...any) (pgx.Rows, error) {
+	if tx, ok := getTx(ctx); ok {
+		return tx.Query(ctx, sql, args...)
+	}
+	return database.Pool.Query(ctx, sql, args...)
+}
 
-	if err := fn(txCtx); err != nil {
-		return err
+func dbExec(ctx context.Context, database *db.Database, sql string, args ...any) (pgconn.CommandTag, error) {
+	if tx, ok := getTx(ctx); ok {
+		return tx.Exec(ctx, sql, args...)
 	}
+	return database.Pool.Exec(ctx, sql, args...)
+}
 
-	return tx.Commit(ctx)
+func scanDelivery(row pgx.Row) (*model.Delivery, error) {
+	d := &model.Delivery{}
+	err := row.Scan(
+		&d.ID, &d.CourierID, &d.OrderID, &d.Status, &d.AssignedAt, &d.Deadline, &d.CompletedAt, &d.CancelledAt,
+		&d.Priority, &d.Size, &d.Zone,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return d, nil
+}
+
+func (r *DeliveryPostgresRepository) WithTx(ctx context.Context, fn func(txCtx context.Context) error) error {
+	return r.DB.WithTx(ctx, fn)
 }
 
 func (r *DeliveryPostgresRepository) Create(ctx context.Context, d *model.Delivery) error {
 	const query = `
-        INSERT INTO delivery (courier_id, order_id, assigned_at, deadline)
-        VALUES ($1, $2, $3, $4)
+        INSERT INTO delivery (courier_id, order_id, status, assigned_at, deadline, priority, size, zone)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
         RETURNING id;
     `
 
-	tx, ok := getTx(ctx)
-	if ok {
-		return tx.QueryRow(ctx, query,
-			d.CourierID, d.OrderID, d.AssignedAt, d.Deadline,
-		).Scan(&d.ID)
+	if d.Status == "" {
+		d.Status = model.DeliveryStatusAssigned
+	}
+	if d.Size == "" {
+		d.Size = model.OrderSizeMedium
 	}
 
-	return r.DB.Pool.QueryRow(ctx, query,
-		d.CourierID, d.OrderID, d.AssignedAt, d.Deadline,
+	err := dbQueryRow(ctx, r.DB, query,
+		d.CourierID, d.OrderID, d.Status, d.AssignedAt, d.Deadline, d.Priority, string(d.Size), d.Zone,
 	).Scan(&d.ID)
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == activeOrderIndex {
+		return ErrConflict
+	}
+	return err
+}
+
+// GetByOrderID возвращает последнюю доставку заказа в любом статусе
+func (r *DeliveryPostgresRepository) GetByOrderID(ctx context.Context, orderID string) (*model.Delivery, error) {
+	const query = `
+        SELECT ` + deliveryColumns + `
+        FROM delivery
+        WHERE order_id=$1
+        ORDER BY id DESC
+        LIMIT 1;
+    `
+
+	return scanDelivery(dbQueryRow(ctx, r.DB, query, orderID))
 }
 
-func (r *DeliveryPostgresRepository) DeleteByOrderID(ctx context.Context, orderID string) (*model.Delivery, error) {
+// GetActiveByOrderID возвращает активную доставку заказа и блокирует её до конца транзакции
+func (r *DeliveryPostgresRepository) GetActiveByOrderID(ctx context.Context, orderID string) (*model.Delivery, error) {
 	const query = `
-        DELETE FROM delivery 
-        WHERE order_id=$1 
-        RETURNING id, courier_id, order_id, assigned_at, deadline;
+        SELECT ` + deliveryColumns + `
+        FROM delivery
+        WHERE order_id=$1 AND status = ANY($2)
+        FOR UPDATE;
     `
 
-	d := &model.Delivery{}
-	tx, ok := getTx(ctx)
+	return scanDelivery(dbQueryRow(ctx, r.DB, query, orderID, model.ActiveDeliveryStatuses()))
+}
 
-	var err error
-	if ok {
-		err = tx.QueryRow(ctx, query, orderID).Scan(
-			&d.ID, &d.CourierID, &d.OrderID, &d.AssignedAt, &d.Deadline,
-		)
-	} else {
-		err = r.DB.Pool.QueryRow(ctx, query, orderID).Scan(
-			&d.ID, &d.CourierID, &d.OrderID, &d.AssignedAt, &d.Deadline,
-		)
-	}
+// UpdateStatus переводит доставку в новый статус, только если переход допустим из текущего
+func (r *DeliveryPostgresRepository) UpdateStatus(
+	ctx context.Context,
+	id int64,
+	status model.DeliveryStatus,
+	at time.Time,
+) error {
+	const query = `
+        UPDATE delivery SET
+            status = $2,
+            completed_at = CASE WHEN $2 = $4 THEN $3 ELSE completed_at END,
+            cancelled_at = CASE WHEN $2 = $5 THEN $3 ELSE cancelled_at END,
+            updated_at = $3
+        WHERE id=$1 AND status = ANY($6);
+    `
 
+	cmd, err := dbExec(ctx, r.DB, query,
+		id, string(status), at,
+		string(model.DeliveryStatusDelivered), string(model.DeliveryStatusCancelled),
+		status.AllowedFrom(),
+	)
 	if err != nil {
-		if err == pgx.ErrNoRows {
-			return nil, ErrNotFound
+		return err
+	}
+
+	if cmd.RowsAffected() == 0 {
+		var exists bool
+		err := dbQueryRow(ctx, r.DB, `SELECT EXISTS(SELECT 1 FROM delivery WHERE id=$1);`, id).Scan(&exists)
+		if err != nil {
+			return err
 		}
-		return nil, err
+		if !exists {
+			return ErrNotFound
+		}
+		return model.ErrInvalidTransition
 	}
 
-	return d, nil
+	return nil
 }
 
-func (r *DeliveryPostgresRepository) GetByOrderID(ctx context.Context, orderID string) (*model.Delivery, error) {
+// ListOverdue возвращает активные доставки с истёкшим дедлайном.
+// Строки блокируются до конца транзакции, занятые другим обработчиком пропускаются.
+func (r *DeliveryPostgresRepository) ListOverdue(ctx context.Context, now time.Time, limit int) ([]*model.Delivery, error) {
 	const query = `
-        SELECT id, courier_id, order_id, assigned_at, deadline
+        SELECT ` + deliveryColumns + `
         FROM delivery
-        WHERE order_id=$1;
+        WHERE status = ANY($1) AND deadline < $2
+        ORDER BY deadline ASC
+        LIMIT $3
+        FOR UPDATE SKIP LOCKED;
     `
 
-	d := &model.Delivery{}
-	tx, ok := getTx(ctx)
+	rows, err := dbQuery(ctx, r.DB, query, model.ActiveDeliveryStatuses(), now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
 
-	var err error
-	if ok {
-		err = tx.QueryRow(ctx, query, orderID).Scan(
-			&d.ID, &d.CourierID, &d.OrderID, &d.AssignedAt, &d.Deadline,
-		)
-	} else {
-		err = r.DB.Pool.QueryRow(ctx, query, orderID).Scan(
-			&d.ID, &d.CourierID, &d.OrderID, &d.AssignedAt, &d.Deadline,
-		)
+	list := make([]*model.Delivery, 0)
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, d)
+	}
+
+	return list, rows.Err()
+}
+
+// AddEvent пишет переход статуса в историю доставки
+func (r *DeliveryPostgresRepository) AddEvent(ctx context.Context, e *model.DeliveryEvent) error {
+	const query = `
+        INSERT INTO delivery_events (delivery_id, order_id, courier_id, from_status, to_status, reason, created_at)
+        VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6, $7)
+        RETURNING id;
+    `
+
+	return dbQueryRow(ctx, r.DB, query,
+		e.DeliveryID, e.OrderID, e.CourierID, string(e.FromStatus), string(e.ToStatus), e.Reason, e.CreatedAt,
+	).Scan(&e.ID)
+}
+
+// List возвращает доставки по фильтру, от новых к старым
+func (r *DeliveryPostgresRepository) List(ctx context.Context, f model.DeliveryFilter) ([]*model.Delivery, error) {
+	conds := make([]string, 0, 5)
+	args := make([]any, 0, 6)
+
+	add := func(cond string, arg any) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+
+	if f.CourierID != 0 {
+		add("courier_id = $%d", f.CourierID)
+	}
+	if f.Status != "" {
+		add("status = $%d", string(f.Status))
+	}
+	if !f.From.IsZero() {
+		add("assigned_at >= $%d", f.From)
+	}
+	if !f.To.IsZero() {
+		add("assigned_at < $%d", f.To)
+	}
+	if f.BeforeID != 0 {
+		add("id < $%d", f.BeforeID)
+	}
+
+	where := ""
+	if len(conds) > 0 {
+		where = "WHERE " + strings.Join(conds, " AND ")
 	}
 
+	args = append(args, f.Limit)
+	query := fmt.Sprintf(`
+        SELECT %s
+        FROM delivery
+        %s
+        ORDER BY id DESC
+        LIMIT $%d;
+    `, deliveryColumns, where, len(args))
+
+	rows, err := dbQuery(ctx, r.DB, query, args...)
 	if err != nil {
-		if err == pgx.ErrNoRows {
-			return nil, ErrNotFound
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := make([]*model.Delivery, 0)
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
 		}
+		list = append(list, d)
+	}
+
+	return list, rows.Err()
+}
+
+// ListEvents возвращает историю переходов доставки в хронологическом порядке
+func (r *DeliveryPostgresRepository) ListEvents(ctx context.Context, deliveryID int64) ([]*model.DeliveryEvent, error) {
+	const query = `
+        SELECT id, delivery_id, order_id, courier_id, COALESCE(from_status, ''), to_status, reason, created_at
+        FROM delivery_events
+        WHERE delivery_id=$1
+        ORDER BY id ASC;
+    `
+
+	rows, err := dbQuery(ctx, r.DB, query, deliveryID)
+	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
-	return d, nil
+	list := make([]*model.DeliveryEvent, 0)
+	for rows.Next() {
+		e := &model.DeliveryEvent{}
+		err := rows.Scan(
+			&e.ID, &e.DeliveryID, &e.OrderID, &e.CourierID, &e.FromStatus, &e.ToStatus, &e.Reason, &e.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, e)
+	}
+
+	return list, rows.Err()
 }
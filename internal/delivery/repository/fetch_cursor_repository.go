@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/Avito-courses/course-go-avito-israpilovsha/internal/db"
+	"github.com/Avito-courses/course-go-avito-israpilovsha/internal/delivery/model"
+	"github.com/jackc/pgx/v5"
+)
+
+type FetchCursorPostgresRepository struct {
+	DB *db.Database
+}
+
+func NewFetchCursorRepository(database *db.Database) *FetchCursorPostgresRepository {
+	return &FetchCursorPostgresRepository{DB: database}
+}
+
+// Get возвращает сохранённый курсор; если его нет, ErrNotFound
+func (r *FetchCursorPostgresRepository) Get(ctx context.Context, name string) (*model.FetchCursor, error) {
+	const query = `SELECT created_at, order_ids FROM order_fetch_cursor WHERE name = $1;`
+
+	c := &model.FetchCursor{}
+	err := dbQueryRow(ctx, r.DB, query, name).Scan(&c.CreatedAt, &c.OrderIDs)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (r *FetchCursorPostgresRepository) Save(ctx context.Context, name string, c *model.FetchCursor) error {
+	const query = `
+        INSERT INTO order_fetch_cursor (name, created_at, order_ids, updated_at)
+        VALUES ($1, $2, $3, now())
+        ON CONFLICT (name) DO UPDATE
+            SET created_at = EXCLUDED.created_at,
+                order_ids = EXCLUDED.order_ids,
+                updated_at = EXCLUDED.updated_at;
+    `
+
+	_, err := dbExec(ctx, r.DB, query, name, c.CreatedAt, c.OrderIDs)
+	return err
+}
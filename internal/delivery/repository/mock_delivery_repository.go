@@ -7,6 +7,7 @@ package repository
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	model "github.com/Avito-courses/course-go-avito-israpilovsha/internal/delivery/model"
 	gomock "github.com/golang/mock/gomock"
@@ -35,6 +36,20 @@ func (m *MockDeliveryRepository) EXPECT() *MockDeliveryRepositoryMockRecorder {
 	return m.recorder
 }
 
+// AddEvent mocks base method.
+func (m *MockDeliveryRepository) AddEvent(ctx context.Context, e *model.DeliveryEvent) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddEvent", ctx, e)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddEvent indicates an expected call of AddEvent.
+func (mr *MockDeliveryRepositoryMockRecorder) AddEvent(ctx, e interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddEvent", reflect.TypeOf((*MockDeliveryRepository)(nil).AddEvent), ctx, e)
+}
+
 // Create mocks base method.
 func (m *MockDeliveryRepository) Create(ctx context.Context, d *model.Delivery) error {
 	m.ctrl.T.Helper()
@@ -49,19 +64,19 @@ func (mr *MockDeliveryRepositoryMockRecorder) Create(ctx, d interface{}) *gomock
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockDeliveryRepository)(nil).Create), ctx, d)
 }
 
-// DeleteByOrderID mocks base method.
-func (m *MockDeliveryRepository) DeleteByOrderID(ctx context.Context, orderID string) (*model.Delivery, error) {
+// GetActiveByOrderID mocks base method.
+func (m *MockDeliveryRepository) GetActiveByOrderID(ctx context.Context, orderID string) (*model.Delivery, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteByOrderID", ctx, orderID)
+	ret := m.ctrl.Call(m, "GetActiveByOrderID", ctx, orderID)
 	ret0, _ := ret[0].(*model.Delivery)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// DeleteByOrderID indicates an expected call of DeleteByOrderID.
-func (mr *MockDeliveryRepositoryMockRecorder) DeleteByOrderID(ctx, orderID interface{}) *gomock.Call {
+// GetActiveByOrderID indicates an expected call of GetActiveByOrderID.
+func (mr *MockDeliveryRepositoryMockRecorder) GetActiveByOrderID(ctx, orderID interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteByOrderID", reflect.TypeOf((*MockDeliveryRepository)(nil).DeleteByOrderID), ctx, orderID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetActiveByOrderID", reflect.TypeOf((*MockDeliveryRepository)(nil).GetActiveByOrderID), ctx, orderID)
 }
 
 // GetByOrderID mocks base method.
@@ -79,6 +94,65 @@ func (mr *MockDeliveryRepositoryMockRecorder) GetByOrderID(ctx, orderID interfac
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByOrderID", reflect.TypeOf((*MockDeliveryRepository)(nil).GetByOrderID), ctx, orderID)
 }
 
+// List mocks base method.
+func (m *MockDeliveryRepository) List(ctx context.Context, f model.DeliveryFilter) ([]*model.Delivery, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", ctx, f)
+	ret0, _ := ret[0].([]*model.Delivery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockDeliveryRepositoryMockRecorder) List(ctx, f interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockDeliveryRepository)(nil).List), ctx, f)
+}
+
+// ListEvents mocks base method.
+func (m *MockDeliveryRepository) ListEvents(ctx context.Context, deliveryID int64) ([]*model.DeliveryEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListEvents", ctx, deliveryID)
+	ret0, _ := ret[0].([]*model.DeliveryEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListEvents indicates an expected call of ListEvents.
+func (mr *MockDeliveryRepositoryMockRecorder) ListEvents(ctx, deliveryID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListEvents", reflect.TypeOf((*MockDeliveryRepository)(nil).ListEvents), ctx, deliveryID)
+}
+
+// ListOverdue mocks base method.
+func (m *MockDeliveryRepository) ListOverdue(ctx context.Context, now time.Time, limit int) ([]*model.Delivery, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListOverdue", ctx, now, limit)
+	ret0, _ := ret[0].([]*model.Delivery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListOverdue indicates an expected call of ListOverdue.
+func (mr *MockDeliveryRepositoryMockRecorder) ListOverdue(ctx, now, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListOverdue", reflect.TypeOf((*MockDeliveryRepository)(nil).ListOverdue), ctx, now, limit)
+}
+
+// UpdateStatus mocks base method.
+func (m *MockDeliveryRepository) UpdateStatus(ctx context.Context, id int64, status model.DeliveryStatus, at time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateStatus", ctx, id, status, at)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateStatus indicates an expected call of UpdateStatus.
+func (mr *MockDeliveryRepositoryMockRecorder) UpdateStatus(ctx, id, status, at interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateStatus", reflect.TypeOf((*MockDeliveryRepository)(nil).UpdateStatus), ctx, id, status, at)
+}
+
 // WithTx mocks base method.
 func (m *MockDeliveryRepository) WithTx(ctx context.Context, fn func(context.Context) error) error {
 	m.ctrl.T.Helper()
@@ -92,3 +166,308 @@ func (mr *MockDeliveryRepositoryMockRecorder) WithTx(ctx, fn interface{}) *gomoc
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WithTx", reflect.TypeOf((*MockDeliveryRepository)(nil).WithTx), ctx, fn)
 }
+
+// MockPendingOrderRepository is a mock of PendingOrderRepository interface.
+type MockPendingOrderRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockPendingOrderRepositoryMockRecorder
+}
+
+// MockPendingOrderRepositoryMockRecorder is the mock recorder for MockPendingOrderRepository.
+type MockPendingOrderRepositoryMockRecorder struct {
+	mock *MockPendingOrderRepository
+}
+
+// NewMockPendingOrderRepository creates a new mock instance.
+func NewMockPendingOrderRepository(ctrl *gomock.Controller) *MockPendingOrderRepository {
+	mock := &MockPendingOrderRepository{ctrl: ctrl}
+	mock.recorder = &MockPendingOrderRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockPendingOrderRepository) EXPECT() *MockPendingOrderRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Depth mocks base method.
+func (m *MockPendingOrderRepository) Depth(ctx context.Context) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Depth", ctx)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Depth indicates an expected call of Depth.
+func (mr *MockPendingOrderRepositoryMockRecorder) Depth(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Depth", reflect.TypeOf((*MockPendingOrderRepository)(nil).Depth), ctx)
+}
+
+// Enqueue mocks base method.
+func (m *MockPendingOrderRepository) Enqueue(ctx context.Context, p *model.PendingOrder) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Enqueue", ctx, p)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Enqueue indicates an expected call of Enqueue.
+func (mr *MockPendingOrderRepositoryMockRecorder) Enqueue(ctx, p interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Enqueue", reflect.TypeOf((*MockPendingOrderRepository)(nil).Enqueue), ctx, p)
+}
+
+// Next mocks base method.
+func (m *MockPendingOrderRepository) Next(ctx context.Context, skip []string) (*model.PendingOrder, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Next", ctx, skip)
+	ret0, _ := ret[0].(*model.PendingOrder)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Next indicates an expected call of Next.
+func (mr *MockPendingOrderRepositoryMockRecorder) Next(ctx, skip interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Next", reflect.TypeOf((*MockPendingOrderRepository)(nil).Next), ctx, skip)
+}
+
+// Remove mocks base method.
+func (m *MockPendingOrderRepository) Remove(ctx context.Context, orderID string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Remove", ctx, orderID)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Remove indicates an expected call of Remove.
+func (mr *MockPendingOrderRepositoryMockRecorder) Remove(ctx, orderID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Remove", reflect.TypeOf((*MockPendingOrderRepository)(nil).Remove), ctx, orderID)
+}
+
+// MockDeadlinePolicyRepository is a mock of DeadlinePolicyRepository interface.
+type MockDeadlinePolicyRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockDeadlinePolicyRepositoryMockRecorder
+}
+
+// MockDeadlinePolicyRepositoryMockRecorder is the mock recorder for MockDeadlinePolicyRepository.
+type MockDeadlinePolicyRepositoryMockRecorder struct {
+	mock *MockDeadlinePolicyRepository
+}
+
+// NewMockDeadlinePolicyRepository creates a new mock instance.
+func NewMockDeadlinePolicyRepository(ctrl *gomock.Controller) *MockDeadlinePolicyRepository {
+	mock := &MockDeadlinePolicyRepository{ctrl: ctrl}
+	mock.recorder = &MockDeadlinePolicyRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockDeadlinePolicyRepository) EXPECT() *MockDeadlinePolicyRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Get mocks base method.
+func (m *MockDeadlinePolicyRepository) Get(ctx context.Context) (*model.DeadlinePolicy, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", ctx)
+	ret0, _ := ret[0].(*model.DeadlinePolicy)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockDeadlinePolicyRepositoryMockRecorder) Get(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockDeadlinePolicyRepository)(nil).Get), ctx)
+}
+
+// Save mocks base method.
+func (m *MockDeadlinePolicyRepository) Save(ctx context.Context, p *model.DeadlinePolicy) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Save", ctx, p)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Save indicates an expected call of Save.
+func (mr *MockDeadlinePolicyRepositoryMockRecorder) Save(ctx, p interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Save", reflect.TypeOf((*MockDeadlinePolicyRepository)(nil).Save), ctx, p)
+}
+
+// MockProcessedEventRepository is a mock of ProcessedEventRepository interface.
+type MockProcessedEventRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockProcessedEventRepositoryMockRecorder
+}
+
+// MockProcessedEventRepositoryMockRecorder is the mock recorder for MockProcessedEventRepository.
+type MockProcessedEventRepositoryMockRecorder struct {
+	mock *MockProcessedEventRepository
+}
+
+// NewMockProcessedEventRepository creates a new mock instance.
+func NewMockProcessedEventRepository(ctrl *gomock.Controller) *MockProcessedEventRepository {
+	mock := &MockProcessedEventRepository{ctrl: ctrl}
+	mock.recorder = &MockProcessedEventRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockProcessedEventRepository) EXPECT() *MockProcessedEventRepositoryMockRecorder {
+	return m.recorder
+}
+
+// DeleteBefore mocks base method.
+func (m *MockProcessedEventRepository) DeleteBefore(ctx context.Context, before time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteBefore", ctx, before)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteBefore indicates an expected call of DeleteBefore.
+func (mr *MockProcessedEventRepositoryMockRecorder) DeleteBefore(ctx, before interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteBefore", reflect.TypeOf((*MockProcessedEventRepository)(nil).DeleteBefore), ctx, before)
+}
+
+// MarkProcessed mocks base method.
+func (m *MockProcessedEventRepository) MarkProcessed(ctx context.Context, key string, at time.Time) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkProcessed", ctx, key, at)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MarkProcessed indicates an expected call of MarkProcessed.
+func (mr *MockProcessedEventRepositoryMockRecorder) MarkProcessed(ctx, key, at interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkProcessed", reflect.TypeOf((*MockProcessedEventRepository)(nil).MarkProcessed), ctx, key, at)
+}
+
+// WithTx mocks base method.
+func (m *MockProcessedEventRepository) WithTx(ctx context.Context, fn func(context.Context) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WithTx", ctx, fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WithTx indicates an expected call of WithTx.
+func (mr *MockProcessedEventRepositoryMockRecorder) WithTx(ctx, fn interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WithTx", reflect.TypeOf((*MockProcessedEventRepository)(nil).WithTx), ctx, fn)
+}
+
+// MockOrderVersionRepository is a mock of OrderVersionRepository interface.
+type MockOrderVersionRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockOrderVersionRepositoryMockRecorder
+}
+
+// MockOrderVersionRepositoryMockRecorder is the mock recorder for MockOrderVersionRepository.
+type MockOrderVersionRepositoryMockRecorder struct {
+	mock *MockOrderVersionRepository
+}
+
+// NewMockOrderVersionRepository creates a new mock instance.
+func NewMockOrderVersionRepository(ctrl *gomock.Controller) *MockOrderVersionRepository {
+	mock := &MockOrderVersionRepository{ctrl: ctrl}
+	mock.recorder = &MockOrderVersionRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockOrderVersionRepository) EXPECT() *MockOrderVersionRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Advance mocks base method.
+func (m *MockOrderVersionRepository) Advance(ctx context.Context, orderID string, version model.OrderVersion, at time.Time) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Advance", ctx, orderID, version, at)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Advance indicates an expected call of Advance.
+func (mr *MockOrderVersionRepositoryMockRecorder) Advance(ctx, orderID, version, at interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Advance", reflect.TypeOf((*MockOrderVersionRepository)(nil).Advance), ctx, orderID, version, at)
+}
+
+// WithTx mocks base method.
+func (m *MockOrderVersionRepository) WithTx(ctx context.Context, fn func(context.Context) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WithTx", ctx, fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WithTx indicates an expected call of WithTx.
+func (mr *MockOrderVersionRepositoryMockRecorder) WithTx(ctx, fn interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WithTx", reflect.TypeOf((*MockOrderVersionRepository)(nil).WithTx), ctx, fn)
+}
+
+// MockFetchCursorRepository is a mock of FetchCursorRepository interface.
+type MockFetchCursorRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockFetchCursorRepositoryMockRecorder
+}
+
+// MockFetchCursorRepositoryMockRecorder is the mock recorder for MockFetchCursorRepository.
+type MockFetchCursorRepositoryMockRecorder struct {
+	mock *MockFetchCursorRepository
+}
+
+// NewMockFetchCursorRepository creates a new mock instance.
+func NewMockFetchCursorRepository(ctrl *gomock.Controller) *MockFetchCursorRepository {
+	mock := &MockFetchCursorRepository{ctrl: ctrl}
+	mock.recorder = &MockFetchCursorRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockFetchCursorRepository) EXPECT() *MockFetchCursorRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Get mocks base method.
+func (m *MockFetchCursorRepository) Get(ctx context.Context, name string) (*model.FetchCursor, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", ctx, name)
+	ret0, _ := ret[0].(*model.FetchCursor)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockFetchCursorRepositoryMockRecorder) Get(ctx, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockFetchCursorRepository)(nil).Get), ctx, name)
+}
+
+// Save mocks base method.
+func (m *MockFetchCursorRepository) Save(ctx context.Context, name string, c *model.FetchCursor) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Save", ctx, name, c)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Save indicates an expected call of Save.
+func (mr *MockFetchCursorRepositoryMockRecorder) Save(ctx, name, c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Save", reflect.TypeOf((*MockFetchCursorRepository)(nil).Save), ctx, name, c)
+}
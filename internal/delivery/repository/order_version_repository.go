@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/Avito-courses/course-go-avito-israpilovsha/internal/db"
+	"github.com/Avito-courses/course-go-avito-israpilovsha/internal/delivery/model"
+)
+
+type OrderVersionPostgresRepository struct {
+	DB *db.Database
+}
+
+func NewOrderVersionRepository(database *db.Database) *OrderVersionPostgresRepository {
+	return &OrderVersionPostgresRepository{DB: database}
+}
+
+func (r *OrderVersionPostgresRepository) WithTx(ctx context.Context, fn func(txCtx context.Context) error) error {
+	return r.DB.WithTx(ctx, fn)
+}
+
+// Advance обновляет версию заказа, только если новая больше сохранённой.
+// Событие с номером версии сравнивается с сохранённым номером, событие без номера —
+// с сохранённым временем изменения; схема, которой у заказа ещё не было, всегда новее.
+// Строка заказа блокируется до конца транзакции, поэтому параллельные события одного заказа
+// применяются по очереди.
+func (r *OrderVersionPostgresRepository) Advance(
+	ctx context.Context,
+	orderID string,
+	version model.OrderVersion,
+	at time.Time,
+) (bool, error) {
+	const query = `
+        INSERT INTO order_versions (order_id, version, changed_at, updated_at)
+        VALUES ($1, $2, $3, $4)
+        ON CONFLICT (order_id) DO UPDATE
+            SET version = COALESCE(EXCLUDED.version, order_versions.version),
+                changed_at = COALESCE(EXCLUDED.changed_at, order_versions.changed_at),
+                updated_at = EXCLUDED.updated_at
+            WHERE CASE
+                WHEN EXCLUDED.version IS NOT NULL
+                    THEN order_versions.version IS NULL OR order_versions.version < EXCLUDED.version
+                ELSE order_versions.changed_at IS NULL OR order_versions.changed_at < EXCLUDED.changed_at
+            END;
+    `
+
+	var (
+		number    *int64
+		changedAt *time.Time
+	)
+	if version.Number > 0 {
+		number = &version.Number
+	}
+	if !version.ChangedAt.IsZero() {
+		changedAt = &version.ChangedAt
+	}
+
+	cmd, err := dbExec(ctx, r.DB, query, orderID, number, changedAt, at)
+	if err != nil {
+		return false, err
+	}
+	return cmd.RowsAffected() == 1, nil
+}
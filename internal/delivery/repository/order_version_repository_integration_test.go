@@ -0,0 +1,87 @@
+//go:build integration
+// +build integration
+
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Avito-courses/course-go-avito-israpilovsha/internal/db"
+	"github.com/Avito-courses/course-go-avito-israpilovsha/internal/delivery/model"
+	"github.com/Avito-courses/course-go-avito-israpilovsha/internal/delivery/repository"
+	"go.uber.org/zap"
+
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
+)
+
+// TestOrderVersionRepositoryMixedSchemesIntegration — номер версии и время изменения
+// сравниваются каждое со своим: событие со временем не блокирует события с номером
+func TestOrderVersionRepositoryMixedSchemesIntegration(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	pgContainer, err := postgres.RunContainer(ctx,
+		testcontainers.WithImage("postgres:15"),
+		postgres.WithDatabase("test_db"),
+		postgres.WithUsername("user"),
+		postgres.WithPassword("password"),
+	)
+	if err != nil {
+		t.Fatalf("failed to start container: %v", err)
+	}
+
+	defer func() {
+		_ = pgContainer.Terminate(ctx)
+	}()
+
+	dsn, err := pgContainer.ConnectionString(ctx, "sslmode=disable")
+	if err != nil {
+		t.Fatalf("failed to get DSN: %v", err)
+	}
+
+	database := db.New(dsn, zap.NewExample().Sugar())
+	defer database.Close()
+
+	schema := `
+		CREATE TABLE order_versions (
+			order_id   VARCHAR(255) PRIMARY KEY,
+			version    BIGINT,
+			changed_at TIMESTAMPTZ,
+			updated_at TIMESTAMP NOT NULL DEFAULT now()
+		);
+	`
+	if _, err := database.Pool.Exec(ctx, schema); err != nil {
+		t.Fatalf("failed to apply schema: %v", err)
+	}
+
+	repo := repository.NewOrderVersionRepository(database)
+	t0 := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	steps := []struct {
+		name      string
+		version   model.OrderVersion
+		wantNewer bool
+	}{
+		{name: "first timestamp", version: model.OrderVersion{ChangedAt: t0}, wantNewer: true},
+		{name: "first number after timestamp", version: model.OrderVersion{Number: 1}, wantNewer: true},
+		{name: "same number", version: model.OrderVersion{Number: 1}, wantNewer: false},
+		{name: "next number", version: model.OrderVersion{Number: 2}, wantNewer: true},
+		{name: "older timestamp", version: model.OrderVersion{ChangedAt: t0.Add(-time.Second)}, wantNewer: false},
+		{name: "newer timestamp", version: model.OrderVersion{ChangedAt: t0.Add(time.Second)}, wantNewer: true},
+		{name: "older number after timestamp", version: model.OrderVersion{Number: 1}, wantNewer: false},
+	}
+
+	for _, s := range steps {
+		newer, err := repo.Advance(ctx, "o-1", s.version, time.Now())
+		if err != nil {
+			t.Fatalf("%s: Advance failed: %v", s.name, err)
+		}
+		if newer != s.wantNewer {
+			t.Fatalf("%s: Advance() = %v, want %v", s.name, newer, s.wantNewer)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/Avito-courses/course-go-avito-israpilovsha/internal/db"
+	"github.com/Avito-courses/course-go-avito-israpilovsha/internal/delivery/model"
+	"github.com/jackc/pgx/v5"
+)
+
+type PendingOrderPostgresRepository struct {
+	DB *db.Database
+}
+
+func NewPendingOrderRepository(database *db.Database) *PendingOrderPostgresRepository {
+	return &PendingOrderPostgresRepository{DB: database}
+}
+
+// Enqueue ставит заказ в очередь; повторная постановка сохраняет исходное время,
+// может только повысить приоритет и дополняет список исключённых курьеров
+func (r *PendingOrderPostgresRepository) Enqueue(ctx context.Context, p *model.PendingOrder) error {
+	const query = `
+        INSERT INTO pending_orders (order_id, priority, size, zone, exclude_courier_ids, enqueued_at)
+        VALUES ($1, $2, $3, $4, COALESCE($5::BIGINT[], '{}'), $6)
+        ON CONFLICT (order_id) DO UPDATE
+            SET priority = GREATEST(pending_orders.priority, EXCLUDED.priority),
+                exclude_courier_ids = ARRAY(
+                    SELECT DISTINCT unnest(pending_orders.exclude_courier_ids || EXCLUDED.exclude_courier_ids)
+                )
+        RETURNING priority, exclude_courier_ids, enqueued_at;
+    `
+
+	if p.Size == "" {
+		p.Size = model.OrderSizeMedium
+	}
+
+	return dbQueryRow(ctx, r.DB, query,
+		p.OrderID, p.Priority, string(p.Size), p.Zone, p.ExcludeCourierIDs, p.EnqueuedAt,
+	).Scan(&p.Priority, &p.ExcludeCourierIDs, &p.EnqueuedAt)
+}
+
+// Next возвращает первый заказ очереди и блокирует его до конца транзакции.
+// Заказы, которые уже разбирает другой обработчик, и заказы из skip пропускаются.
+func (r *PendingOrderPostgresRepository) Next(ctx context.Context, skip []string) (*model.PendingOrder, error) {
+	const query = `
+        SELECT order_id, priority, size, zone, exclude_courier_ids, enqueued_at
+        FROM pending_orders
+        WHERE order_id <> ALL(COALESCE($1::TEXT[], '{}'))
+        ORDER BY priority DESC, enqueued_at ASC
+        LIMIT 1
+        FOR UPDATE SKIP LOCKED;
+    `
+
+	p := &model.PendingOrder{}
+	err := dbQueryRow(ctx, r.DB, query, skip).Scan(
+		&p.OrderID, &p.Priority, &p.Size, &p.Zone, &p.ExcludeCourierIDs, &p.EnqueuedAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// Remove убирает заказ из очереди и сообщает, был ли он там
+func (r *PendingOrderPostgresRepository) Remove(ctx context.Context, orderID string) (bool, error) {
+	const query = `DELETE FROM pending_orders WHERE order_id=$1;`
+
+	cmd, err := dbExec(ctx, r.DB, query, orderID)
+	if err != nil {
+		return false, err
+	}
+
+	return cmd.RowsAffected() > 0, nil
+}
+
+func (r *PendingOrderPostgresRepository) Depth(ctx context.Context) (int64, error) {
+	const query = `SELECT COUNT(*) FROM pending_orders;`
+
+	var depth int64
+	err := dbQueryRow(ctx, r.DB, query).Scan(&depth)
+	return depth, err
+}
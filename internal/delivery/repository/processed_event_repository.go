@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/Avito-courses/course-go-avito-israpilovsha/internal/db"
+)
+
+type ProcessedEventPostgresRepository struct {
+	DB *db.Database
+}
+
+func NewProcessedEventRepository(database *db.Database) *ProcessedEventPostgresRepository {
+	return &ProcessedEventPostgresRepository{DB: database}
+}
+
+func (r *ProcessedEventPostgresRepository) WithTx(ctx context.Context, fn func(txCtx context.Context) error) error {
+	return r.DB.WithTx(ctx, fn)
+}
+
+// MarkProcessed вставляет ключ события; вызывается в транзакции вместе с его обработкой,
+// поэтому при откате ключ тоже не сохраняется
+func (r *ProcessedEventPostgresRepository) MarkProcessed(ctx context.Context, key string, at time.Time) (bool, error) {
+	const query = `
+        INSERT INTO processed_events (event_key, processed_at)
+        VALUES ($1, $2)
+        ON CONFLICT (event_key) DO NOTHING;
+    `
+
+	cmd, err := dbExec(ctx, r.DB, query, key, at)
+	if err != nil {
+		return false, err
+	}
+	return cmd.RowsAffected() == 1, nil
+}
+
+// DeleteBefore удаляет ключи, обработанные раньше before
+func (r *ProcessedEventPostgresRepository) DeleteBefore(ctx context.Context, before time.Time) (int64, error) {
+	const query = `DELETE FROM processed_events WHERE processed_at < $1;`
+
+	cmd, err := dbExec(ctx, r.DB, query, before)
+	if err != nil {
+		return 0, err
+	}
+	return cmd.RowsAffected(), nil
+}
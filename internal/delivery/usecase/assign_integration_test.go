@@ -0,0 +1,352 @@
+//go:build integration
+// +build integration
+
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/Avito-courses/course-go-avito-israpilovsha/internal/courier/model"
+	courierRepo "github.com/Avito-courses/course-go-avito-israpilovsha/internal/courier/repository"
+	"github.com/Avito-courses/course-go-avito-israpilovsha/internal/db"
+	deliveryRepo "github.com/Avito-courses/course-go-avito-israpilovsha/internal/delivery/repository"
+	"github.com/Avito-courses/course-go-avito-israpilovsha/internal/delivery/usecase"
+	"go.uber.org/zap"
+
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
+)
+
+// newAssignDatabase поднимает Postgres со схемой курьеров и доставок
+func newAssignDatabase(t *testing.T, ctx context.Context) *db.Database {
+	t.Helper()
+
+	pgContainer, err := postgres.RunContainer(ctx,
+		testcontainers.WithImage("postgres:15"),
+		postgres.WithDatabase("test_db"),
+		postgres.WithUsername("user"),
+		postgres.WithPassword("password"),
+	)
+	if err != nil {
+		t.Fatalf("failed to start container: %v", err)
+	}
+
+	t.Cleanup(func() {
+		_ = pgContainer.Terminate(ctx)
+	})
+
+	dsn, err := pgContainer.ConnectionString(ctx, "sslmode=disable", "pool_max_conns=32")
+	if err != nil {
+		t.Fatalf("failed to get DSN: %v", err)
+	}
+
+	logger := zap.NewExample().Sugar()
+	database := db.New(dsn, logger)
+	t.Cleanup(database.Close)
+
+	schema := `
+		CREATE TABLE couriers (
+			id BIGSERIAL PRIMARY KEY,
+			name TEXT NOT NULL,
+			phone TEXT NOT NULL UNIQUE,
+			status TEXT NOT NULL DEFAULT 'available',
+			transport_type TEXT NOT NULL DEFAULT 'on_foot',
+			created_at TIMESTAMP DEFAULT NOW(),
+			updated_at TIMESTAMP DEFAULT NOW()
+		);
+
+		CREATE TABLE delivery (
+			id BIGSERIAL PRIMARY KEY,
+			courier_id BIGINT NOT NULL,
+			order_id VARCHAR(255) NOT NULL,
+			status TEXT NOT NULL DEFAULT 'assigned',
+			assigned_at TIMESTAMP NOT NULL DEFAULT NOW(),
+			deadline TIMESTAMP NOT NULL,
+			completed_at TIMESTAMP,
+			cancelled_at TIMESTAMP,
+			priority INT NOT NULL DEFAULT 0,
+			size TEXT NOT NULL DEFAULT 'medium',
+			zone TEXT NOT NULL DEFAULT '',
+			updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+		);
+
+		CREATE UNIQUE INDEX ux_delivery_active_order_id
+		ON delivery(order_id)
+		WHERE status IN ('assigned', 'picked_up');
+
+		CREATE TABLE delivery_events (
+			id BIGSERIAL PRIMARY KEY,
+			delivery_id BIGINT NOT NULL REFERENCES delivery(id),
+			order_id VARCHAR(255) NOT NULL,
+			courier_id BIGINT NOT NULL,
+			from_status TEXT,
+			to_status TEXT NOT NULL,
+			reason TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMP NOT NULL DEFAULT NOW()
+		);
+	`
+
+	if _, err := database.Pool.Exec(ctx, schema); err != nil {
+		t.Fatalf("failed to apply schema: %v", err)
+	}
+
+	return database
+}
+
+func TestAssignConcurrentNoDoubleBookingIntegration(t *testing.T) {
+	t.Parallel()
+
+	const (
+		couriers = 50
+		orders   = 300
+	)
+
+	ctx := context.Background()
+
+	database := newAssignDatabase(t, ctx)
+
+	cRepo := courierRepo.NewCourierRepository(database)
+	dRepo := deliveryRepo.NewDeliveryRepository(database)
+	svc := usecase.NewDeliveryService(cRepo, dRepo)
+
+	for i := 0; i < couriers; i++ {
+		c := &model.Courier{
+			Name:          fmt.Sprintf("courier-%d", i),
+			Phone:         fmt.Sprintf("+7900%07d", i),
+			Status:        model.CourierStatusAvailable,
+			TransportType: "scooter",
+		}
+		if err := cRepo.Create(ctx, c); err != nil {
+			t.Fatalf("Create courier failed: %v", err)
+		}
+	}
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		assigned  int
+		noCourier int
+		failures  []error
+	)
+
+	start := make(chan struct{})
+	for i := 0; i < orders; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+
+			_, _, err := svc.Assign(ctx, fmt.Sprintf("order-%d", i))
+
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				assigned++
+			case errors.Is(err, courierRepo.ErrNotFound):
+				noCourier++
+			default:
+				failures = append(failures, err)
+			}
+		}(i)
+	}
+
+	close(start)
+	wg.Wait()
+
+	if len(failures) > 0 {
+		t.Fatalf("unexpected assign errors (%d), first: %v", len(failures), failures[0])
+	}
+	if assigned != couriers {
+		t.Fatalf("expected %d successful assigns, got %d (no courier: %d)", couriers, assigned, noCourier)
+	}
+
+	rows, err := database.Pool.Query(ctx, `
+		SELECT courier_id, COUNT(*)
+		FROM delivery
+		WHERE status IN ('assigned', 'picked_up')
+		GROUP BY courier_id
+		HAVING COUNT(*) > 1;
+	`)
+	if err != nil {
+		t.Fatalf("double booking query failed: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var courierID, cnt int64
+		if err := rows.Scan(&courierID, &cnt); err != nil {
+			t.Fatalf("scan failed: %v", err)
+		}
+		t.Errorf("courier %d got %d active orders", courierID, cnt)
+	}
+
+	var busy int
+	err = database.Pool.QueryRow(ctx,
+		`SELECT COUNT(*) FROM couriers WHERE status = $1;`, model.CourierStatusBusy,
+	).Scan(&busy)
+	if err != nil {
+		t.Fatalf("busy count query failed: %v", err)
+	}
+	if busy != couriers {
+		t.Fatalf("expected %d busy couriers, got %d", couriers, busy)
+	}
+}
+
+// TestAssignInsideOuterTransactionIntegration — Assign внутри чужой транзакции,
+// как при обработке события вместе с отметкой о нём: конфликт параллельного назначения
+// не должен ломать внешнюю транзакцию
+func TestAssignInsideOuterTransactionIntegration(t *testing.T) {
+	t.Parallel()
+
+	const callers = 8
+
+	ctx := context.Background()
+	database := newAssignDatabase(t, ctx)
+
+	cRepo := courierRepo.NewCourierRepository(database)
+	dRepo := deliveryRepo.NewDeliveryRepository(database)
+	svc := usecase.NewDeliveryService(cRepo, dRepo)
+
+	for i := 0; i < callers; i++ {
+		c := &model.Courier{
+			Name:          fmt.Sprintf("courier-%d", i),
+			Phone:         fmt.Sprintf("+7901%07d", i),
+			Status:        model.CourierStatusAvailable,
+			TransportType: "car",
+		}
+		if err := cRepo.Create(ctx, c); err != nil {
+			t.Fatalf("Create courier failed: %v", err)
+		}
+	}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		ids      = make(map[int64]bool)
+		failures []error
+	)
+
+	start := make(chan struct{})
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+
+			err := database.WithTx(ctx, func(txCtx context.Context) error {
+				d, _, err := svc.Assign(txCtx, "same-order")
+				if err != nil {
+					return err
+				}
+
+				// внешняя транзакция должна оставаться рабочей после Assign
+				if _, err := dRepo.GetActiveByOrderID(txCtx, "same-order"); err != nil {
+					return fmt.Errorf("outer tx after assign: %w", err)
+				}
+
+				mu.Lock()
+				ids[d.ID] = true
+				mu.Unlock()
+				return nil
+			})
+			if err != nil {
+				mu.Lock()
+				failures = append(failures, err)
+				mu.Unlock()
+			}
+		}()
+	}
+
+	close(start)
+	wg.Wait()
+
+	if len(failures) > 0 {
+		t.Fatalf("unexpected errors (%d), first: %v", len(failures), failures[0])
+	}
+	if len(ids) != 1 {
+		t.Fatalf("expected every caller to get the same delivery, got %v", ids)
+	}
+
+	var active int
+	err := database.Pool.QueryRow(ctx,
+		`SELECT COUNT(*) FROM delivery WHERE order_id = 'same-order' AND status = 'assigned';`,
+	).Scan(&active)
+	if err != nil {
+		t.Fatalf("active count query failed: %v", err)
+	}
+	if active != 1 {
+		t.Fatalf("expected 1 active delivery, got %d", active)
+	}
+
+	var busy int
+	err = database.Pool.QueryRow(ctx,
+		`SELECT COUNT(*) FROM couriers WHERE status = $1;`, model.CourierStatusBusy,
+	).Scan(&busy)
+	if err != nil {
+		t.Fatalf("busy count query failed: %v", err)
+	}
+	if busy != 1 {
+		t.Fatalf("expected 1 busy courier, got %d", busy)
+	}
+}
+
+// statusProbe — получатель сигнала, который в момент сигнала читает статус курьера
+// отдельным соединением, как это делает разбор очереди
+type statusProbe struct {
+	database  *db.Database
+	courierID int64
+	seen      []model.CourierStatus
+}
+
+func (p *statusProbe) NotifyCourierAvailable() {
+	var status model.CourierStatus
+	_ = p.database.Pool.QueryRow(context.Background(),
+		`SELECT status FROM couriers WHERE id = $1;`, p.courierID,
+	).Scan(&status)
+	p.seen = append(p.seen, status)
+}
+
+// TestCompleteNotifiesAfterOuterCommitIntegration — внутри чужой транзакции сигнал
+// об освободившемся курьере уходит только после её коммита, когда курьер уже виден свободным
+func TestCompleteNotifiesAfterOuterCommitIntegration(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	database := newAssignDatabase(t, ctx)
+
+	cRepo := courierRepo.NewCourierRepository(database)
+	dRepo := deliveryRepo.NewDeliveryRepository(database)
+
+	c := &model.Courier{Name: "courier", Phone: "+79010000000", Status: model.CourierStatusAvailable, TransportType: "car"}
+	if err := cRepo.Create(ctx, c); err != nil {
+		t.Fatalf("Create courier failed: %v", err)
+	}
+	if _, _, err := usecase.NewDeliveryService(cRepo, dRepo).Assign(ctx, "order-1"); err != nil {
+		t.Fatalf("Assign failed: %v", err)
+	}
+
+	probe := &statusProbe{database: database, courierID: c.ID}
+	complete := usecase.NewCompleteService(dRepo, cRepo, usecase.CompleteNotifies(probe))
+
+	err := database.WithTx(ctx, func(txCtx context.Context) error {
+		if err := complete.Complete(txCtx, "order-1"); err != nil {
+			return err
+		}
+		if len(probe.seen) != 0 {
+			return errors.New("notified before the outer transaction committed")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("outer transaction failed: %v", err)
+	}
+
+	if len(probe.seen) != 1 || probe.seen[0] != model.CourierStatusAvailable {
+		t.Fatalf("expected one notification seeing an available courier, got %v", probe.seen)
+	}
+}
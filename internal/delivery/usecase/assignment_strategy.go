@@ -0,0 +1,177 @@
+package usecase
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	courierModel "github.com/Avito-courses/course-go-avito-israpilovsha/internal/courier/model"
+	deliveryModel "github.com/Avito-courses/course-go-avito-israpilovsha/internal/delivery/model"
+)
+
+const (
+	StrategyLeastLoaded     = "least_loaded"
+	StrategyRoundRobin      = "round_robin"
+	StrategyFastestFirst    = "fastest_transport"
+	StrategyTransportBySize = "transport_by_size"
+)
+
+// AssignmentStrategy выбирает порядок, в котором сервис пытается занять свободных курьеров.
+// Rank не должен менять входной срез; курьеры, не подходящие заказу, в результат не попадают.
+type AssignmentStrategy interface {
+	Name() string
+	Rank(req deliveryModel.AssignRequest, candidates []*courierModel.Candidate) []*courierModel.Candidate
+}
+
+// NewAssignmentStrategy возвращает стратегию по имени из конфига; пустое имя — least_loaded
+func NewAssignmentStrategy(name string) (AssignmentStrategy, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", StrategyLeastLoaded:
+		return LeastLoadedStrategy{}, nil
+	case StrategyRoundRobin:
+		return RoundRobinStrategy{}, nil
+	case StrategyFastestFirst:
+		return FastestTransportStrategy{}, nil
+	case StrategyTransportBySize:
+		return TransportBySizeStrategy{}, nil
+	default:
+		return nil, fmt.Errorf("unknown assignment strategy %q", name)
+	}
+}
+
+// LeastLoadedStrategy — сначала курьеры с наименьшим числом доставок за окно загрузки.
+// При нулевом окне учитываются все доставки.
+type LeastLoadedStrategy struct{}
+
+func (LeastLoadedStrategy) Name() string { return StrategyLeastLoaded }
+
+func (LeastLoadedStrategy) Rank(
+	_ deliveryModel.AssignRequest,
+	candidates []*courierModel.Candidate,
+) []*courierModel.Candidate {
+	return sortedCandidates(candidates, func(a, b *courierModel.Candidate) int {
+		return a.RecentDeliveries - b.RecentDeliveries
+	})
+}
+
+// RoundRobinStrategy — сначала курьеры, дольше всех ждущие заказа; новые курьеры идут первыми
+type RoundRobinStrategy struct{}
+
+func (RoundRobinStrategy) Name() string { return StrategyRoundRobin }
+
+func (RoundRobinStrategy) Rank(
+	_ deliveryModel.AssignRequest,
+	candidates []*courierModel.Candidate,
+) []*courierModel.Candidate {
+	return sortedCandidates(candidates, func(a, b *courierModel.Candidate) int {
+		switch {
+		case a.LastAssignedAt == nil && b.LastAssignedAt == nil:
+			return 0
+		case a.LastAssignedAt == nil:
+			return -1
+		case b.LastAssignedAt == nil:
+			return 1
+		default:
+			return a.LastAssignedAt.Compare(*b.LastAssignedAt)
+		}
+	})
+}
+
+// transportSpeed — ранг скорости транспорта, меньше — быстрее
+var transportSpeed = map[deliveryModel.TransportType]int{
+	deliveryModel.TransportCar:     0,
+	deliveryModel.TransportScooter: 1,
+	deliveryModel.TransportOnFoot:  2,
+}
+
+func speedRank(transport string) int {
+	if rank, ok := transportSpeed[deliveryModel.TransportType(transport)]; ok {
+		return rank
+	}
+	return len(transportSpeed)
+}
+
+// FastestTransportStrategy — сначала самый быстрый транспорт, внутри — наименее загруженные
+type FastestTransportStrategy struct{}
+
+func (FastestTransportStrategy) Name() string { return StrategyFastestFirst }
+
+func (FastestTransportStrategy) Rank(
+	_ deliveryModel.AssignRequest,
+	candidates []*courierModel.Candidate,
+) []*courierModel.Candidate {
+	return sortedCandidates(candidates, func(a, b *courierModel.Candidate) int {
+		if d := speedRank(a.Courier.TransportType) - speedRank(b.Courier.TransportType); d != 0 {
+			return d
+		}
+		return a.RecentDeliveries - b.RecentDeliveries
+	})
+}
+
+// sizeTransports — допустимый транспорт для габарита заказа в порядке предпочтения.
+// Крупный заказ пешком не везут.
+var sizeTransports = map[deliveryModel.OrderSize][]deliveryModel.TransportType{
+	deliveryModel.OrderSizeSmall: {
+		deliveryModel.TransportOnFoot, deliveryModel.TransportScooter, deliveryModel.TransportCar,
+	},
+	deliveryModel.OrderSizeMedium: {
+		deliveryModel.TransportScooter, deliveryModel.TransportCar, deliveryModel.TransportOnFoot,
+	},
+	deliveryModel.OrderSizeLarge: {
+		deliveryModel.TransportCar, deliveryModel.TransportScooter,
+	},
+}
+
+// TransportBySizeStrategy — транспорт подбирается под габарит заказа, внутри — наименее загруженные
+type TransportBySizeStrategy struct{}
+
+func (TransportBySizeStrategy) Name() string { return StrategyTransportBySize }
+
+func (TransportBySizeStrategy) Rank(
+	req deliveryModel.AssignRequest,
+	candidates []*courierModel.Candidate,
+) []*courierModel.Candidate {
+	preferred, ok := sizeTransports[req.Size]
+	if !ok {
+		preferred = sizeTransports[deliveryModel.OrderSizeMedium]
+	}
+
+	rank := make(map[deliveryModel.TransportType]int, len(preferred))
+	for i, t := range preferred {
+		rank[t] = i
+	}
+
+	suitable := make([]*courierModel.Candidate, 0, len(candidates))
+	for _, c := range candidates {
+		if _, ok := rank[deliveryModel.TransportType(c.Courier.TransportType)]; ok {
+			suitable = append(suitable, c)
+		}
+	}
+
+	return sortedCandidates(suitable, func(a, b *courierModel.Candidate) int {
+		ra := rank[deliveryModel.TransportType(a.Courier.TransportType)]
+		rb := rank[deliveryModel.TransportType(b.Courier.TransportType)]
+		if d := ra - rb; d != 0 {
+			return d
+		}
+		return a.RecentDeliveries - b.RecentDeliveries
+	})
+}
+
+// sortedCandidates сортирует копию среза по cmp; при равенстве меньший id идёт первым
+func sortedCandidates(
+	candidates []*courierModel.Candidate,
+	cmp func(a, b *courierModel.Candidate) int,
+) []*courierModel.Candidate {
+	out := make([]*courierModel.Candidate, len(candidates))
+	copy(out, candidates)
+
+	sort.SliceStable(out, func(i, j int) bool {
+		if d := cmp(out[i], out[j]); d != 0 {
+			return d < 0
+		}
+		return out[i].Courier.ID < out[j].Courier.ID
+	})
+
+	return out
+}
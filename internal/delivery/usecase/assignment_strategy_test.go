@@ -0,0 +1,87 @@
+package usecase_test
+
+import (
+	"testing"
+	"time"
+
+	courierModel "github.com/Avito-courses/course-go-avito-israpilovsha/internal/courier/model"
+	deliveryModel "github.com/Avito-courses/course-go-avito-israpilovsha/internal/delivery/model"
+	"github.com/Avito-courses/course-go-avito-israpilovsha/internal/delivery/usecase"
+)
+
+func candidate(id int64, transport string, recent int, last *time.Time) *courierModel.Candidate {
+	return &courierModel.Candidate{
+		Courier:          &courierModel.Courier{ID: id, TransportType: transport},
+		RecentDeliveries: recent,
+		LastAssignedAt:   last,
+	}
+}
+
+func ids(list []*courierModel.Candidate) []int64 {
+	out := make([]int64, 0, len(list))
+	for _, c := range list {
+		out = append(out, c.Courier.ID)
+	}
+	return out
+}
+
+func TestAssignmentStrategies(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	earlier := now.Add(-time.Hour)
+
+	candidates := []*courierModel.Candidate{
+		candidate(1, "on_foot", 3, &now),
+		candidate(2, "car", 5, &earlier),
+		candidate(3, "scooter", 0, nil),
+		candidate(4, "car", 1, &now),
+	}
+
+	tests := []struct {
+		name     string
+		strategy string
+		size     deliveryModel.OrderSize
+		want     []int64
+	}{
+		{"least loaded", usecase.StrategyLeastLoaded, "", []int64{3, 4, 1, 2}},
+		{"round robin", usecase.StrategyRoundRobin, "", []int64{3, 2, 1, 4}},
+		{"fastest transport", usecase.StrategyFastestFirst, "", []int64{4, 2, 3, 1}},
+		{"small order", usecase.StrategyTransportBySize, deliveryModel.OrderSizeSmall, []int64{1, 3, 4, 2}},
+		{"medium order", usecase.StrategyTransportBySize, deliveryModel.OrderSizeMedium, []int64{3, 4, 2, 1}},
+		{"large order skips on foot", usecase.StrategyTransportBySize, deliveryModel.OrderSizeLarge, []int64{4, 2, 3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			st, err := usecase.NewAssignmentStrategy(tt.strategy)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			got := ids(st.Rank(deliveryModel.AssignRequest{Size: tt.size}, candidates))
+			if len(got) != len(tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("expected %v, got %v", tt.want, got)
+				}
+			}
+		})
+	}
+
+	if candidates[0].Courier.ID != 1 {
+		t.Fatal("Rank must not reorder input slice")
+	}
+}
+
+func TestNewAssignmentStrategy_Unknown(t *testing.T) {
+	t.Parallel()
+
+	if _, err := usecase.NewAssignmentStrategy("random"); err == nil {
+		t.Fatal("expected error for unknown strategy")
+	}
+}
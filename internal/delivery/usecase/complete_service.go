@@ -2,32 +2,93 @@ package usecase
 
 import (
 	"context"
+	"time"
 
 	courierRepo "github.com/Avito-courses/course-go-avito-israpilovsha/internal/courier/repository"
+	"github.com/Avito-courses/course-go-avito-israpilovsha/internal/db"
+	deliveryModel "github.com/Avito-courses/course-go-avito-israpilovsha/internal/delivery/model"
 	deliveryRepo "github.com/Avito-courses/course-go-avito-israpilovsha/internal/delivery/repository"
+	outboxRepo "github.com/Avito-courses/course-go-avito-israpilovsha/internal/outbox/repository"
 )
 
 type CompleteService struct {
 	deliveryRepo deliveryRepo.DeliveryRepository
 	courierRepo  courierRepo.CourierRepository
+	notifiers    []AvailabilityNotifier
+	outbox       outboxRepo.OutboxWriter
+	nowFunc      func() time.Time
+}
+
+type CompleteOption func(*CompleteService)
+
+// CompleteNotifies подписывает получателей сигнала об освободившемся курьере
+func CompleteNotifies(notifiers ...AvailabilityNotifier) CompleteOption {
+	return func(s *CompleteService) {
+		s.notifiers = append(s.notifiers, notifiers...)
+	}
+}
+
+// CompleteWithOutbox включает запись delivery.completed и courier.status_changed в outbox
+func CompleteWithOutbox(w outboxRepo.OutboxWriter) CompleteOption {
+	return func(s *CompleteService) {
+		s.outbox = w
+	}
 }
 
 func NewCompleteService(
 	d deliveryRepo.DeliveryRepository,
 	c courierRepo.CourierRepository,
+	opts ...CompleteOption,
 ) *CompleteService {
-	return &CompleteService{
+	s := &CompleteService{
 		deliveryRepo: d,
 		courierRepo:  c,
+		nowFunc:      time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(s)
 	}
+
+	return s
 }
 
-func (s *CompleteService) Complete(ctx context.Context, orderID string) error {
+// PickUp отмечает, что курьер забрал заказ: assigned → picked_up
+func (s *CompleteService) PickUp(ctx context.Context, orderID string) error {
 	return s.deliveryRepo.WithTx(ctx, func(txCtx context.Context) error {
-		d, err := s.deliveryRepo.GetByOrderID(txCtx, orderID)
+		d, err := s.deliveryRepo.GetActiveByOrderID(txCtx, orderID)
+		if err != nil {
+			return err
+		}
+
+		return transitionDelivery(
+			txCtx, s.deliveryRepo, s.courierRepo, s.outbox,
+			d, deliveryModel.DeliveryStatusPickedUp, ReasonPickedUp, s.nowFunc(),
+		)
+	})
+}
+
+// Complete переводит активную доставку заказа в delivered и освобождает курьера
+func (s *CompleteService) Complete(ctx context.Context, orderID string) error {
+	err := s.deliveryRepo.WithTx(ctx, func(txCtx context.Context) error {
+		d, err := s.deliveryRepo.GetActiveByOrderID(txCtx, orderID)
 		if err != nil {
 			return err
 		}
-		return s.courierRepo.UpdateStatus(txCtx, d.CourierID, "available")
+
+		return transitionDelivery(
+			txCtx, s.deliveryRepo, s.courierRepo, s.outbox,
+			d, deliveryModel.DeliveryStatusDelivered, ReasonCompleted, s.nowFunc(),
+		)
+	})
+	if err != nil {
+		return err
+	}
+
+	db.AfterCommit(ctx, func() {
+		for _, n := range s.notifiers {
+			n.NotifyCourierAvailable()
+		}
 	})
+	return nil
 }
@@ -0,0 +1,96 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	deliveryModel "github.com/Avito-courses/course-go-avito-israpilovsha/internal/delivery/model"
+	deliveryRepo "github.com/Avito-courses/course-go-avito-israpilovsha/internal/delivery/repository"
+	"go.uber.org/zap"
+)
+
+// DeadlineCalculator считает дедлайн доставки при назначении
+type DeadlineCalculator interface {
+	Deadline(transport deliveryModel.TransportType, zone string, now time.Time) time.Time
+}
+
+// DeadlinePolicyService держит действующую политику дедлайнов в памяти
+// и периодически перечитывает её из БД, чтобы изменения применялись без деплоя.
+type DeadlinePolicyService struct {
+	repo    deliveryRepo.DeadlinePolicyRepository
+	log     *zap.SugaredLogger
+	current atomic.Pointer[deliveryModel.DeadlinePolicy]
+}
+
+func NewDeadlinePolicyService(repo deliveryRepo.DeadlinePolicyRepository, log *zap.SugaredLogger) *DeadlinePolicyService {
+	s := &DeadlinePolicyService{repo: repo, log: log}
+	s.current.Store(deliveryModel.DefaultDeadlinePolicy())
+	return s
+}
+
+// Current возвращает действующую политику; менять её нельзя, только заменить через Update
+func (s *DeadlinePolicyService) Current() *deliveryModel.DeadlinePolicy {
+	return s.current.Load()
+}
+
+func (s *DeadlinePolicyService) Deadline(transport deliveryModel.TransportType, zone string, now time.Time) time.Time {
+	return s.current.Load().Deadline(transport, zone, now)
+}
+
+// Reload перечитывает политику из БД. Если политики в БД нет или она некорректна,
+// продолжает действовать предыдущая.
+func (s *DeadlinePolicyService) Reload(ctx context.Context) error {
+	p, err := s.repo.Get(ctx)
+	if errors.Is(err, deliveryRepo.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := p.Validate(); err != nil {
+		return err
+	}
+
+	s.current.Store(p)
+	return nil
+}
+
+// Update проверяет и сохраняет новую политику, после чего она сразу начинает действовать
+func (s *DeadlinePolicyService) Update(ctx context.Context, p *deliveryModel.DeadlinePolicy) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+
+	if err := s.repo.Save(ctx, p); err != nil {
+		return err
+	}
+
+	s.current.Store(p)
+	s.log.Infow("deadline policy updated", "updated_at", p.UpdatedAt)
+	return nil
+}
+
+// StartReload — фоновая задача: перечитывает политику раз в interval,
+// чтобы изменения, сделанные через другой инстанс, доходили до всех
+func (s *DeadlinePolicyService) StartReload(ctx context.Context, interval time.Duration) {
+	if err := s.Reload(ctx); err != nil {
+		s.log.Warnw("deadline policy load failed, using previous", "err", err)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Reload(ctx); err != nil && ctx.Err() == nil {
+				s.log.Warnw("deadline policy reload failed", "err", err)
+			}
+		}
+	}
+}
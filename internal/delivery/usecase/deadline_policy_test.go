@@ -0,0 +1,196 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	deliveryModel "github.com/Avito-courses/course-go-avito-israpilovsha/internal/delivery/model"
+	deliveryMock "github.com/Avito-courses/course-go-avito-israpilovsha/internal/delivery/repository"
+	"github.com/Avito-courses/course-go-avito-israpilovsha/internal/delivery/usecase"
+	"github.com/golang/mock/gomock"
+	"go.uber.org/zap"
+)
+
+func TestDeadlinePolicyDeadline(t *testing.T) {
+	t.Parallel()
+
+	p := deliveryModel.DefaultDeadlinePolicy()
+	p.RushHours = []deliveryModel.RushHour{
+		{From: "18:00", To: "21:00", Multiplier: 2},
+		{From: "23:00", To: "01:00", Multiplier: 1.5},
+	}
+	p.Zones = map[string]deliveryModel.ZoneOverride{
+		"center": {
+			Transports: map[deliveryModel.TransportType]deliveryModel.Duration{
+				deliveryModel.TransportCar: deliveryModel.Duration(10 * time.Minute),
+			},
+			Multiplier: 1.5,
+		},
+		"suburb": {Default: deliveryModel.Duration(60 * time.Minute)},
+	}
+
+	day := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	rush := time.Date(2025, 1, 1, 19, 30, 0, 0, time.UTC)
+	night := time.Date(2025, 1, 1, 0, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		transport deliveryModel.TransportType
+		zone      string
+		now       time.Time
+		want      time.Duration
+	}{
+		{"car", deliveryModel.TransportCar, "", day, 5 * time.Minute},
+		{"unknown transport", "bike", "", day, 30 * time.Minute},
+		{"rush hour", deliveryModel.TransportScooter, "", rush, 30 * time.Minute},
+		{"rush over midnight", deliveryModel.TransportCar, "", night, 7*time.Minute + 30*time.Second},
+		{"zone transport", deliveryModel.TransportCar, "center", day, 15 * time.Minute},
+		{"zone falls back to transport", deliveryModel.TransportScooter, "suburb", day, 15 * time.Minute},
+		{"zone default", deliveryModel.TransportOnFoot, "suburb", day, 60 * time.Minute},
+		{"unknown zone", deliveryModel.TransportCar, "nowhere", day, 5 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := p.Deadline(tt.transport, tt.zone, tt.now).Sub(tt.now)
+			if got != tt.want {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestDeadlinePolicyRushHoursInTimeZone(t *testing.T) {
+	t.Parallel()
+
+	p := deliveryModel.DefaultDeadlinePolicy()
+	p.TimeZone = "Europe/Moscow"
+	p.RushHours = []deliveryModel.RushHour{{From: "18:00", To: "21:00", Multiplier: 2}}
+	if err := p.Validate(); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		now  time.Time
+		want time.Duration
+	}{
+		// 16:30 UTC — 19:30 по Москве
+		{"rush in local time", time.Date(2025, 1, 1, 16, 30, 0, 0, time.UTC), 10 * time.Minute},
+		// 19:30 UTC — 22:30 по Москве
+		{"after rush in local time", time.Date(2025, 1, 1, 19, 30, 0, 0, time.UTC), 5 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := p.Deadline(deliveryModel.TransportCar, "", tt.now).Sub(tt.now)
+			if got != tt.want {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestDeadlinePolicyValidate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		mutate func(p *deliveryModel.DeadlinePolicy)
+	}{
+		{"zero default", func(p *deliveryModel.DeadlinePolicy) { p.Default = 0 }},
+		{"negative transport", func(p *deliveryModel.DeadlinePolicy) {
+			p.Transports[deliveryModel.TransportCar] = -1
+		}},
+		{"bad rush clock", func(p *deliveryModel.DeadlinePolicy) {
+			p.RushHours = []deliveryModel.RushHour{{From: "25:00", To: "10:00", Multiplier: 1}}
+		}},
+		{"zero multiplier", func(p *deliveryModel.DeadlinePolicy) {
+			p.RushHours = []deliveryModel.RushHour{{From: "08:00", To: "10:00"}}
+		}},
+		{"unknown time zone", func(p *deliveryModel.DeadlinePolicy) { p.TimeZone = "Mars/Olympus" }},
+		{"local time zone", func(p *deliveryModel.DeadlinePolicy) { p.TimeZone = "Local" }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			p := deliveryModel.DefaultDeadlinePolicy()
+			tt.mutate(p)
+			if err := p.Validate(); !errors.Is(err, deliveryModel.ErrInvalidDeadlinePolicy) {
+				t.Fatalf("expected ErrInvalidDeadlinePolicy, got %v", err)
+			}
+		})
+	}
+}
+
+func TestDeadlinePolicyServiceReload(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := deliveryMock.NewMockDeadlinePolicyRepository(ctrl)
+	svc := usecase.NewDeadlinePolicyService(repo, zap.NewNop().Sugar())
+
+	stored := deliveryModel.DefaultDeadlinePolicy()
+	stored.Transports[deliveryModel.TransportCar] = deliveryModel.Duration(20 * time.Minute)
+
+	invalid := deliveryModel.DefaultDeadlinePolicy()
+	invalid.Default = 0
+
+	gomock.InOrder(
+		repo.EXPECT().Get(gomock.Any()).Return(nil, deliveryMock.ErrNotFound),
+		repo.EXPECT().Get(gomock.Any()).Return(stored, nil),
+		repo.EXPECT().Get(gomock.Any()).Return(invalid, nil),
+	)
+
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// политики в БД нет — действует политика по умолчанию
+	if err := svc.Reload(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := svc.Deadline(deliveryModel.TransportCar, "", now).Sub(now); got != 5*time.Minute {
+		t.Fatalf("expected default 5m, got %v", got)
+	}
+
+	if err := svc.Reload(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := svc.Deadline(deliveryModel.TransportCar, "", now).Sub(now); got != 20*time.Minute {
+		t.Fatalf("expected reloaded 20m, got %v", got)
+	}
+
+	// некорректная политика не заменяет действующую
+	if err := svc.Reload(context.Background()); !errors.Is(err, deliveryModel.ErrInvalidDeadlinePolicy) {
+		t.Fatalf("expected ErrInvalidDeadlinePolicy, got %v", err)
+	}
+	if svc.Current() != stored {
+		t.Fatal("invalid policy must not replace current one")
+	}
+}
+
+func TestDeadlinePolicyServiceUpdateRejectsInvalid(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := deliveryMock.NewMockDeadlinePolicyRepository(ctrl)
+	svc := usecase.NewDeadlinePolicyService(repo, zap.NewNop().Sugar())
+
+	p := deliveryModel.DefaultDeadlinePolicy()
+	p.Default = 0
+
+	if err := svc.Update(context.Background(), p); !errors.Is(err, deliveryModel.ErrInvalidDeadlinePolicy) {
+		t.Fatalf("expected ErrInvalidDeadlinePolicy, got %v", err)
+	}
+}
@@ -2,97 +2,542 @@ package usecase
 
 import (
 	"context"
+	"errors"
+	"slices"
+	"strconv"
 	"time"
 
 	courierModel "github.com/Avito-courses/course-go-avito-israpilovsha/internal/courier/model"
 	courierRepo "github.com/Avito-courses/course-go-avito-israpilovsha/internal/courier/repository"
+	"github.com/Avito-courses/course-go-avito-israpilovsha/internal/db"
 	deliveryModel "github.com/Avito-courses/course-go-avito-israpilovsha/internal/delivery/model"
 	deliveryRepo "github.com/Avito-courses/course-go-avito-israpilovsha/internal/delivery/repository"
+	"github.com/Avito-courses/course-go-avito-israpilovsha/internal/metrics"
+	outboxRepo "github.com/Avito-courses/course-go-avito-israpilovsha/internal/outbox/repository"
+	"go.uber.org/zap"
+)
+
+const (
+	// releaseBatchSize — сколько просроченных доставок обрабатывается за один тик
+	releaseBatchSize = 100
+
+	defaultListLimit = 50
+	maxListLimit     = 200
 )
 
 type DeliveryService struct {
 	courierRepo  courierRepo.CourierRepository
 	deliveryRepo deliveryRepo.DeliveryRepository
+	pendingRepo  deliveryRepo.PendingOrderRepository
+	strategy     AssignmentStrategy
+	deadlines    DeadlineCalculator
+	outbox       outboxRepo.OutboxWriter
+	log          *zap.SugaredLogger
 	nowFunc      func() time.Time
+
+	// reassignExpired — назначать ли заказ другому курьеру после просрочки
+	reassignExpired bool
+
+	// loadWindow — окно, за которое стратегии считают загрузку курьера; 0 — за всё время
+	loadWindow time.Duration
+
+	// drainCh будит разбор очереди, когда освобождается курьер
+	drainCh chan struct{}
+}
+
+type Option func(*DeliveryService)
+
+// WithPendingQueue включает очередь заказов, для которых не нашлось свободного курьера
+func WithPendingQueue(p deliveryRepo.PendingOrderRepository) Option {
+	return func(s *DeliveryService) {
+		s.pendingRepo = p
+	}
+}
+
+// WithStrategy задаёт стратегию выбора курьера; по умолчанию least_loaded
+func WithStrategy(st AssignmentStrategy) Option {
+	return func(s *DeliveryService) {
+		s.strategy = st
+	}
+}
+
+// WithLoadWindow задаёт окно подсчёта загрузки курьеров
+func WithLoadWindow(window time.Duration) Option {
+	return func(s *DeliveryService) {
+		s.loadWindow = window
+	}
+}
+
+// WithDeadlines задаёт расчёт дедлайнов; по умолчанию CalculateDeadline
+func WithDeadlines(d DeadlineCalculator) Option {
+	return func(s *DeliveryService) {
+		s.deadlines = d
+	}
+}
+
+// WithExpiredReassign включает переназначение заказа другому курьеру после просрочки доставки
+func WithExpiredReassign(enabled bool) Option {
+	return func(s *DeliveryService) {
+		s.reassignExpired = enabled
+	}
+}
+
+// WithOutbox включает запись событий доставки и курьеров в outbox
+func WithOutbox(w outboxRepo.OutboxWriter) Option {
+	return func(s *DeliveryService) {
+		s.outbox = w
+	}
 }
 
-func NewDeliveryService(c courierRepo.CourierRepository, d deliveryRepo.DeliveryRepository) *DeliveryService {
-	return &DeliveryService{
+func WithLogger(log *zap.SugaredLogger) Option {
+	return func(s *DeliveryService) {
+		s.log = log
+	}
+}
+
+func NewDeliveryService(
+	c courierRepo.CourierRepository,
+	d deliveryRepo.DeliveryRepository,
+	opts ...Option,
+) *DeliveryService {
+	s := &DeliveryService{
 		courierRepo:  c,
 		deliveryRepo: d,
+		strategy:     LeastLoadedStrategy{},
+		log:          zap.NewNop().Sugar(),
 		nowFunc:      time.Now,
+		drainCh:      make(chan struct{}, 1),
+	}
+
+	for _, opt := range opts {
+		opt(s)
 	}
+
+	return s
 }
 
+// Assign назначает заказ свободному курьеру
 func (s *DeliveryService) Assign(ctx context.Context, orderID string) (*deliveryModel.Delivery, *courierModel.Courier, error) {
-	c, err := s.courierRepo.FindAvailable(ctx)
+	return s.AssignOrder(ctx, deliveryModel.AssignRequest{OrderID: orderID})
+}
+
+// AssignOrder назначает заказ свободному курьеру.
+// Курьер занимается внутри той же транзакции, что и создание доставки,
+// поэтому параллельные назначения не получат одного и того же курьера.
+// Если у заказа уже есть активная доставка, возвращается она — повторное назначение безопасно.
+// Если свободных курьеров нет и очередь включена, заказ ставится в очередь
+// и возвращается deliveryModel.ErrOrderQueued. Назначенный заказ убирается из очереди,
+// если он там ждал.
+func (s *DeliveryService) AssignOrder(
+	ctx context.Context,
+	req deliveryModel.AssignRequest,
+) (*deliveryModel.Delivery, *courierModel.Courier, error) {
+	var (
+		delivery *deliveryModel.Delivery
+		courier  *courierModel.Courier
+		queued   bool
+		dequeued bool
+	)
+
+	err := s.deliveryRepo.WithTx(ctx, func(txCtx context.Context) error {
+		d, c, err := s.activeAssignment(txCtx, req.OrderID)
+		if err != nil || d != nil {
+			delivery, courier = d, c
+			return err
+		}
+
+		now := s.nowFunc()
+
+		d, c, err = s.assignTx(txCtx, req, ReasonAssigned, now)
+		if err != nil {
+			return err
+		}
+
+		if c != nil {
+			delivery, courier = d, c
+			if s.pendingRepo == nil {
+				return nil
+			}
+			// иначе строка очереди осталась бы и разбор очереди упёрся бы в уже назначенный заказ
+			dequeued, err = s.pendingRepo.Remove(txCtx, req.OrderID)
+			return err
+		}
+
+		if s.pendingRepo == nil {
+			return courierRepo.ErrNotFound
+		}
+
+		queued = true
+		return s.pendingRepo.Enqueue(txCtx, &deliveryModel.PendingOrder{
+			OrderID:    req.OrderID,
+			Priority:   req.Priority,
+			Size:       req.Size,
+			Zone:       req.Zone,
+			EnqueuedAt: now,
+		})
+	})
+
+	if errors.Is(err, deliveryRepo.ErrConflict) {
+		// параллельное назначение того же заказа успело первым
+		return s.activeAssignment(ctx, req.OrderID)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if queued {
+		s.refreshQueueDepth(ctx)
+		return nil, nil, deliveryModel.ErrOrderQueued
+	}
+	if dequeued {
+		s.refreshQueueDepth(ctx)
+	}
+
+	return delivery, courier, nil
+}
+
+// activeAssignment возвращает активную доставку заказа и её курьера; без неё — nil, nil, nil
+func (s *DeliveryService) activeAssignment(
+	ctx context.Context,
+	orderID string,
+) (*deliveryModel.Delivery, *courierModel.Courier, error) {
+	d, err := s.deliveryRepo.GetActiveByOrderID(ctx, orderID)
+	if errors.Is(err, deliveryRepo.ErrNotFound) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	c, err := s.courierRepo.GetByID(ctx, d.CourierID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return d, c, nil
+}
+
+// assignTx занимает курьера и создаёт доставку; без свободного курьера возвращает nil, nil, nil.
+// reason пишется в историю доставки. Должна вызываться внутри WithTx.
+func (s *DeliveryService) assignTx(
+	txCtx context.Context,
+	req deliveryModel.AssignRequest,
+	reason string,
+	now time.Time,
+) (*deliveryModel.Delivery, *courierModel.Courier, error) {
+	c, err := s.claimCourier(txCtx, req, now)
 	if err != nil {
 		return nil, nil, err
 	}
 	if c == nil {
-		return nil, nil, courierRepo.ErrNotFound
+		return nil, nil, nil
 	}
 
-	var delivery *deliveryModel.Delivery
+	delivery := &deliveryModel.Delivery{
+		CourierID:  c.ID,
+		OrderID:    req.OrderID,
+		Status:     deliveryModel.DeliveryStatusAssigned,
+		AssignedAt: now,
+		Deadline:   s.deadline(c.TransportType, req.Zone, now),
+		Priority:   req.Priority,
+		Size:       req.Size,
+		Zone:       req.Zone,
+	}
 
-	err = s.deliveryRepo.WithTx(ctx, func(txCtx context.Context) error {
+	if err := s.deliveryRepo.Create(txCtx, delivery); err != nil {
+		return nil, nil, err
+	}
 
-		now := s.nowFunc()
+	err = s.deliveryRepo.AddEvent(txCtx, &deliveryModel.DeliveryEvent{
+		DeliveryID: delivery.ID,
+		OrderID:    delivery.OrderID,
+		CourierID:  delivery.CourierID,
+		ToStatus:   delivery.Status,
+		Reason:     reason,
+		CreatedAt:  now,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := recordDelivery(txCtx, s.outbox, delivery, reason, now); err != nil {
+		return nil, nil, err
+	}
+
+	err = recordCourierStatus(
+		txCtx, s.outbox, c.ID,
+		courierModel.CourierStatusAvailable, courierModel.CourierStatusBusy, delivery.OrderID, now,
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return delivery, c, nil
+}
+
+func (s *DeliveryService) deadline(transport, zone string, now time.Time) time.Time {
+	if s.deadlines == nil {
+		return CalculateDeadline(transport, now)
+	}
+	return s.deadlines.Deadline(deliveryModel.TransportType(transport), zone, now)
+}
+
+// claimCourier занимает первого курьера в порядке стратегии.
+// Кандидата могло занять параллельное назначение, поэтому при неудаче пробуем следующего.
+func (s *DeliveryService) claimCourier(
+	txCtx context.Context,
+	req deliveryModel.AssignRequest,
+	now time.Time,
+) (*courierModel.Courier, error) {
+	var since time.Time
+	if s.loadWindow > 0 {
+		since = now.Add(-s.loadWindow)
+	}
 
-		delivery = &deliveryModel.Delivery{
-			CourierID:  c.ID,
-			OrderID:    orderID,
-			AssignedAt: now,
-			Deadline:   CalculateDeadline(c.TransportType, now),
+	candidates, err := s.courierRepo.ListAvailable(txCtx, since)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, cand := range s.strategy.Rank(req, candidates) {
+		if slices.Contains(req.ExcludeCourierIDs, cand.Courier.ID) {
+			continue
+		}
+
+		c, err := s.courierRepo.ClaimByID(txCtx, cand.Courier.ID)
+		if err != nil {
+			return nil, err
+		}
+		if c != nil {
+			return c, nil
 		}
+	}
+
+	return nil, nil
+}
+
+// Unassign отменяет активную доставку заказа; запись остаётся в истории со статусом cancelled.
+// Если заказ ещё ждёт в очереди, он просто убирается из неё.
+func (s *DeliveryService) Unassign(ctx context.Context, orderID string) (*deliveryModel.Delivery, error) {
+	var (
+		result   *deliveryModel.Delivery
+		dequeued bool
+	)
 
-		if err := s.deliveryRepo.Create(txCtx, delivery); err != nil {
+	err := s.deliveryRepo.WithTx(ctx, func(txCtx context.Context) error {
+		d, err := s.deliveryRepo.GetActiveByOrderID(txCtx, orderID)
+		if err != nil {
+			if errors.Is(err, deliveryRepo.ErrNotFound) && s.pendingRepo != nil {
+				removed, rmErr := s.pendingRepo.Remove(txCtx, orderID)
+				if rmErr != nil {
+					return rmErr
+				}
+				if removed {
+					dequeued = true
+					return nil
+				}
+			}
 			return err
 		}
 
-		if err := s.courierRepo.UpdateStatus(txCtx, c.ID, "busy"); err != nil {
+		err = transitionDelivery(
+			txCtx, s.deliveryRepo, s.courierRepo, s.outbox,
+			d, deliveryModel.DeliveryStatusCancelled, ReasonUnassigned, s.nowFunc(),
+		)
+		if err != nil {
 			return err
 		}
 
+		result = d
 		return nil
 	})
 
+	if err != nil {
+		return nil, err
+	}
+
+	if dequeued {
+		s.refreshQueueDepth(ctx)
+		return nil, deliveryModel.ErrOrderDequeued
+	}
+
+	db.AfterCommit(ctx, s.NotifyCourierAvailable)
+	return result, nil
+}
+
+// GetByOrderID возвращает последнюю доставку заказа вместе с историей переходов
+func (s *DeliveryService) GetByOrderID(
+	ctx context.Context,
+	orderID string,
+) (*deliveryModel.Delivery, []*deliveryModel.DeliveryEvent, error) {
+	d, err := s.deliveryRepo.GetByOrderID(ctx, orderID)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	return delivery, c, nil
+	events, err := s.deliveryRepo.ListEvents(ctx, d.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return d, events, nil
 }
 
-func (s *DeliveryService) Unassign(ctx context.Context, orderID string) (*deliveryModel.Delivery, error) {
-	var result *deliveryModel.Delivery
+// List возвращает страницу доставок по фильтру; NextCursor пуст на последней странице
+func (s *DeliveryService) List(ctx context.Context, f deliveryModel.DeliveryFilter) (*deliveryModel.DeliveryPage, error) {
+	if f.Limit <= 0 {
+		f.Limit = defaultListLimit
+	}
+	if f.Limit > maxListLimit {
+		f.Limit = maxListLimit
+	}
+
+	// берём на одну запись больше, чтобы понять, есть ли следующая страница
+	limit := f.Limit
+	f.Limit++
+
+	items, err := s.deliveryRepo.List(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &deliveryModel.DeliveryPage{Items: items}
+	if len(items) > limit {
+		page.Items = items[:limit]
+		page.NextCursor = strconv.FormatInt(page.Items[limit-1].ID, 10)
+	}
+
+	return page, nil
+}
+
+// ReleaseResult — итог одного прохода по просроченным доставкам
+type ReleaseResult struct {
+	Expired    int
+	Reassigned int
+	Queued     int
+}
+
+// ReleaseExpired помечает просроченные доставки как expired и освобождает курьеров.
+// Если включено переназначение, заказ сразу отдаётся другому курьеру,
+// а без свободных курьеров ставится в очередь ожидания.
+func (s *DeliveryService) ReleaseExpired(ctx context.Context) (ReleaseResult, error) {
+	var res ReleaseResult
 
 	err := s.deliveryRepo.WithTx(ctx, func(txCtx context.Context) error {
-		d, err := s.deliveryRepo.DeleteByOrderID(txCtx, orderID)
+		res = ReleaseResult{}
+		now := s.nowFunc()
+
+		overdue, err := s.deliveryRepo.ListOverdue(txCtx, now, releaseBatchSize)
 		if err != nil {
 			return err
 		}
-		result = d
 
-		if err := s.courierRepo.UpdateStatus(txCtx, d.CourierID, "available"); err != nil {
-			return err
+		for _, d := range overdue {
+			missedBy := d.CourierID
+
+			err := transitionDelivery(
+				txCtx, s.deliveryRepo, s.courierRepo, s.outbox,
+				d, deliveryModel.DeliveryStatusExpired, ReasonDeadlineMissed, now,
+			)
+			if err != nil {
+				return err
+			}
+			res.Expired++
+
+			s.log.Infow("delivery expired",
+				"order_id", d.OrderID,
+				"delivery_id", d.ID,
+				"courier_id", missedBy,
+				"deadline", d.Deadline,
+			)
+
+			if !s.reassignExpired {
+				continue
+			}
+
+			reassigned, queued, err := s.reassignTx(txCtx, d, missedBy, now)
+			if err != nil {
+				return err
+			}
+			if reassigned {
+				res.Reassigned++
+			}
+			if queued {
+				res.Queued++
+			}
 		}
 
 		return nil
 	})
 
 	if err != nil {
-		return nil, err
+		return ReleaseResult{}, err
 	}
 
-	return result, nil
+	metrics.DeliveriesExpiredTotal.Add(float64(res.Expired))
+	metrics.DeliveriesReassignedTotal.WithLabelValues("reassigned").Add(float64(res.Reassigned))
+	metrics.DeliveriesReassignedTotal.WithLabelValues("queued").Add(float64(res.Queued))
+
+	if res.Queued > 0 {
+		s.refreshQueueDepth(ctx)
+	}
+	if res.Expired > 0 {
+		s.NotifyCourierAvailable()
+	}
+	return res, nil
 }
 
-// ReleaseExpired проверяет просроченные заказы и освобождает курьеров
-func (s *DeliveryService) ReleaseExpired(ctx context.Context) error {
-	now := s.nowFunc()
-	_, err := s.courierRepo.ReleaseExpired(ctx, now)
-	return err
+// reassignTx отдаёт заказ просроченной доставки другому курьеру с теми же параметрами заказа.
+// Без свободного курьера заказ ставится в очередь, если она включена;
+// из очереди он достанется первому освободившемуся курьеру, кроме просрочившего.
+func (s *DeliveryService) reassignTx(
+	txCtx context.Context,
+	expired *deliveryModel.Delivery,
+	missedBy int64,
+	now time.Time,
+) (reassigned, queued bool, err error) {
+	orderID := expired.OrderID
+	req := deliveryModel.AssignRequest{
+		OrderID:           orderID,
+		Priority:          expired.Priority,
+		Size:              expired.Size,
+		Zone:              expired.Zone,
+		ExcludeCourierIDs: []int64{missedBy},
+	}
+
+	d, c, err := s.assignTx(txCtx, req, ReasonReassigned, now)
+	if err != nil {
+		return false, false, err
+	}
+
+	if c != nil {
+		s.log.Infow("expired order reassigned",
+			"order_id", orderID,
+			"delivery_id", d.ID,
+			"courier_id", c.ID,
+			"missed_by", missedBy,
+		)
+		return true, false, nil
+	}
+
+	if s.pendingRepo == nil {
+		s.log.Warnw("expired order left without courier", "order_id", orderID, "missed_by", missedBy)
+		return false, false, nil
+	}
+
+	err = s.pendingRepo.Enqueue(txCtx, &deliveryModel.PendingOrder{
+		OrderID:           orderID,
+		Priority:          req.Priority,
+		Size:              req.Size,
+		Zone:              req.Zone,
+		ExcludeCourierIDs: req.ExcludeCourierIDs,
+		EnqueuedAt:        now,
+	})
+	if err != nil {
+		return false, false, err
+	}
+
+	return false, true, nil
 }
 
 // StartAutoRelease — фоновая задача
@@ -104,7 +549,22 @@ func (s *DeliveryService) StartAutoRelease(ctx context.Context, interval time.Du
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
-			_ = s.ReleaseExpired(ctx)
+			res, err := s.ReleaseExpired(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					metrics.AutoReleaseErrorsTotal.Inc()
+					s.log.Warnw("expired delivery release failed", "err", err)
+				}
+				continue
+			}
+
+			if res.Expired > 0 {
+				s.log.Infow("expired deliveries released",
+					"expired", res.Expired,
+					"reassigned", res.Reassigned,
+					"queued", res.Queued,
+				)
+			}
 		}
 	}
 }
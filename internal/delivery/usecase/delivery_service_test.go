@@ -3,6 +3,7 @@ package usecase_test
 import (
 	"context"
 	"errors"
+	"slices"
 	"testing"
 	"time"
 
@@ -11,6 +12,7 @@ import (
 	deliveryModel "github.com/Avito-courses/course-go-avito-israpilovsha/internal/delivery/model"
 	deliveryMock "github.com/Avito-courses/course-go-avito-israpilovsha/internal/delivery/repository"
 	"github.com/Avito-courses/course-go-avito-israpilovsha/internal/delivery/usecase"
+	outboxModel "github.com/Avito-courses/course-go-avito-israpilovsha/internal/outbox/model"
 	"github.com/golang/mock/gomock"
 )
 
@@ -30,13 +32,22 @@ func TestAssignSuccess(t *testing.T) {
 		TransportType: "car",
 	}
 
-	cRepo.EXPECT().FindAvailable(gomock.Any()).Return(c, nil)
 	dRepo.EXPECT().WithTx(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, fn func(context.Context) error) error {
 		return fn(context.Background())
 	})
 
+	dRepo.EXPECT().GetActiveByOrderID(gomock.Any(), gomock.Any()).Return(nil, deliveryMock.ErrNotFound)
+	cRepo.EXPECT().ListAvailable(gomock.Any(), gomock.Any()).
+		Return([]*courierModel.Candidate{{Courier: c}}, nil)
+	cRepo.EXPECT().ClaimByID(gomock.Any(), c.ID).Return(c, nil)
 	dRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil)
-	cRepo.EXPECT().UpdateStatus(gomock.Any(), int64(1), "busy").Return(nil)
+	dRepo.EXPECT().AddEvent(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, e *deliveryModel.DeliveryEvent) error {
+			if e.ToStatus != deliveryModel.DeliveryStatusAssigned || e.FromStatus != "" {
+				t.Fatalf("unexpected event %+v", e)
+			}
+			return nil
+		})
 
 	_, courier, err := svc.Assign(context.Background(), orderID)
 	if err != nil {
@@ -58,11 +69,15 @@ func TestAssignNoCouriers(t *testing.T) {
 
 	svc := usecase.NewDeliveryService(cRepo, dRepo)
 
-	cRepo.EXPECT().FindAvailable(gomock.Any()).Return(nil, nil)
+	dRepo.EXPECT().WithTx(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, fn func(context.Context) error) error {
+		return fn(context.Background())
+	})
+	dRepo.EXPECT().GetActiveByOrderID(gomock.Any(), gomock.Any()).Return(nil, deliveryMock.ErrNotFound)
+	cRepo.EXPECT().ListAvailable(gomock.Any(), gomock.Any()).Return(nil, nil)
 
 	_, _, err := svc.Assign(context.Background(), "x")
-	if err == nil {
-		t.Fatalf("expected error, got nil")
+	if !errors.Is(err, courierMock.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
 	}
 }
 
@@ -78,11 +93,14 @@ func TestAssignRepoError(t *testing.T) {
 
 	c := &courierModel.Courier{ID: 1, TransportType: "car"}
 
-	cRepo.EXPECT().FindAvailable(gomock.Any()).Return(c, nil)
 	dRepo.EXPECT().WithTx(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, fn func(context.Context) error) error {
 		return fn(context.Background())
 	})
 
+	dRepo.EXPECT().GetActiveByOrderID(gomock.Any(), gomock.Any()).Return(nil, deliveryMock.ErrNotFound)
+	cRepo.EXPECT().ListAvailable(gomock.Any(), gomock.Any()).
+		Return([]*courierModel.Candidate{{Courier: c}}, nil)
+	cRepo.EXPECT().ClaimByID(gomock.Any(), c.ID).Return(c, nil)
 	dRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(errors.New("db error"))
 
 	_, _, err := svc.Assign(context.Background(), "x")
@@ -101,15 +119,23 @@ func TestUnassignSuccess(t *testing.T) {
 	dRepo := deliveryMock.NewMockDeliveryRepository(ctrl)
 	svc := usecase.NewDeliveryService(cRepo, dRepo)
 
-	d := &deliveryModel.Delivery{ID: 1, CourierID: 10, OrderID: "abc"}
+	d := &deliveryModel.Delivery{ID: 1, CourierID: 10, OrderID: "abc", Status: deliveryModel.DeliveryStatusAssigned}
 
 	dRepo.EXPECT().WithTx(gomock.Any(), gomock.Any()).
 		DoAndReturn(func(_ context.Context, fn func(context.Context) error) error {
 			return fn(context.Background())
 		})
 
-	dRepo.EXPECT().DeleteByOrderID(gomock.Any(), "abc").Return(d, nil)
-	cRepo.EXPECT().UpdateStatus(gomock.Any(), int64(10), "available").Return(nil)
+	dRepo.EXPECT().GetActiveByOrderID(gomock.Any(), "abc").Return(d, nil)
+	dRepo.EXPECT().UpdateStatus(gomock.Any(), int64(1), deliveryModel.DeliveryStatusCancelled, gomock.Any()).Return(nil)
+	dRepo.EXPECT().AddEvent(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, e *deliveryModel.DeliveryEvent) error {
+			if e.FromStatus != deliveryModel.DeliveryStatusAssigned || e.ToStatus != deliveryModel.DeliveryStatusCancelled {
+				t.Errorf("unexpected event %+v", e)
+			}
+			return nil
+		})
+	cRepo.EXPECT().ReleaseByID(gomock.Any(), int64(10)).Return(true, nil)
 
 	out, err := svc.Unassign(context.Background(), "abc")
 	if err != nil {
@@ -118,6 +144,9 @@ func TestUnassignSuccess(t *testing.T) {
 	if out.CourierID != 10 {
 		t.Fatalf("expected courierID=10, got %d", out.CourierID)
 	}
+	if out.Status != deliveryModel.DeliveryStatusCancelled || out.CancelledAt == nil {
+		t.Fatalf("expected cancelled delivery with cancelled_at, got %+v", out)
+	}
 }
 
 func TestUnassignNotFound(t *testing.T) {
@@ -135,7 +164,7 @@ func TestUnassignNotFound(t *testing.T) {
 			return fn(context.Background())
 		})
 
-	dRepo.EXPECT().DeleteByOrderID(gomock.Any(), "x").Return(nil, errors.New("not found"))
+	dRepo.EXPECT().GetActiveByOrderID(gomock.Any(), "x").Return(nil, deliveryMock.ErrNotFound)
 
 	_, err := svc.Unassign(context.Background(), "x")
 	if err == nil {
@@ -153,15 +182,17 @@ func TestUnassignUpdateStatusFails(t *testing.T) {
 	dRepo := deliveryMock.NewMockDeliveryRepository(ctrl)
 	svc := usecase.NewDeliveryService(cRepo, dRepo)
 
-	d := &deliveryModel.Delivery{ID: 1, CourierID: 10, OrderID: "abc"}
+	d := &deliveryModel.Delivery{ID: 1, CourierID: 10, OrderID: "abc", Status: deliveryModel.DeliveryStatusAssigned}
 
 	dRepo.EXPECT().WithTx(gomock.Any(), gomock.Any()).
 		DoAndReturn(func(_ context.Context, fn func(context.Context) error) error {
 			return fn(context.Background())
 		})
 
-	dRepo.EXPECT().DeleteByOrderID(gomock.Any(), "abc").Return(d, nil)
-	cRepo.EXPECT().UpdateStatus(gomock.Any(), int64(10), "available").Return(errors.New("fail"))
+	dRepo.EXPECT().GetActiveByOrderID(gomock.Any(), "abc").Return(d, nil)
+	dRepo.EXPECT().UpdateStatus(gomock.Any(), int64(1), deliveryModel.DeliveryStatusCancelled, gomock.Any()).Return(nil)
+	dRepo.EXPECT().AddEvent(gomock.Any(), gomock.Any()).Return(nil)
+	cRepo.EXPECT().ReleaseByID(gomock.Any(), int64(10)).Return(false, errors.New("fail"))
 
 	_, err := svc.Unassign(context.Background(), "abc")
 	if err == nil {
@@ -179,13 +210,111 @@ func TestReleaseExpiredSuccess(t *testing.T) {
 	dRepo := deliveryMock.NewMockDeliveryRepository(ctrl)
 	svc := usecase.NewDeliveryService(cRepo, dRepo)
 
-	cRepo.EXPECT().
-		ReleaseExpired(gomock.Any(), gomock.Any()).
-		Return(int64(5), nil)
+	overdue := []*deliveryModel.Delivery{
+		{ID: 1, CourierID: 10, OrderID: "a", Status: deliveryModel.DeliveryStatusAssigned},
+		{ID: 2, CourierID: 20, OrderID: "b", Status: deliveryModel.DeliveryStatusPickedUp},
+	}
+
+	dRepo.EXPECT().WithTx(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, fn func(context.Context) error) error {
+			return fn(context.Background())
+		})
+	dRepo.EXPECT().ListOverdue(gomock.Any(), gomock.Any(), gomock.Any()).Return(overdue, nil)
+	dRepo.EXPECT().UpdateStatus(gomock.Any(), int64(1), deliveryModel.DeliveryStatusExpired, gomock.Any()).Return(nil)
+	dRepo.EXPECT().UpdateStatus(gomock.Any(), int64(2), deliveryModel.DeliveryStatusExpired, gomock.Any()).Return(nil)
+	dRepo.EXPECT().AddEvent(gomock.Any(), gomock.Any()).Times(2).Return(nil)
+	cRepo.EXPECT().ReleaseByID(gomock.Any(), int64(10)).Return(true, nil)
+	cRepo.EXPECT().ReleaseByID(gomock.Any(), int64(20)).Return(true, nil)
+
+	res, err := svc.ReleaseExpired(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.Expired != 2 || res.Reassigned != 0 {
+		t.Fatalf("unexpected result %+v", res)
+	}
+}
+
+// TestReleaseExpiredReassigns - заказ отдаётся другому курьеру, а без свободных ставится в очередь
+func TestReleaseExpiredReassigns(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cRepo := courierMock.NewMockCourierRepository(ctrl)
+	dRepo := deliveryMock.NewMockDeliveryRepository(ctrl)
+	pRepo := deliveryMock.NewMockPendingOrderRepository(ctrl)
+	svc := usecase.NewDeliveryService(cRepo, dRepo,
+		usecase.WithPendingQueue(pRepo),
+		usecase.WithExpiredReassign(true),
+	)
+
+	overdue := []*deliveryModel.Delivery{
+		{ID: 1, CourierID: 10, OrderID: "a", Status: deliveryModel.DeliveryStatusAssigned},
+		{
+			ID: 2, CourierID: 20, OrderID: "b", Status: deliveryModel.DeliveryStatusAssigned,
+			Priority: 5, Size: deliveryModel.OrderSizeLarge, Zone: "center",
+		},
+	}
+
+	missed := &courierModel.Courier{ID: 10, TransportType: "car"}
+	other := &courierModel.Courier{ID: 30, TransportType: "car"}
 
-	if err := svc.ReleaseExpired(context.Background()); err != nil {
+	dRepo.EXPECT().WithTx(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, fn func(context.Context) error) error {
+			return fn(context.Background())
+		})
+	dRepo.EXPECT().ListOverdue(gomock.Any(), gomock.Any(), gomock.Any()).Return(overdue, nil)
+	dRepo.EXPECT().UpdateStatus(gomock.Any(), gomock.Any(), deliveryModel.DeliveryStatusExpired, gomock.Any()).
+		Times(2).Return(nil)
+	cRepo.EXPECT().ReleaseByID(gomock.Any(), gomock.Any()).Times(2).Return(true, nil)
+
+	gomock.InOrder(
+		cRepo.EXPECT().ListAvailable(gomock.Any(), gomock.Any()).
+			Return([]*courierModel.Candidate{{Courier: missed}, {Courier: other}}, nil),
+		cRepo.EXPECT().ListAvailable(gomock.Any(), gomock.Any()).Return(nil, nil),
+	)
+	// курьер, пропустивший дедлайн, заказ обратно не получает
+	cRepo.EXPECT().ClaimByID(gomock.Any(), int64(30)).Return(other, nil)
+	dRepo.EXPECT().Create(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, d *deliveryModel.Delivery) error {
+			if d.OrderID != "a" || d.CourierID != 30 {
+				t.Errorf("unexpected reassigned delivery %+v", d)
+			}
+			return nil
+		})
+
+	var reasons []string
+	dRepo.EXPECT().AddEvent(gomock.Any(), gomock.Any()).Times(3).
+		DoAndReturn(func(_ context.Context, e *deliveryModel.DeliveryEvent) error {
+			reasons = append(reasons, e.Reason)
+			return nil
+		})
+
+	pRepo.EXPECT().Enqueue(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, p *deliveryModel.PendingOrder) error {
+			// заказ сохраняет параметры и не вернётся к просрочившему курьеру
+			if p.OrderID != "b" || p.Priority != 5 || p.Size != deliveryModel.OrderSizeLarge ||
+				p.Zone != "center" || !slices.Equal(p.ExcludeCourierIDs, []int64{20}) {
+				t.Errorf("unexpected queued order %+v", p)
+			}
+			return nil
+		})
+	pRepo.EXPECT().Depth(gomock.Any()).Return(int64(1), nil)
+
+	res, err := svc.ReleaseExpired(context.Background())
+	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
+
+	want := usecase.ReleaseResult{Expired: 2, Reassigned: 1, Queued: 1}
+	if res != want {
+		t.Fatalf("expected %+v, got %+v", want, res)
+	}
+	if len(reasons) != 3 || reasons[1] != usecase.ReasonReassigned {
+		t.Fatalf("unexpected event reasons %v", reasons)
+	}
 }
 
 func TestReleaseExpiredError(t *testing.T) {
@@ -198,11 +327,13 @@ func TestReleaseExpiredError(t *testing.T) {
 	dRepo := deliveryMock.NewMockDeliveryRepository(ctrl)
 	svc := usecase.NewDeliveryService(cRepo, dRepo)
 
-	cRepo.EXPECT().
-		ReleaseExpired(gomock.Any(), gomock.Any()).
-		Return(int64(0), errors.New("db error"))
+	dRepo.EXPECT().WithTx(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, fn func(context.Context) error) error {
+			return fn(context.Background())
+		})
+	dRepo.EXPECT().ListOverdue(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, errors.New("db error"))
 
-	if err := svc.ReleaseExpired(context.Background()); err == nil {
+	if _, err := svc.ReleaseExpired(context.Background()); err == nil {
 		t.Fatalf("expected error, got nil")
 	}
 }
@@ -216,11 +347,16 @@ func TestStartAutoRelease(t *testing.T) {
 	dRepo := deliveryMock.NewMockDeliveryRepository(ctrl)
 	svc := usecase.NewDeliveryService(cRepo, dRepo)
 
-	// ReleaseExpired внутри будет вызывать courierRepo.ReleaseExpired
-	cRepo.EXPECT().
-		ReleaseExpired(gomock.Any(), gomock.Any()).
+	// ReleaseExpired внутри будет искать просроченные доставки
+	dRepo.EXPECT().WithTx(gomock.Any(), gomock.Any()).
 		AnyTimes().
-		Return(int64(0), nil)
+		DoAndReturn(func(_ context.Context, fn func(context.Context) error) error {
+			return fn(context.Background())
+		})
+	dRepo.EXPECT().
+		ListOverdue(gomock.Any(), gomock.Any(), gomock.Any()).
+		AnyTimes().
+		Return(nil, nil)
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -230,6 +366,184 @@ func TestStartAutoRelease(t *testing.T) {
 	time.Sleep(25 * time.Millisecond)
 }
 
+func TestCompleteSuccess(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cRepo := courierMock.NewMockCourierRepository(ctrl)
+	dRepo := deliveryMock.NewMockDeliveryRepository(ctrl)
+	svc := usecase.NewCompleteService(dRepo, cRepo)
+
+	d := &deliveryModel.Delivery{ID: 3, CourierID: 7, OrderID: "done", Status: deliveryModel.DeliveryStatusPickedUp}
+
+	dRepo.EXPECT().WithTx(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, fn func(context.Context) error) error {
+			return fn(context.Background())
+		})
+	dRepo.EXPECT().GetActiveByOrderID(gomock.Any(), "done").Return(d, nil)
+	dRepo.EXPECT().UpdateStatus(gomock.Any(), int64(3), deliveryModel.DeliveryStatusDelivered, gomock.Any()).Return(nil)
+	dRepo.EXPECT().AddEvent(gomock.Any(), gomock.Any()).Return(nil)
+	cRepo.EXPECT().ReleaseByID(gomock.Any(), int64(7)).Return(true, nil)
+
+	if err := svc.Complete(context.Background(), "done"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if d.Status != deliveryModel.DeliveryStatusDelivered || d.CompletedAt == nil {
+		t.Fatalf("expected delivered with completed_at, got %+v", d)
+	}
+}
+
+func TestPickUp(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		status  deliveryModel.DeliveryStatus
+		wantErr error
+	}{
+		{"assigned", deliveryModel.DeliveryStatusAssigned, nil},
+		{"already picked up", deliveryModel.DeliveryStatusPickedUp, deliveryModel.ErrInvalidTransition},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			cRepo := courierMock.NewMockCourierRepository(ctrl)
+			dRepo := deliveryMock.NewMockDeliveryRepository(ctrl)
+			outbox := &recordingOutbox{}
+			svc := usecase.NewCompleteService(dRepo, cRepo, usecase.CompleteWithOutbox(outbox))
+
+			d := &deliveryModel.Delivery{ID: 3, CourierID: 7, OrderID: "o", Status: tt.status}
+
+			dRepo.EXPECT().WithTx(gomock.Any(), gomock.Any()).
+				DoAndReturn(func(_ context.Context, fn func(context.Context) error) error {
+					return fn(context.Background())
+				})
+			dRepo.EXPECT().GetActiveByOrderID(gomock.Any(), "o").Return(d, nil)
+			if tt.wantErr == nil {
+				dRepo.EXPECT().UpdateStatus(gomock.Any(), int64(3), deliveryModel.DeliveryStatusPickedUp, gomock.Any()).Return(nil)
+				dRepo.EXPECT().AddEvent(gomock.Any(), gomock.Any()).Return(nil)
+			}
+
+			// курьер остаётся занятым: cRepo.ReleaseByID не ожидается
+			if err := svc.PickUp(context.Background(), "o"); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if d.Status != deliveryModel.DeliveryStatusPickedUp {
+				t.Fatalf("expected picked_up, got %s", d.Status)
+			}
+			if len(outbox.events) != 1 || outbox.events[0].Type != outboxModel.EventDeliveryPickedUp {
+				t.Fatalf("expected one delivery.picked_up event, got %+v", outbox.events)
+			}
+		})
+	}
+}
+
+type recordingOutbox struct{ events []*outboxModel.Event }
+
+func (o *recordingOutbox) Add(_ context.Context, e *outboxModel.Event) error {
+	o.events = append(o.events, e)
+	return nil
+}
+
+func TestCompleteWritesOutboxEvents(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cRepo := courierMock.NewMockCourierRepository(ctrl)
+	dRepo := deliveryMock.NewMockDeliveryRepository(ctrl)
+	outbox := &recordingOutbox{}
+	svc := usecase.NewCompleteService(dRepo, cRepo, usecase.CompleteWithOutbox(outbox))
+
+	d := &deliveryModel.Delivery{ID: 3, CourierID: 7, OrderID: "done", Status: deliveryModel.DeliveryStatusPickedUp}
+
+	dRepo.EXPECT().WithTx(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, fn func(context.Context) error) error {
+			return fn(context.Background())
+		})
+	dRepo.EXPECT().GetActiveByOrderID(gomock.Any(), "done").Return(d, nil)
+	dRepo.EXPECT().UpdateStatus(gomock.Any(), int64(3), deliveryModel.DeliveryStatusDelivered, gomock.Any()).Return(nil)
+	dRepo.EXPECT().AddEvent(gomock.Any(), gomock.Any()).Return(nil)
+	cRepo.EXPECT().ReleaseByID(gomock.Any(), int64(7)).Return(true, nil)
+
+	if err := svc.Complete(context.Background(), "done"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(outbox.events) != 2 {
+		t.Fatalf("expected 2 outbox events, got %d", len(outbox.events))
+	}
+	if e := outbox.events[0]; e.Type != outboxModel.EventDeliveryCompleted || e.Key != "done" {
+		t.Fatalf("unexpected delivery event %+v", e)
+	}
+	if e := outbox.events[1]; e.Type != outboxModel.EventCourierStatusChanged || e.Key != outboxModel.CourierKey(7) {
+		t.Fatalf("unexpected courier event %+v", e)
+	}
+}
+
+// TestCompleteKeepsCourierThatIsNotBusy — курьера на паузе доставка не делает свободным
+func TestCompleteKeepsCourierThatIsNotBusy(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cRepo := courierMock.NewMockCourierRepository(ctrl)
+	dRepo := deliveryMock.NewMockDeliveryRepository(ctrl)
+	outbox := &recordingOutbox{}
+	svc := usecase.NewCompleteService(dRepo, cRepo, usecase.CompleteWithOutbox(outbox))
+
+	d := &deliveryModel.Delivery{ID: 3, CourierID: 7, OrderID: "done", Status: deliveryModel.DeliveryStatusAssigned}
+
+	dRepo.EXPECT().WithTx(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, fn func(context.Context) error) error {
+			return fn(context.Background())
+		})
+	dRepo.EXPECT().GetActiveByOrderID(gomock.Any(), "done").Return(d, nil)
+	dRepo.EXPECT().UpdateStatus(gomock.Any(), int64(3), deliveryModel.DeliveryStatusDelivered, gomock.Any()).Return(nil)
+	dRepo.EXPECT().AddEvent(gomock.Any(), gomock.Any()).Return(nil)
+	cRepo.EXPECT().ReleaseByID(gomock.Any(), int64(7)).Return(false, nil)
+
+	if err := svc.Complete(context.Background(), "done"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(outbox.events) != 1 || outbox.events[0].Type != outboxModel.EventDeliveryCompleted {
+		t.Fatalf("expected only delivery.completed, got %+v", outbox.events)
+	}
+}
+
+func TestCompleteNotFound(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cRepo := courierMock.NewMockCourierRepository(ctrl)
+	dRepo := deliveryMock.NewMockDeliveryRepository(ctrl)
+	svc := usecase.NewCompleteService(dRepo, cRepo)
+
+	dRepo.EXPECT().WithTx(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, fn func(context.Context) error) error {
+			return fn(context.Background())
+		})
+	dRepo.EXPECT().GetActiveByOrderID(gomock.Any(), "missing").Return(nil, deliveryMock.ErrNotFound)
+
+	if err := svc.Complete(context.Background(), "missing"); !errors.Is(err, deliveryMock.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
 func TestCalculateDeadline(t *testing.T) {
 	t.Parallel()
 
@@ -259,3 +573,401 @@ func TestCalculateDeadline(t *testing.T) {
 		})
 	}
 }
+
+func TestListPagination(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cRepo := courierMock.NewMockCourierRepository(ctrl)
+	dRepo := deliveryMock.NewMockDeliveryRepository(ctrl)
+	svc := usecase.NewDeliveryService(cRepo, dRepo)
+
+	dRepo.EXPECT().List(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, f deliveryModel.DeliveryFilter) ([]*deliveryModel.Delivery, error) {
+			if f.Limit != 3 {
+				t.Errorf("expected repo limit 3, got %d", f.Limit)
+			}
+			return []*deliveryModel.Delivery{{ID: 30}, {ID: 20}, {ID: 10}}, nil
+		})
+
+	page, err := svc.List(context.Background(), deliveryModel.DeliveryFilter{Limit: 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(page.Items) != 2 || page.NextCursor != "20" {
+		t.Fatalf("expected 2 items and cursor 20, got %d items, cursor %q", len(page.Items), page.NextCursor)
+	}
+}
+
+func TestAssignQueuesWhenNoCourier(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cRepo := courierMock.NewMockCourierRepository(ctrl)
+	dRepo := deliveryMock.NewMockDeliveryRepository(ctrl)
+	pRepo := deliveryMock.NewMockPendingOrderRepository(ctrl)
+	svc := usecase.NewDeliveryService(cRepo, dRepo, usecase.WithPendingQueue(pRepo))
+
+	dRepo.EXPECT().WithTx(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, fn func(context.Context) error) error {
+			return fn(context.Background())
+		})
+	dRepo.EXPECT().GetActiveByOrderID(gomock.Any(), gomock.Any()).Return(nil, deliveryMock.ErrNotFound)
+	cRepo.EXPECT().ListAvailable(gomock.Any(), gomock.Any()).Return(nil, nil)
+	pRepo.EXPECT().Enqueue(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, p *deliveryModel.PendingOrder) error {
+			if p.OrderID != "q" || p.Priority != 3 {
+				t.Errorf("unexpected pending order %+v", p)
+			}
+			return nil
+		})
+	pRepo.EXPECT().Depth(gomock.Any()).Return(int64(1), nil)
+
+	_, _, err := svc.AssignOrder(context.Background(), deliveryModel.AssignRequest{OrderID: "q", Priority: 3})
+	if !errors.Is(err, deliveryModel.ErrOrderQueued) {
+		t.Fatalf("expected ErrOrderQueued, got %v", err)
+	}
+}
+
+func TestDrainQueueAssignsUntilNoCourier(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cRepo := courierMock.NewMockCourierRepository(ctrl)
+	dRepo := deliveryMock.NewMockDeliveryRepository(ctrl)
+	pRepo := deliveryMock.NewMockPendingOrderRepository(ctrl)
+	svc := usecase.NewDeliveryService(cRepo, dRepo, usecase.WithPendingQueue(pRepo))
+
+	dRepo.EXPECT().WithTx(gomock.Any(), gomock.Any()).
+		Times(2).
+		DoAndReturn(func(_ context.Context, fn func(context.Context) error) error {
+			return fn(context.Background())
+		})
+
+	first := &deliveryModel.PendingOrder{
+		OrderID:           "first",
+		ExcludeCourierIDs: []int64{2},
+		EnqueuedAt:        time.Now().Add(-time.Minute),
+	}
+	second := &deliveryModel.PendingOrder{OrderID: "second", EnqueuedAt: time.Now()}
+	gomock.InOrder(
+		pRepo.EXPECT().Next(gomock.Any(), gomock.Any()).Return(first, nil),
+		pRepo.EXPECT().Next(gomock.Any(), gomock.Any()).Return(second, nil),
+	)
+	dRepo.EXPECT().GetActiveByOrderID(gomock.Any(), gomock.Any()).Times(2).Return(nil, deliveryMock.ErrNotFound)
+	c := &courierModel.Courier{ID: 1}
+	// курьер 2 просрочил этот заказ раньше и пропускается
+	excluded := &courierModel.Courier{ID: 2}
+	gomock.InOrder(
+		cRepo.EXPECT().ListAvailable(gomock.Any(), gomock.Any()).
+			Return([]*courierModel.Candidate{{Courier: excluded}, {Courier: c}}, nil),
+		cRepo.EXPECT().ListAvailable(gomock.Any(), gomock.Any()).Return(nil, nil),
+		// для второго заказа курьера нет — проверяем, остались ли свободные вообще
+		cRepo.EXPECT().ListAvailable(gomock.Any(), gomock.Any()).Return(nil, nil),
+	)
+	cRepo.EXPECT().ClaimByID(gomock.Any(), int64(1)).Return(c, nil)
+	dRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil)
+	dRepo.EXPECT().AddEvent(gomock.Any(), gomock.Any()).Return(nil)
+	pRepo.EXPECT().Remove(gomock.Any(), "first").Return(true, nil)
+	pRepo.EXPECT().Depth(gomock.Any()).Return(int64(1), nil)
+
+	assigned, err := svc.DrainQueue(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if assigned != 1 {
+		t.Fatalf("expected 1 assigned, got %d", assigned)
+	}
+}
+
+// TestDrainQueueSkipsUnservableHead - голова очереди исключает единственного свободного курьера,
+// но заказ за ней всё равно назначается
+func TestDrainQueueSkipsUnservableHead(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cRepo := courierMock.NewMockCourierRepository(ctrl)
+	dRepo := deliveryMock.NewMockDeliveryRepository(ctrl)
+	pRepo := deliveryMock.NewMockPendingOrderRepository(ctrl)
+	svc := usecase.NewDeliveryService(cRepo, dRepo, usecase.WithPendingQueue(pRepo))
+
+	dRepo.EXPECT().WithTx(gomock.Any(), gomock.Any()).
+		Times(3).
+		DoAndReturn(func(_ context.Context, fn func(context.Context) error) error {
+			return fn(context.Background())
+		})
+
+	head := &deliveryModel.PendingOrder{
+		OrderID:           "head",
+		ExcludeCourierIDs: []int64{1},
+		EnqueuedAt:        time.Now().Add(-time.Minute),
+	}
+	next := &deliveryModel.PendingOrder{OrderID: "next", EnqueuedAt: time.Now()}
+	gomock.InOrder(
+		pRepo.EXPECT().Next(gomock.Any(), gomock.Len(0)).Return(head, nil),
+		pRepo.EXPECT().Next(gomock.Any(), []string{"head"}).Return(next, nil),
+		pRepo.EXPECT().Next(gomock.Any(), []string{"head"}).Return(nil, deliveryMock.ErrNotFound),
+	)
+	dRepo.EXPECT().GetActiveByOrderID(gomock.Any(), gomock.Any()).Times(2).Return(nil, deliveryMock.ErrNotFound)
+
+	c := &courierModel.Courier{ID: 1}
+	cRepo.EXPECT().ListAvailable(gomock.Any(), gomock.Any()).
+		Times(3).
+		Return([]*courierModel.Candidate{{Courier: c}}, nil)
+	cRepo.EXPECT().ClaimByID(gomock.Any(), int64(1)).Return(c, nil)
+	dRepo.EXPECT().Create(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, d *deliveryModel.Delivery) error {
+			if d.OrderID != "next" || d.CourierID != 1 {
+				t.Errorf("unexpected delivery %+v", d)
+			}
+			return nil
+		})
+	dRepo.EXPECT().AddEvent(gomock.Any(), gomock.Any()).Return(nil)
+	pRepo.EXPECT().Remove(gomock.Any(), "next").Return(true, nil)
+	pRepo.EXPECT().Depth(gomock.Any()).Return(int64(1), nil)
+
+	assigned, err := svc.DrainQueue(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if assigned != 1 {
+		t.Fatalf("expected 1 assigned, got %d", assigned)
+	}
+}
+
+// TestDrainQueueDropsAssignedOrder - заказ из головы очереди уже назначили напрямую
+func TestDrainQueueDropsAssignedOrder(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cRepo := courierMock.NewMockCourierRepository(ctrl)
+	dRepo := deliveryMock.NewMockDeliveryRepository(ctrl)
+	pRepo := deliveryMock.NewMockPendingOrderRepository(ctrl)
+	svc := usecase.NewDeliveryService(cRepo, dRepo, usecase.WithPendingQueue(pRepo))
+
+	dRepo.EXPECT().WithTx(gomock.Any(), gomock.Any()).
+		Times(2).
+		DoAndReturn(func(_ context.Context, fn func(context.Context) error) error {
+			return fn(context.Background())
+		})
+	gomock.InOrder(
+		pRepo.EXPECT().Next(gomock.Any(), gomock.Any()).Return(&deliveryModel.PendingOrder{OrderID: "done"}, nil),
+		pRepo.EXPECT().Next(gomock.Any(), gomock.Any()).Return(nil, deliveryMock.ErrNotFound),
+	)
+	dRepo.EXPECT().GetActiveByOrderID(gomock.Any(), "done").
+		Return(&deliveryModel.Delivery{ID: 4, OrderID: "done"}, nil)
+	pRepo.EXPECT().Remove(gomock.Any(), "done").Return(true, nil)
+	pRepo.EXPECT().Depth(gomock.Any()).Return(int64(0), nil)
+
+	assigned, err := svc.DrainQueue(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if assigned != 0 {
+		t.Fatalf("expected 0 assigned, got %d", assigned)
+	}
+}
+
+// TestDrainQueueDropsOrderOnConflict - заказ назначили напрямую параллельно с разбором
+func TestDrainQueueDropsOrderOnConflict(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cRepo := courierMock.NewMockCourierRepository(ctrl)
+	dRepo := deliveryMock.NewMockDeliveryRepository(ctrl)
+	pRepo := deliveryMock.NewMockPendingOrderRepository(ctrl)
+	svc := usecase.NewDeliveryService(cRepo, dRepo, usecase.WithPendingQueue(pRepo))
+
+	dRepo.EXPECT().WithTx(gomock.Any(), gomock.Any()).
+		Times(2).
+		DoAndReturn(func(_ context.Context, fn func(context.Context) error) error {
+			return fn(context.Background())
+		})
+	gomock.InOrder(
+		pRepo.EXPECT().Next(gomock.Any(), gomock.Any()).Return(&deliveryModel.PendingOrder{OrderID: "race"}, nil),
+		pRepo.EXPECT().Next(gomock.Any(), gomock.Any()).Return(nil, deliveryMock.ErrNotFound),
+	)
+	c := &courierModel.Courier{ID: 1}
+	dRepo.EXPECT().GetActiveByOrderID(gomock.Any(), "race").Return(nil, deliveryMock.ErrNotFound)
+	cRepo.EXPECT().ListAvailable(gomock.Any(), gomock.Any()).
+		Return([]*courierModel.Candidate{{Courier: c}}, nil)
+	cRepo.EXPECT().ClaimByID(gomock.Any(), int64(1)).Return(c, nil)
+	dRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(deliveryMock.ErrConflict)
+	pRepo.EXPECT().Remove(gomock.Any(), "race").Return(true, nil)
+	pRepo.EXPECT().Depth(gomock.Any()).Return(int64(0), nil)
+
+	assigned, err := svc.DrainQueue(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if assigned != 0 {
+		t.Fatalf("expected 0 assigned, got %d", assigned)
+	}
+}
+
+// TestAssignRemovesQueuedOrder - прямое назначение убирает заказ из очереди
+func TestAssignRemovesQueuedOrder(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cRepo := courierMock.NewMockCourierRepository(ctrl)
+	dRepo := deliveryMock.NewMockDeliveryRepository(ctrl)
+	pRepo := deliveryMock.NewMockPendingOrderRepository(ctrl)
+	svc := usecase.NewDeliveryService(cRepo, dRepo, usecase.WithPendingQueue(pRepo))
+
+	c := &courierModel.Courier{ID: 1}
+	dRepo.EXPECT().WithTx(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, fn func(context.Context) error) error {
+			return fn(context.Background())
+		})
+	dRepo.EXPECT().GetActiveByOrderID(gomock.Any(), "q").Return(nil, deliveryMock.ErrNotFound)
+	cRepo.EXPECT().ListAvailable(gomock.Any(), gomock.Any()).
+		Return([]*courierModel.Candidate{{Courier: c}}, nil)
+	cRepo.EXPECT().ClaimByID(gomock.Any(), int64(1)).Return(c, nil)
+	dRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil)
+	dRepo.EXPECT().AddEvent(gomock.Any(), gomock.Any()).Return(nil)
+	pRepo.EXPECT().Remove(gomock.Any(), "q").Return(true, nil)
+	pRepo.EXPECT().Depth(gomock.Any()).Return(int64(0), nil)
+
+	if _, _, err := svc.Assign(context.Background(), "q"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestUnassignRemovesQueuedOrder(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cRepo := courierMock.NewMockCourierRepository(ctrl)
+	dRepo := deliveryMock.NewMockDeliveryRepository(ctrl)
+	pRepo := deliveryMock.NewMockPendingOrderRepository(ctrl)
+	svc := usecase.NewDeliveryService(cRepo, dRepo, usecase.WithPendingQueue(pRepo))
+
+	dRepo.EXPECT().WithTx(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, fn func(context.Context) error) error {
+			return fn(context.Background())
+		})
+	dRepo.EXPECT().GetActiveByOrderID(gomock.Any(), "q").Return(nil, deliveryMock.ErrNotFound)
+	pRepo.EXPECT().Remove(gomock.Any(), "q").Return(true, nil)
+	pRepo.EXPECT().Depth(gomock.Any()).Return(int64(0), nil)
+
+	if _, err := svc.Unassign(context.Background(), "q"); !errors.Is(err, deliveryModel.ErrOrderDequeued) {
+		t.Fatalf("expected ErrOrderDequeued, got %v", err)
+	}
+}
+
+// TestAssignSkipsCourierClaimedConcurrently - первого кандидата заняли параллельно, берём следующего
+func TestAssignSkipsCourierClaimedConcurrently(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cRepo := courierMock.NewMockCourierRepository(ctrl)
+	dRepo := deliveryMock.NewMockDeliveryRepository(ctrl)
+	svc := usecase.NewDeliveryService(cRepo, dRepo, usecase.WithStrategy(usecase.FastestTransportStrategy{}))
+
+	foot := &courierModel.Courier{ID: 1, TransportType: "on_foot"}
+	car := &courierModel.Courier{ID: 2, TransportType: "car"}
+	scooter := &courierModel.Courier{ID: 3, TransportType: "scooter"}
+
+	dRepo.EXPECT().WithTx(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, fn func(context.Context) error) error {
+			return fn(context.Background())
+		})
+	dRepo.EXPECT().GetActiveByOrderID(gomock.Any(), gomock.Any()).Return(nil, deliveryMock.ErrNotFound)
+	cRepo.EXPECT().ListAvailable(gomock.Any(), gomock.Any()).
+		Return([]*courierModel.Candidate{{Courier: foot}, {Courier: car}, {Courier: scooter}}, nil)
+	gomock.InOrder(
+		cRepo.EXPECT().ClaimByID(gomock.Any(), int64(2)).Return(nil, nil),
+		cRepo.EXPECT().ClaimByID(gomock.Any(), int64(3)).Return(scooter, nil),
+	)
+	dRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil)
+	dRepo.EXPECT().AddEvent(gomock.Any(), gomock.Any()).Return(nil)
+
+	_, courier, err := svc.Assign(context.Background(), "x")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if courier.ID != 3 {
+		t.Fatalf("expected courier 3, got %d", courier.ID)
+	}
+}
+
+// TestAssignReturnsActiveDelivery - повторное назначение возвращает уже существующую доставку
+func TestAssignReturnsActiveDelivery(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cRepo := courierMock.NewMockCourierRepository(ctrl)
+	dRepo := deliveryMock.NewMockDeliveryRepository(ctrl)
+	svc := usecase.NewDeliveryService(cRepo, dRepo)
+
+	existing := &deliveryModel.Delivery{ID: 5, CourierID: 2, OrderID: "dup", Status: deliveryModel.DeliveryStatusAssigned}
+
+	dRepo.EXPECT().WithTx(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, fn func(context.Context) error) error {
+			return fn(context.Background())
+		})
+	dRepo.EXPECT().GetActiveByOrderID(gomock.Any(), "dup").Return(existing, nil)
+	cRepo.EXPECT().GetByID(gomock.Any(), int64(2)).Return(&courierModel.Courier{ID: 2}, nil)
+
+	d, c, err := svc.Assign(context.Background(), "dup")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if d.ID != 5 || c.ID != 2 {
+		t.Fatalf("expected existing delivery 5 with courier 2, got %d / %d", d.ID, c.ID)
+	}
+}
+
+// TestAssignConflictReturnsExisting - параллельное назначение успело создать доставку раньше
+func TestAssignConflictReturnsExisting(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cRepo := courierMock.NewMockCourierRepository(ctrl)
+	dRepo := deliveryMock.NewMockDeliveryRepository(ctrl)
+	svc := usecase.NewDeliveryService(cRepo, dRepo)
+
+	c := &courierModel.Courier{ID: 1, TransportType: "car"}
+	existing := &deliveryModel.Delivery{ID: 9, CourierID: 3, OrderID: "race", Status: deliveryModel.DeliveryStatusAssigned}
+
+	dRepo.EXPECT().WithTx(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, fn func(context.Context) error) error {
+			return fn(context.Background())
+		})
+	gomock.InOrder(
+		dRepo.EXPECT().GetActiveByOrderID(gomock.Any(), "race").Return(nil, deliveryMock.ErrNotFound),
+		dRepo.EXPECT().GetActiveByOrderID(gomock.Any(), "race").Return(existing, nil),
+	)
+	cRepo.EXPECT().ListAvailable(gomock.Any(), gomock.Any()).
+		Return([]*courierModel.Candidate{{Courier: c}}, nil)
+	cRepo.EXPECT().ClaimByID(gomock.Any(), c.ID).Return(c, nil)
+	dRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(deliveryMock.ErrConflict)
+	cRepo.EXPECT().GetByID(gomock.Any(), int64(3)).Return(&courierModel.Courier{ID: 3}, nil)
+
+	d, courier, err := svc.Assign(context.Background(), "race")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if d.ID != 9 || courier.ID != 3 {
+		t.Fatalf("expected existing delivery 9 with courier 3, got %d / %d", d.ID, courier.ID)
+	}
+}
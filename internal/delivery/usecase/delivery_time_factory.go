@@ -1,16 +1,15 @@
 package usecase
 
-import "time"
+import (
+	"time"
 
-type TransportType string
+	deliveryModel "github.com/Avito-courses/course-go-avito-israpilovsha/internal/delivery/model"
+)
 
+var defaultDeadlinePolicy = deliveryModel.DefaultDeadlinePolicy()
+
+// CalculateDeadline считает дедлайн по политике по умолчанию.
+// Используется, когда сервису не передана загружаемая политика.
 func CalculateDeadline(transport string, now time.Time) time.Time {
-	switch transport {
-	case "scooter":
-		return now.Add(15 * time.Minute)
-	case "car":
-		return now.Add(5 * time.Minute)
-	default:
-		return now.Add(30 * time.Minute)
-	}
+	return defaultDeadlinePolicy.Deadline(deliveryModel.TransportType(transport), "", now)
 }
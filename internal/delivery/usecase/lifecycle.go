@@ -0,0 +1,86 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	courierModel "github.com/Avito-courses/course-go-avito-israpilovsha/internal/courier/model"
+	courierRepo "github.com/Avito-courses/course-go-avito-israpilovsha/internal/courier/repository"
+	deliveryModel "github.com/Avito-courses/course-go-avito-israpilovsha/internal/delivery/model"
+	deliveryRepo "github.com/Avito-courses/course-go-avito-israpilovsha/internal/delivery/repository"
+	outboxRepo "github.com/Avito-courses/course-go-avito-israpilovsha/internal/outbox/repository"
+)
+
+// Причины переходов, которые пишутся в историю доставки
+const (
+	ReasonAssigned       = "assigned"
+	ReasonPickedUp       = "picked_up"
+	ReasonUnassigned     = "unassigned"
+	ReasonCompleted      = "completed"
+	ReasonDeadlineMissed = "deadline_missed"
+	ReasonReassigned     = "reassigned_after_expiry"
+)
+
+// transitionDelivery переводит доставку в новый статус, пишет переход в историю и outbox
+// и освобождает курьера, если доставка завершилась. Курьер, который уже не busy
+// (на паузе, офлайн, деактивирован), остаётся в своём статусе. Должна вызываться внутри WithTx.
+func transitionDelivery(
+	ctx context.Context,
+	dRepo deliveryRepo.DeliveryRepository,
+	cRepo courierRepo.CourierRepository,
+	events outboxRepo.OutboxWriter,
+	d *deliveryModel.Delivery,
+	to deliveryModel.DeliveryStatus,
+	reason string,
+	now time.Time,
+) error {
+	if !d.Status.CanTransitionTo(to) {
+		return deliveryModel.ErrInvalidTransition
+	}
+
+	if err := dRepo.UpdateStatus(ctx, d.ID, to, now); err != nil {
+		return err
+	}
+
+	err := dRepo.AddEvent(ctx, &deliveryModel.DeliveryEvent{
+		DeliveryID: d.ID,
+		OrderID:    d.OrderID,
+		CourierID:  d.CourierID,
+		FromStatus: d.Status,
+		ToStatus:   to,
+		Reason:     reason,
+		CreatedAt:  now,
+	})
+	if err != nil {
+		return err
+	}
+
+	d.Status = to
+	switch to {
+	case deliveryModel.DeliveryStatusDelivered:
+		d.CompletedAt = &now
+	case deliveryModel.DeliveryStatusCancelled:
+		d.CancelledAt = &now
+	}
+
+	if err := recordDelivery(ctx, events, d, reason, now); err != nil {
+		return err
+	}
+
+	if !to.IsTerminal() {
+		return nil
+	}
+
+	released, err := cRepo.ReleaseByID(ctx, d.CourierID)
+	if err != nil {
+		return err
+	}
+	if !released {
+		return nil
+	}
+
+	return recordCourierStatus(
+		ctx, events, d.CourierID,
+		courierModel.CourierStatusBusy, courierModel.CourierStatusAvailable, d.OrderID, now,
+	)
+}
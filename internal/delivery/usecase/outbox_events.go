@@ -0,0 +1,74 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	courierModel "github.com/Avito-courses/course-go-avito-israpilovsha/internal/courier/model"
+	deliveryModel "github.com/Avito-courses/course-go-avito-israpilovsha/internal/delivery/model"
+	outboxModel "github.com/Avito-courses/course-go-avito-israpilovsha/internal/outbox/model"
+	outboxRepo "github.com/Avito-courses/course-go-avito-israpilovsha/internal/outbox/repository"
+)
+
+var deliveryEventTypes = map[deliveryModel.DeliveryStatus]string{
+	deliveryModel.DeliveryStatusAssigned:  outboxModel.EventDeliveryAssigned,
+	deliveryModel.DeliveryStatusPickedUp:  outboxModel.EventDeliveryPickedUp,
+	deliveryModel.DeliveryStatusDelivered: outboxModel.EventDeliveryCompleted,
+	deliveryModel.DeliveryStatusCancelled: outboxModel.EventDeliveryCancelled,
+	deliveryModel.DeliveryStatusExpired:   outboxModel.EventDeliveryExpired,
+}
+
+// recordDelivery пишет событие о доставке; ключ — order_id, чтобы события заказа шли по порядку.
+// Без outbox ничего не делает.
+func recordDelivery(ctx context.Context, w outboxRepo.OutboxWriter, d *deliveryModel.Delivery, reason string, at time.Time) error {
+	if w == nil {
+		return nil
+	}
+
+	payload := outboxModel.DeliveryPayload{
+		DeliveryID: d.ID,
+		OrderID:    d.OrderID,
+		CourierID:  d.CourierID,
+		Status:     string(d.Status),
+		Reason:     reason,
+	}
+	if d.Status == deliveryModel.DeliveryStatusAssigned {
+		payload.Deadline = &d.Deadline
+	}
+
+	e, err := outboxModel.NewEvent(deliveryEventTypes[d.Status], d.OrderID, payload, at)
+	if err != nil {
+		return err
+	}
+	return w.Add(ctx, e)
+}
+
+// recordCourierStatus пишет смену статуса курьера, вызванную доставкой заказа orderID
+func recordCourierStatus(
+	ctx context.Context,
+	w outboxRepo.OutboxWriter,
+	courierID int64,
+	from, to courierModel.CourierStatus,
+	orderID string,
+	at time.Time,
+) error {
+	if w == nil {
+		return nil
+	}
+
+	e, err := outboxModel.NewEvent(
+		outboxModel.EventCourierStatusChanged,
+		outboxModel.CourierKey(courierID),
+		outboxModel.CourierStatusPayload{
+			CourierID: courierID,
+			From:      string(from),
+			To:        string(to),
+			OrderID:   orderID,
+		},
+		at,
+	)
+	if err != nil {
+		return err
+	}
+	return w.Add(ctx, e)
+}
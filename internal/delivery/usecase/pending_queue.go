@@ -0,0 +1,171 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	courierModel "github.com/Avito-courses/course-go-avito-israpilovsha/internal/courier/model"
+	deliveryModel "github.com/Avito-courses/course-go-avito-israpilovsha/internal/delivery/model"
+	deliveryRepo "github.com/Avito-courses/course-go-avito-israpilovsha/internal/delivery/repository"
+	"github.com/Avito-courses/course-go-avito-israpilovsha/internal/metrics"
+)
+
+// errNoSuitableCourier — для головы очереди нет подходящего свободного курьера
+var errNoSuitableCourier = errors.New("no suitable courier for pending order")
+
+// AvailabilityNotifier получает сигнал, что какой-то курьер стал доступен
+type AvailabilityNotifier interface {
+	NotifyCourierAvailable()
+}
+
+// NotifyCourierAvailable будит разбор очереди; не блокирует вызывающего
+func (s *DeliveryService) NotifyCourierAvailable() {
+	if s.pendingRepo == nil {
+		return
+	}
+
+	select {
+	case s.drainCh <- struct{}{}:
+	default:
+	}
+}
+
+// DrainQueue назначает ожидающие заказы, пока есть свободные курьеры.
+// Каждый заказ забирается из очереди в отдельной транзакции вместе с назначением.
+// Заказы, которые уже назначены в обход очереди, просто убираются из неё.
+// Заказ, которому не подходит ни один свободный курьер (исключённые курьеры, размер заказа),
+// пропускается до следующего разбора, чтобы не задерживать заказы за ним.
+func (s *DeliveryService) DrainQueue(ctx context.Context) (int, error) {
+	if s.pendingRepo == nil {
+		return 0, nil
+	}
+
+	assigned, dropped := 0, 0
+	var skipped []string
+	defer func() {
+		if assigned > 0 || dropped > 0 {
+			s.refreshQueueDepth(ctx)
+		}
+	}()
+
+	for ctx.Err() == nil {
+		var (
+			head    *deliveryModel.PendingOrder
+			pending *deliveryModel.PendingOrder
+			courier *courierModel.Courier
+		)
+
+		err := s.deliveryRepo.WithTx(ctx, func(txCtx context.Context) error {
+			p, err := s.pendingRepo.Next(txCtx, skipped)
+			if err != nil {
+				return err
+			}
+			head = p
+
+			_, err = s.deliveryRepo.GetActiveByOrderID(txCtx, p.OrderID)
+			switch {
+			case err == nil:
+				_, err = s.pendingRepo.Remove(txCtx, p.OrderID)
+				return err
+			case !errors.Is(err, deliveryRepo.ErrNotFound):
+				return err
+			}
+
+			_, c, err := s.assignTx(txCtx, deliveryModel.AssignRequest{
+				OrderID:           p.OrderID,
+				Priority:          p.Priority,
+				Size:              p.Size,
+				Zone:              p.Zone,
+				ExcludeCourierIDs: p.ExcludeCourierIDs,
+			}, ReasonAssigned, s.nowFunc())
+			if err != nil {
+				return err
+			}
+			if c == nil {
+				return errNoSuitableCourier
+			}
+
+			if _, err := s.pendingRepo.Remove(txCtx, p.OrderID); err != nil {
+				return err
+			}
+
+			pending, courier = p, c
+			return nil
+		})
+
+		switch {
+		case errors.Is(err, deliveryRepo.ErrConflict):
+			// заказ назначили напрямую параллельно с разбором — в очереди он больше не нужен
+			if _, err := s.pendingRepo.Remove(ctx, head.OrderID); err != nil {
+				return assigned, err
+			}
+			fallthrough
+		case err == nil && courier == nil:
+			dropped++
+			s.log.Infow("pending order already assigned, removed from queue", "order_id", head.OrderID)
+			continue
+		case errors.Is(err, errNoSuitableCourier):
+			free, err := s.courierRepo.ListAvailable(ctx, time.Time{})
+			if err != nil {
+				return assigned, err
+			}
+			if len(free) == 0 {
+				// курьеры снова закончились
+				return assigned, nil
+			}
+			skipped = append(skipped, head.OrderID)
+			s.log.Debugw("pending order skipped, no suitable courier", "order_id", head.OrderID)
+			continue
+		case errors.Is(err, deliveryRepo.ErrNotFound):
+			// в очереди не осталось заказов, которые можно разобрать
+			return assigned, nil
+		case err != nil:
+			return assigned, err
+		}
+
+		assigned++
+		metrics.PendingOrderWaitSeconds.Observe(s.nowFunc().Sub(pending.EnqueuedAt).Seconds())
+		s.log.Infow("pending order assigned",
+			"order_id", pending.OrderID,
+			"courier_id", courier.ID,
+			"priority", pending.Priority,
+		)
+	}
+
+	return assigned, ctx.Err()
+}
+
+// StartQueueDrainer — фоновая задача: разбирает очередь по сигналу и раз в interval
+func (s *DeliveryService) StartQueueDrainer(ctx context.Context, interval time.Duration) {
+	if s.pendingRepo == nil {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	s.refreshQueueDepth(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.drainCh:
+		case <-ticker.C:
+		}
+
+		if _, err := s.DrainQueue(ctx); err != nil && ctx.Err() == nil {
+			s.log.Warnw("pending queue drain failed", "err", err)
+		}
+	}
+}
+
+func (s *DeliveryService) refreshQueueDepth(ctx context.Context) {
+	depth, err := s.pendingRepo.Depth(ctx)
+	if err != nil {
+		s.log.Warnw("pending queue depth failed", "err", err)
+		return
+	}
+	metrics.PendingOrdersDepth.Set(float64(depth))
+}
@@ -0,0 +1,103 @@
+package fakeorder
+
+import (
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Chaos — искусственные задержки и ошибки публичного API, чтобы проверять повторы и circuit breaker
+type Chaos struct {
+	// Latency и Jitter — задержка каждого ответа: Latency плюс случайная добавка до Jitter
+	Latency time.Duration
+	Jitter  time.Duration
+	// ErrorRate — доля запросов из [0, 1], на которые вернётся ErrorCode
+	ErrorRate float64
+	// ErrorCode — код искусственной ошибки; по умолчанию 503
+	ErrorCode int
+	// RetryAfter — заголовок Retry-After в искусственных ошибках; 0 — без него
+	RetryAfter time.Duration
+}
+
+// chaosRequest — Chaos в JSON админского API, длительности строками ("200ms")
+type chaosRequest struct {
+	Latency    string  `json:"latency"`
+	Jitter     string  `json:"jitter"`
+	ErrorRate  float64 `json:"error_rate"`
+	ErrorCode  int     `json:"error_code"`
+	RetryAfter string  `json:"retry_after"`
+}
+
+func (c Chaos) request() chaosRequest {
+	return chaosRequest{
+		Latency:    c.Latency.String(),
+		Jitter:     c.Jitter.String(),
+		ErrorRate:  c.ErrorRate,
+		ErrorCode:  c.ErrorCode,
+		RetryAfter: c.RetryAfter.String(),
+	}
+}
+
+func (r chaosRequest) chaos() (Chaos, error) {
+	var (
+		c   = Chaos{ErrorRate: r.ErrorRate, ErrorCode: r.ErrorCode}
+		err error
+	)
+	for _, f := range []struct {
+		raw string
+		dst *time.Duration
+	}{{r.Latency, &c.Latency}, {r.Jitter, &c.Jitter}, {r.RetryAfter, &c.RetryAfter}} {
+		if f.raw == "" {
+			continue
+		}
+		if *f.dst, err = time.ParseDuration(f.raw); err != nil {
+			return Chaos{}, err
+		}
+	}
+	return c.normalize(), nil
+}
+
+func (c Chaos) normalize() Chaos {
+	c.ErrorRate = math.Min(math.Max(c.ErrorRate, 0), 1)
+	if c.ErrorCode < 400 || c.ErrorCode > 599 {
+		c.ErrorCode = http.StatusServiceUnavailable
+	}
+	return c
+}
+
+func (c Chaos) delay() time.Duration {
+	d := c.Latency
+	if c.Jitter > 0 {
+		d += rand.N(c.Jitter)
+	}
+	return d
+}
+
+// injectFaults задерживает запрос и с вероятностью ErrorRate отвечает ошибкой вместо обработчика
+func (s *Server) injectFaults(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c := s.Chaos()
+
+		if d := c.delay(); d > 0 {
+			t := time.NewTimer(d)
+			select {
+			case <-t.C:
+			case <-r.Context().Done():
+				t.Stop()
+				return
+			}
+		}
+
+		if c.ErrorRate > 0 && rand.Float64() < c.ErrorRate {
+			if c.RetryAfter > 0 {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(c.RetryAfter.Seconds()))))
+			}
+			respondError(w, c.ErrorCode, "injected failure")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
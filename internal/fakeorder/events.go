@@ -0,0 +1,51 @@
+package fakeorder
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/IBM/sarama"
+
+	"github.com/Avito-courses/course-go-avito-israpilovsha/internal/worker"
+)
+
+// Publisher отправляет события изменения заказов в формате, который читает consumer сервиса
+type Publisher interface {
+	Publish(ctx context.Context, ev worker.OrderEvent) error
+}
+
+// KafkaPublisher пишет события в топик заказов с ключом order_id
+type KafkaPublisher struct {
+	producer sarama.SyncProducer
+	topic    string
+}
+
+func NewKafkaPublisher(producer sarama.SyncProducer, topic string) *KafkaPublisher {
+	return &KafkaPublisher{producer: producer, topic: topic}
+}
+
+func (p *KafkaPublisher) Publish(_ context.Context, ev worker.OrderEvent) error {
+	payload, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+
+	_, _, err = p.producer.SendMessage(&sarama.ProducerMessage{
+		Topic: p.topic,
+		Key:   sarama.StringEncoder(ev.OrderID),
+		Value: sarama.ByteEncoder(payload),
+	})
+	return err
+}
+
+// orderEvent — событие о текущем состоянии заказа; event_id однозначен для версии
+func orderEvent(o Order) worker.OrderEvent {
+	return worker.OrderEvent{
+		EventID:   fmt.Sprintf("%s-v%d", o.ID, o.Version),
+		OrderID:   o.ID,
+		Status:    o.Status,
+		Version:   o.Version,
+		UpdatedAt: o.UpdatedAt,
+	}
+}
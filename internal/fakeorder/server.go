@@ -0,0 +1,256 @@
+package fakeorder
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// nextCursorHeader — курсор следующей страницы списка заказов, как его ждёт order.Gateway
+const nextCursorHeader = "X-Next-Cursor"
+
+// Server — HTTP API фейкового order-сервиса: публичная часть, которую вызывает order.Gateway,
+// и /admin для создания заказов, смены статусов и настройки сбоев.
+// Аутентификация не проверяется — подходит любой ORDER_AUTH_MODE.
+type Server struct {
+	store   *Store
+	log     *zap.SugaredLogger
+	events  Publisher
+	noBatch bool
+
+	mu    sync.RWMutex
+	chaos Chaos
+}
+
+type Option func(*Server)
+
+// WithEvents публикует событие на каждое создание и смену статуса заказа
+func WithEvents(p Publisher) Option {
+	return func(s *Server) {
+		s.events = p
+	}
+}
+
+// WithChaos задаёт начальные задержки и ошибки; их можно поменять через PUT /admin/chaos
+func WithChaos(c Chaos) Option {
+	return func(s *Server) {
+		s.chaos = c.normalize()
+	}
+}
+
+// WithoutBatch отключает POST /public/api/v1/orders/statuses, как у сервиса старой версии
+func WithoutBatch() Option {
+	return func(s *Server) {
+		s.noBatch = true
+	}
+}
+
+func NewServer(store *Store, log *zap.SugaredLogger, opts ...Option) *Server {
+	s := &Server{store: store, log: log, chaos: Chaos{}.normalize()}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *Server) Handler() http.Handler {
+	r := mux.NewRouter()
+
+	api := r.PathPrefix("/public/api/v1").Subrouter()
+	api.Use(s.injectFaults)
+	api.HandleFunc("/orders", s.listOrders).Methods(http.MethodGet)
+	if !s.noBatch {
+		api.HandleFunc("/orders/statuses", s.getStatuses).Methods(http.MethodPost)
+	}
+	api.HandleFunc("/order/{id}/status", s.getStatus).Methods(http.MethodGet)
+
+	admin := r.PathPrefix("/admin").Subrouter()
+	admin.HandleFunc("/orders", s.createOrder).Methods(http.MethodPost)
+	admin.HandleFunc("/orders/{id}/status", s.transitionOrder).Methods(http.MethodPost)
+	admin.HandleFunc("/chaos", s.getChaos).Methods(http.MethodGet)
+	admin.HandleFunc("/chaos", s.setChaos).Methods(http.MethodPut)
+
+	return r
+}
+
+// Chaos возвращает текущие настройки сбоев
+func (s *Server) Chaos() Chaos {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.chaos
+}
+
+// listOrders отдаёт заказы, созданные после from, страницами по limit; cursor — offset следующей страницы
+func (s *Server) listOrders(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	var from time.Time
+	if raw := q.Get("from"); raw != "" {
+		t, err := time.Parse(time.RFC3339Nano, raw)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "invalid from")
+			return
+		}
+		from = t
+	}
+
+	limit, err := queryInt(q.Get("limit"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid limit")
+		return
+	}
+	offset, err := queryInt(q.Get("cursor"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid cursor")
+		return
+	}
+
+	orders, next := s.store.List(from, offset, limit)
+	if next > 0 {
+		w.Header().Set(nextCursorHeader, strconv.Itoa(next))
+	}
+	respondJSON(w, http.StatusOK, orders)
+}
+
+func (s *Server) getStatus(w http.ResponseWriter, r *http.Request) {
+	o, err := s.store.Get(mux.Vars(r)["id"])
+	if err != nil {
+		respondError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, statusResponse{OrderID: o.ID, Status: o.Status})
+}
+
+func (s *Server) getStatuses(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		OrderIDs []string `json:"order_ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	resp := struct {
+		Statuses []statusResponse `json:"statuses"`
+		Missing  []string         `json:"missing"`
+	}{Statuses: []statusResponse{}, Missing: []string{}}
+
+	for _, id := range req.OrderIDs {
+		if o, err := s.store.Get(id); err == nil {
+			resp.Statuses = append(resp.Statuses, statusResponse{OrderID: o.ID, Status: o.Status})
+		} else {
+			resp.Missing = append(resp.Missing, id)
+		}
+	}
+	respondJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) createOrder(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ID string `json:"id"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+	}
+
+	o, err := s.store.Create(req.ID)
+	if err != nil {
+		respondError(w, http.StatusConflict, err.Error())
+		return
+	}
+
+	s.publish(r, o)
+	respondJSON(w, http.StatusCreated, o)
+}
+
+func (s *Server) transitionOrder(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Status string `json:"status"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	o, err := s.store.Transition(mux.Vars(r)["id"], req.Status)
+	switch {
+	case errors.Is(err, ErrNotFound):
+		respondError(w, http.StatusNotFound, err.Error())
+		return
+	case err != nil:
+		respondError(w, http.StatusConflict, err.Error())
+		return
+	}
+
+	s.publish(r, o)
+	respondJSON(w, http.StatusOK, o)
+}
+
+func (s *Server) getChaos(w http.ResponseWriter, _ *http.Request) {
+	respondJSON(w, http.StatusOK, s.Chaos().request())
+}
+
+func (s *Server) setChaos(w http.ResponseWriter, r *http.Request) {
+	var req chaosRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	c, err := req.chaos()
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	s.chaos = c
+	s.mu.Unlock()
+
+	s.log.Infow("chaos updated", "latency", c.Latency, "jitter", c.Jitter, "error_rate", c.ErrorRate, "error_code", c.ErrorCode)
+	respondJSON(w, http.StatusOK, c.request())
+}
+
+// publish отправляет событие о заказе; ошибка только логируется — заказ уже изменён
+func (s *Server) publish(r *http.Request, o Order) {
+	if s.events == nil {
+		return
+	}
+	if err := s.events.Publish(r.Context(), orderEvent(o)); err != nil {
+		s.log.Warnw("publish order event failed", "order_id", o.ID, "err", err)
+	}
+}
+
+type statusResponse struct {
+	OrderID string `json:"order_id"`
+	Status  string `json:"status"`
+}
+
+func queryInt(raw string) (int, error) {
+	if raw == "" {
+		return 0, nil
+	}
+	v, err := strconv.Atoi(raw)
+	if err != nil || v < 0 {
+		return 0, errors.New("must be a non-negative integer")
+	}
+	return v, nil
+}
+
+func respondJSON(w http.ResponseWriter, status int, payload any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(payload)
+}
+
+func respondError(w http.ResponseWriter, status int, msg string) {
+	respondJSON(w, status, map[string]string{"error": msg})
+}
@@ -0,0 +1,201 @@
+package fakeorder_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"go.uber.org/zap"
+
+	courierModel "github.com/Avito-courses/course-go-avito-israpilovsha/internal/courier/model"
+	deliveryModel "github.com/Avito-courses/course-go-avito-israpilovsha/internal/delivery/model"
+	deliveryMock "github.com/Avito-courses/course-go-avito-israpilovsha/internal/delivery/repository"
+	"github.com/Avito-courses/course-go-avito-israpilovsha/internal/fakeorder"
+	"github.com/Avito-courses/course-go-avito-israpilovsha/internal/gateway/order"
+	"github.com/Avito-courses/course-go-avito-israpilovsha/internal/worker"
+)
+
+type recordingPublisher struct {
+	events []worker.OrderEvent
+}
+
+func (p *recordingPublisher) Publish(_ context.Context, ev worker.OrderEvent) error {
+	p.events = append(p.events, ev)
+	return nil
+}
+
+func newFakeService(t *testing.T, opts ...fakeorder.Option) *httptest.Server {
+	t.Helper()
+	return newFakeServiceWithStore(t, fakeorder.NewStore(), opts...)
+}
+
+func newFakeServiceWithStore(t *testing.T, store *fakeorder.Store, opts ...fakeorder.Option) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(fakeorder.NewServer(store, zap.NewNop().Sugar(), opts...).Handler())
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func adminPost(t *testing.T, url, body string) int {
+	t.Helper()
+
+	resp, err := http.Post(url, "application/json", bytes.NewBufferString(body))
+	if err != nil {
+		t.Fatalf("POST %s: %v", url, err)
+	}
+	_ = resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestFakeOrderServiceWithGateway(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []fakeorder.Option
+	}{
+		{name: "batch endpoint"},
+		{name: "without batch endpoint", opts: []fakeorder.Option{fakeorder.WithoutBatch()}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := &recordingPublisher{}
+			srv := newFakeService(t, append(tt.opts, fakeorder.WithEvents(events))...)
+
+			for _, id := range []string{"o-1", "o-2", "o-3"} {
+				if code := adminPost(t, srv.URL+"/admin/orders", `{"id":"`+id+`"}`); code != http.StatusCreated {
+					t.Fatalf("create %s: status %d", id, code)
+				}
+			}
+			if code := adminPost(t, srv.URL+"/admin/orders/o-2/status", `{"status":"cancelled"}`); code != http.StatusOK {
+				t.Fatalf("transition: status %d", code)
+			}
+			if code := adminPost(t, srv.URL+"/admin/orders/o-2/status", `{"status":"completed"}`); code != http.StatusConflict {
+				t.Fatalf("transition from terminal status: status %d, want 409", code)
+			}
+
+			gw := order.NewHTTPGateway(srv.URL, order.WithPageSize(2), order.WithBatch(2, 2))
+			ctx := context.Background()
+
+			var (
+				orders []order.Order
+				cursor string
+			)
+			for {
+				page, err := gw.FetchOrders(ctx, time.Time{}, cursor)
+				if err != nil {
+					t.Fatalf("FetchOrders() error = %v", err)
+				}
+				orders = append(orders, page.Orders...)
+				if page.Next == "" {
+					break
+				}
+				cursor = page.Next
+			}
+			if len(orders) != 3 || orders[0].ID != "o-1" || orders[2].ID != "o-3" {
+				t.Fatalf("unexpected orders %+v", orders)
+			}
+
+			results, err := gw.GetStatuses(ctx, []string{"o-1", "o-2", "unknown"})
+			if err != nil {
+				t.Fatalf("GetStatuses() error = %v", err)
+			}
+			if results["o-1"].Status != "created" || results["o-2"].Status != "cancelled" {
+				t.Fatalf("unexpected statuses %+v", results)
+			}
+			if !errors.Is(results["unknown"].Err, order.ErrOrderNotFound) {
+				t.Fatalf("expected ErrOrderNotFound, got %v", results["unknown"].Err)
+			}
+
+			// три создания и одна смена статуса
+			if got := len(events.events); got != 4 {
+				t.Fatalf("published %d events, want 4", got)
+			}
+			last := events.events[3]
+			if last.OrderID != "o-2" || last.Status != "cancelled" || last.Version != 2 {
+				t.Fatalf("unexpected event %+v", last)
+			}
+		})
+	}
+}
+
+func TestFakeOrderServiceChaos(t *testing.T) {
+	srv := newFakeService(t, fakeorder.WithChaos(fakeorder.Chaos{ErrorRate: 1}))
+	adminPost(t, srv.URL+"/admin/orders", `{"id":"o-1"}`)
+
+	gw := order.NewHTTPGateway(srv.URL, order.WithRetryPolicy(order.RetryPolicy{
+		MaxAttempts: 2,
+		BaseDelay:   time.Millisecond,
+		MaxDelay:    time.Millisecond,
+	}))
+
+	if _, err := gw.GetStatus(context.Background(), "o-1"); !errors.Is(err, order.ErrUpstreamUnavailable) {
+		t.Fatalf("expected ErrUpstreamUnavailable, got %v", err)
+	}
+
+	req, _ := http.NewRequest(http.MethodPut, srv.URL+"/admin/chaos", bytes.NewBufferString(`{"latency":"5ms","error_rate":0}`))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("PUT /admin/chaos: %v %v", resp, err)
+	}
+	_ = resp.Body.Close()
+
+	started := time.Now()
+	if status, err := gw.GetStatus(context.Background(), "o-1"); err != nil || status != "created" {
+		t.Fatalf("GetStatus() = %q, %v", status, err)
+	}
+	if time.Since(started) < 5*time.Millisecond {
+		t.Fatal("expected injected latency")
+	}
+}
+
+type flakyAssigner struct {
+	assigned []string
+	fail     map[string]error
+}
+
+func (a *flakyAssigner) Assign(_ context.Context, orderID string) (*deliveryModel.Delivery, *courierModel.Courier, error) {
+	if err := a.fail[orderID]; err != nil {
+		delete(a.fail, orderID)
+		return nil, nil, err
+	}
+	a.assigned = append(a.assigned, orderID)
+	return nil, nil, nil
+}
+
+// TestFakeOrderServiceSharedCreatedAt — заказы с одинаковым created_at не теряются,
+// когда опрос останавливается на одном из них
+func TestFakeOrderServiceSharedCreatedAt(t *testing.T) {
+	store := fakeorder.NewStore(fakeorder.WithCreatedAtPrecision(time.Hour))
+	srv := newFakeServiceWithStore(t, store)
+
+	for _, id := range []string{"o-1", "o-2", "o-3"} {
+		if code := adminPost(t, srv.URL+"/admin/orders", `{"id":"`+id+`"}`); code != http.StatusCreated {
+			t.Fatalf("create %s: status %d", id, code)
+		}
+	}
+
+	ctrl := gomock.NewController(t)
+	cursors := deliveryMock.NewMockFetchCursorRepository(ctrl)
+	cursors.EXPECT().Get(gomock.Any(), gomock.Any()).Return(&deliveryModel.FetchCursor{}, nil)
+	cursors.EXPECT().Save(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes().Return(nil)
+
+	assigner := &flakyAssigner{fail: map[string]error{"o-2": errors.New("db down")}}
+	f := worker.NewOrderFetcher(order.NewHTTPGateway(srv.URL), assigner, zap.NewNop().Sugar(),
+		worker.WithFetchCursor(cursors))
+
+	for range 2 {
+		if err := f.FetchOnce(context.Background()); err != nil {
+			t.Fatalf("FetchOnce() error = %v", err)
+		}
+	}
+	if !slices.Equal(assigner.assigned, []string{"o-1", "o-2", "o-3"}) {
+		t.Fatalf("assigned %v, want [o-1 o-2 o-3]", assigner.assigned)
+	}
+}
@@ -0,0 +1,151 @@
+// Package fakeorder — order-сервис в памяти для локальной разработки и e2e-тестов
+package fakeorder
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Avito-courses/course-go-avito-israpilovsha/internal/delivery/model"
+)
+
+var (
+	ErrNotFound          = errors.New("order not found")
+	ErrExists            = errors.New("order already exists")
+	ErrInvalidTransition = errors.New("invalid order status transition")
+)
+
+type Order struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+	// Version растёт с каждым изменением статуса, как у настоящего order-сервиса
+	Version   int64     `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Store хранит заказы в памяти; безопасен для конкурентного использования
+type Store struct {
+	mu     sync.RWMutex
+	orders map[string]*Order
+	// byCreated — заказы в порядке создания, по нему отдаются страницы
+	byCreated []*Order
+	seq       int
+	now       func() time.Time
+	// precision — до чего округляется время создания; 0 — без округления
+	precision time.Duration
+}
+
+type StoreOption func(*Store)
+
+// WithCreatedAtPrecision округляет время создания заказов вниз до precision, как хранилище
+// с грубыми метками времени: заказы, созданные в одну секунду при precision = time.Second,
+// получают одинаковый created_at, и клиент должен сам различать их на границе from
+func WithCreatedAtPrecision(precision time.Duration) StoreOption {
+	return func(s *Store) {
+		s.precision = precision
+	}
+}
+
+func NewStore(opts ...StoreOption) *Store {
+	s := &Store{orders: make(map[string]*Order), now: time.Now}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Create добавляет заказ в статусе created; пустой id генерируется
+func (s *Store) Create(id string) (Order, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.seq++
+	if id == "" {
+		id = fmt.Sprintf("order-%d", s.seq)
+	}
+	if _, ok := s.orders[id]; ok {
+		return Order{}, ErrExists
+	}
+
+	// время создания не убывает, чтобы byCreated оставался упорядоченным; совпадать оно может
+	now := s.now().UTC()
+	if s.precision > 0 {
+		now = now.Truncate(s.precision)
+	}
+	if n := len(s.byCreated); n > 0 && now.Before(s.byCreated[n-1].CreatedAt) {
+		now = s.byCreated[n-1].CreatedAt
+	}
+
+	o := &Order{
+		ID:        id,
+		Status:    string(model.OrderStatusCreated),
+		Version:   1,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	s.orders[id] = o
+	s.byCreated = append(s.byCreated, o)
+	return *o, nil
+}
+
+// Transition переводит заказ из created в cancelled или completed
+func (s *Store) Transition(id, status string) (Order, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	o, ok := s.orders[id]
+	if !ok {
+		return Order{}, ErrNotFound
+	}
+
+	next := model.ParseOrderStatus(status)
+	if !next.IsTerminal() || model.ParseOrderStatus(o.Status).IsTerminal() {
+		return Order{}, fmt.Errorf("%w: %s -> %s", ErrInvalidTransition, o.Status, status)
+	}
+
+	o.Status = string(next)
+	o.Version++
+	o.UpdatedAt = s.now().UTC()
+	return *o, nil
+}
+
+func (s *Store) Get(id string) (Order, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	o, ok := s.orders[id]
+	if !ok {
+		return Order{}, ErrNotFound
+	}
+	return *o, nil
+}
+
+// List возвращает до limit заказов, созданных строго после from, начиная с offset;
+// next — offset следующей страницы, 0 — страница последняя. limit <= 0 — все сразу.
+func (s *Store) List(from time.Time, offset, limit int) (orders []Order, next int) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	start := sort.Search(len(s.byCreated), func(i int) bool {
+		return s.byCreated[i].CreatedAt.After(from)
+	})
+	rest := s.byCreated[start:]
+	if offset >= len(rest) {
+		return []Order{}, 0
+	}
+	rest = rest[offset:]
+
+	if limit > 0 && len(rest) > limit {
+		rest = rest[:limit]
+		next = offset + limit
+	}
+
+	orders = make([]Order, 0, len(rest))
+	for _, o := range rest {
+		orders = append(orders, *o)
+	}
+	return orders, next
+}
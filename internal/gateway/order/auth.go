@@ -0,0 +1,215 @@
+package order
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Signer добавляет к вызовам order-сервиса данные аутентификации
+type Signer interface {
+	// Headers возвращает заголовки для вызова method к target:
+	// для HTTP — путь с query, для gRPC — полное имя метода.
+	// body — тело HTTP-запроса или сообщение gRPC-запроса в wire-формате protobuf.
+	Headers(ctx context.Context, method, target string, body []byte) (map[string]string, error)
+}
+
+// tokenInvalidator — Signer с кэшированным токеном, который нужно сбросить после 401
+type tokenInvalidator interface {
+	Invalidate()
+}
+
+// BearerToken — статический токен в заголовке Authorization
+type BearerToken string
+
+func (t BearerToken) Headers(context.Context, string, string, []byte) (map[string]string, error) {
+	return map[string]string{"Authorization": "Bearer " + string(t)}, nil
+}
+
+// DevBypass — заголовок X-Bypass-Auth, который песочница order-сервиса принимает вместо авторизации.
+// Только для локальной разработки.
+type DevBypass struct{}
+
+func (DevBypass) Headers(context.Context, string, string, []byte) (map[string]string, error) {
+	return map[string]string{"X-Bypass-Auth": "true"}, nil
+}
+
+// Заголовки HMAC-подписи
+const (
+	HeaderAuthKeyID     = "X-Auth-Key-Id"
+	HeaderAuthTimestamp = "X-Auth-Timestamp"
+	HeaderAuthSignature = "X-Auth-Signature"
+)
+
+// HMACSigner подписывает вызов HMAC-SHA256 от строки
+// "METHOD\ntarget\nunix-timestamp\nhex(sha256(body))"
+type HMACSigner struct {
+	KeyID  string
+	Secret []byte
+	now    func() time.Time
+}
+
+func NewHMACSigner(keyID string, secret []byte) *HMACSigner {
+	return &HMACSigner{KeyID: keyID, Secret: secret, now: time.Now}
+}
+
+func (s *HMACSigner) Headers(_ context.Context, method, target string, body []byte) (map[string]string, error) {
+	ts := strconv.FormatInt(s.now().Unix(), 10)
+	return map[string]string{
+		HeaderAuthKeyID:     s.KeyID,
+		HeaderAuthTimestamp: ts,
+		HeaderAuthSignature: s.Signature(method, target, ts, body),
+	}, nil
+}
+
+// Signature считает подпись; сервер проверяет её тем же способом
+func (s *HMACSigner) Signature(method, target, timestamp string, body []byte) string {
+	bodyHash := sha256.Sum256(body)
+
+	mac := hmac.New(sha256.New, s.Secret)
+	mac.Write([]byte(strings.ToUpper(method) + "\n" + target + "\n" + timestamp + "\n" + hex.EncodeToString(bodyHash[:])))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// ClientCredentialsConfig — OAuth2 client credentials grant (RFC 6749, 4.4)
+type ClientCredentialsConfig struct {
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	// HTTPClient — клиент для запросов токена; по умолчанию с таймаутом 10s
+	HTTPClient *http.Client
+}
+
+// tokenRefreshSkew — за сколько до истечения токен обновляется заранее
+const tokenRefreshSkew = 30 * time.Second
+
+// ClientCredentials получает access token у token URL, кэширует его до истечения
+// и обновляет заранее; одновременные вызовы ждут одно обновление
+type ClientCredentials struct {
+	cfg ClientCredentialsConfig
+	now func() time.Time
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+func NewClientCredentials(cfg ClientCredentialsConfig) *ClientCredentials {
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &ClientCredentials{cfg: cfg, now: time.Now}
+}
+
+func (c *ClientCredentials) Headers(ctx context.Context, _, _ string, _ []byte) (map[string]string, error) {
+	token, err := c.Token(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{"Authorization": "Bearer " + token}, nil
+}
+
+// Token возвращает действующий токен, при необходимости запрашивая новый
+func (c *ClientCredentials) Token(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.token != "" && c.now().Before(c.expires) {
+		return c.token, nil
+	}
+
+	token, ttl, err := c.fetch(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	// короткоживущие токены обновляются на середине срока, остальные — за tokenRefreshSkew
+	c.token = token
+	c.expires = c.now().Add(max(ttl-tokenRefreshSkew, ttl/2))
+	return token, nil
+}
+
+// Invalidate сбрасывает токен — следующий вызов запросит новый
+func (c *ClientCredentials) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.token = ""
+}
+
+func (c *ClientCredentials) fetch(ctx context.Context) (string, time.Duration, error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(c.cfg.Scopes) > 0 {
+		form.Set("scope", strings.Join(c.cfg.Scopes, " "))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.cfg.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", 0, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(c.cfg.ClientID), url.QueryEscape(c.cfg.ClientSecret))
+
+	resp, err := c.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return "", 0, fmt.Errorf("%w: token request: %w", ErrUpstreamUnavailable, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", 0, tokenStatusError(statusError(resp))
+	}
+
+	var res struct {
+		AccessToken string `json:"access_token"`
+		TokenType   string `json:"token_type"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return "", 0, fmt.Errorf("order gateway: decode token: %w", err)
+	}
+	if res.AccessToken == "" {
+		return "", 0, fmt.Errorf("%w: token endpoint returned no access_token", ErrUnauthorized)
+	}
+	if res.TokenType != "" && !strings.EqualFold(res.TokenType, "bearer") {
+		return "", 0, fmt.Errorf("order gateway: unsupported token type %q", res.TokenType)
+	}
+
+	ttl := time.Duration(res.ExpiresIn) * time.Second
+	if ttl <= 0 {
+		// срок не указан — считаем токен действующим час
+		ttl = time.Hour
+	}
+	return res.AccessToken, ttl, nil
+}
+
+// tokenStatusError сопоставляет неуспешный ответ token endpoint с ошибками шлюза:
+// 400/401/403 — учётные данные отклонены, 429 и 5xx — сбой, который стоит повторить.
+// Остальные коды не оборачиваются, чтобы, например, 404 не выглядел как ErrOrderNotFound.
+func tokenStatusError(err error) error {
+	var se *StatusError
+	if !errors.As(err, &se) {
+		return err
+	}
+
+	switch {
+	case se.Code == http.StatusBadRequest || se.Code == http.StatusUnauthorized || se.Code == http.StatusForbidden:
+		return fmt.Errorf("%w: token endpoint: %w", ErrUnauthorized, se)
+	case se.Retryable():
+		return fmt.Errorf("token endpoint: %w", se)
+	default:
+		return fmt.Errorf("order gateway: token endpoint: %v", se)
+	}
+}
@@ -0,0 +1,209 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGateway_Signers(t *testing.T) {
+	hmacSigner := NewHMACSigner("key-1", []byte("secret"))
+	hmacSigner.now = func() time.Time { return time.Unix(1700000000, 0) }
+
+	tests := []struct {
+		name   string
+		signer Signer
+		check  func(r *http.Request) bool
+	}{
+		{
+			name:   "no signer sends no auth",
+			signer: nil,
+			check: func(r *http.Request) bool {
+				return r.Header.Get("Authorization") == "" && r.Header.Get("X-Bypass-Auth") == ""
+			},
+		},
+		{
+			name:   "bearer token",
+			signer: BearerToken("t-1"),
+			check:  func(r *http.Request) bool { return r.Header.Get("Authorization") == "Bearer t-1" },
+		},
+		{
+			name:   "dev bypass",
+			signer: DevBypass{},
+			check:  func(r *http.Request) bool { return r.Header.Get("X-Bypass-Auth") == "true" },
+		},
+		{
+			name:   "hmac signature",
+			signer: hmacSigner,
+			check: func(r *http.Request) bool {
+				want := hmacSigner.Signature(r.Method, r.URL.RequestURI(), r.Header.Get(HeaderAuthTimestamp), nil)
+				return r.Header.Get(HeaderAuthKeyID) == "key-1" &&
+					r.Header.Get(HeaderAuthTimestamp) == "1700000000" &&
+					r.Header.Get(HeaderAuthSignature) == want
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if !tt.check(r) {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				_, _ = w.Write([]byte(`{"order_id":"x","status":"created"}`))
+			}))
+			defer srv.Close()
+
+			var opts []Option
+			if tt.signer != nil {
+				opts = append(opts, WithSigner(tt.signer))
+			}
+			gw := NewHTTPGateway(srv.URL, opts...)
+
+			if _, err := gw.GetStatus(context.Background(), "x"); err != nil {
+				t.Fatalf("GetStatus() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestClientCredentials_CachesAndRefreshes(t *testing.T) {
+	var issued atomic.Int32
+	tokenSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, secret, _ := r.BasicAuth()
+		if r.Method != http.MethodPost || id != "svc" || secret != "pw" ||
+			r.FormValue("grant_type") != "client_credentials" || r.FormValue("scope") != "orders:read" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		n := issued.Add(1)
+		_, _ = w.Write([]byte(`{"access_token":"tok-` + strconv.Itoa(int(n)) + `","token_type":"Bearer","expires_in":120}`))
+	}))
+	defer tokenSrv.Close()
+
+	// order-сервис принимает только второй выданный токен
+	apiSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer tok-2" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{"order_id":"x","status":"created"}`))
+	}))
+	defer apiSrv.Close()
+
+	now := time.Unix(0, 0)
+	cc := NewClientCredentials(ClientCredentialsConfig{
+		TokenURL:     tokenSrv.URL,
+		ClientID:     "svc",
+		ClientSecret: "pw",
+		Scopes:       []string{"orders:read"},
+	})
+	cc.now = func() time.Time { return now }
+
+	gw := NewHTTPGateway(apiSrv.URL, WithSigner(cc))
+
+	// первый токен отклонён — он сбрасывается, и следующий вызов получает новый
+	if _, err := gw.GetStatus(context.Background(), "x"); !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("expected ErrUnauthorized, got %v", err)
+	}
+	for range 2 {
+		if _, err := gw.GetStatus(context.Background(), "x"); err != nil {
+			t.Fatalf("GetStatus() error = %v", err)
+		}
+	}
+	if got := issued.Load(); got != 2 {
+		t.Fatalf("issued tokens = %d, want 2", got)
+	}
+
+	// токен обновляется заранее, за tokenRefreshSkew до истечения
+	now = now.Add(90 * time.Second)
+	if _, err := cc.Token(context.Background()); err != nil {
+		t.Fatalf("Token() error = %v", err)
+	}
+	if got := issued.Load(); got != 3 {
+		t.Fatalf("expected refresh before expiry, issued = %d", got)
+	}
+}
+
+func TestClientCredentials_TokenEndpointErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		code    int
+		wantErr error
+		notErr  error
+	}{
+		{name: "bad credentials", code: http.StatusUnauthorized, wantErr: ErrUnauthorized, notErr: ErrUpstreamUnavailable},
+		{name: "invalid client", code: http.StatusBadRequest, wantErr: ErrUnauthorized, notErr: ErrUpstreamUnavailable},
+		{name: "outage", code: http.StatusServiceUnavailable, wantErr: ErrUpstreamUnavailable, notErr: ErrUnauthorized},
+		{name: "throttled", code: http.StatusTooManyRequests, wantErr: ErrUpstreamUnavailable, notErr: ErrUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokenSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(tt.code)
+			}))
+			defer tokenSrv.Close()
+
+			cc := NewClientCredentials(ClientCredentialsConfig{TokenURL: tokenSrv.URL, ClientID: "svc", ClientSecret: "pw"})
+			_, err := cc.Token(context.Background())
+			if !errors.Is(err, tt.wantErr) || errors.Is(err, tt.notErr) {
+				t.Fatalf("Token() error = %v, want %v and not %v", err, tt.wantErr, tt.notErr)
+			}
+		})
+	}
+
+	t.Run("transport error", func(t *testing.T) {
+		tokenSrv := httptest.NewServer(http.NotFoundHandler())
+		tokenSrv.Close()
+
+		cc := NewClientCredentials(ClientCredentialsConfig{TokenURL: tokenSrv.URL, ClientID: "svc", ClientSecret: "pw"})
+		_, err := cc.Token(context.Background())
+		if !errors.Is(err, ErrUpstreamUnavailable) || errors.Is(err, ErrUnauthorized) {
+			t.Fatalf("Token() error = %v, want ErrUpstreamUnavailable", err)
+		}
+	})
+}
+
+// TestClientCredentials_RetriesTokenOutage — сбой token endpoint повторяется политикой повторов шлюза
+func TestClientCredentials_RetriesTokenOutage(t *testing.T) {
+	var tokenCalls atomic.Int32
+	tokenSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if tokenCalls.Add(1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		_, _ = w.Write([]byte(`{"access_token":"tok","token_type":"Bearer","expires_in":120}`))
+	}))
+	defer tokenSrv.Close()
+
+	apiSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer tok" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{"order_id":"x","status":"created"}`))
+	}))
+	defer apiSrv.Close()
+
+	cc := NewClientCredentials(ClientCredentialsConfig{TokenURL: tokenSrv.URL, ClientID: "svc", ClientSecret: "pw"})
+	gw := NewHTTPGateway(apiSrv.URL, WithSigner(cc), WithRetryPolicy(RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   time.Millisecond,
+		MaxDelay:    time.Millisecond,
+	}))
+
+	if _, err := gw.GetStatus(context.Background(), "x"); err != nil {
+		t.Fatalf("GetStatus() error = %v", err)
+	}
+	if got := tokenCalls.Load(); got != 2 {
+		t.Fatalf("token calls = %d, want 2", got)
+	}
+}
@@ -0,0 +1,131 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// errBatchUnsupported — order-сервис не знает пакетного вызова статусов
+var errBatchUnsupported = errors.New("order gateway: batch status lookup unsupported")
+
+// errNoBatchStatus — order-сервис не вернул заказ ни в статусах, ни в missing
+var errNoBatchStatus = errors.New("order gateway: no status in batch response")
+
+// batchLookup — пакетный и одиночный запрос статуса одной реализации Gateway
+type batchLookup struct {
+	batch  func(ctx context.Context, ids []string) (map[string]StatusResult, error)
+	single func(ctx context.Context, id string) (string, error)
+	// unsupported запоминает, что пакетного вызова нет, чтобы не спрашивать о нём каждый раз
+	unsupported *atomic.Bool
+}
+
+// getStatuses делит ids на пачки по batchSize и запрашивает их пакетным вызовом.
+// Если order-сервис пакетного вызова не поддерживает, статусы запрашиваются по одному.
+// Не больше concurrency запросов одновременно; ошибка пачки записывается каждому её заказу.
+func (o *options) getStatuses(ctx context.Context, ids []string, l batchLookup) (map[string]StatusResult, error) {
+	ids = uniqueIDs(ids)
+	results := make(map[string]StatusResult, len(ids))
+
+	var mu sync.Mutex
+	set := func(id string, r StatusResult) {
+		mu.Lock()
+		results[id] = r
+		mu.Unlock()
+	}
+
+	single := ids
+	if !l.unsupported.Load() {
+		chunks := chunkIDs(ids, o.batchSize)
+		single = nil
+
+		runLimited(ctx, o.concurrency, len(chunks), func(i int) {
+			chunk := chunks[i]
+
+			var (
+				part map[string]StatusResult
+				err  = errBatchUnsupported
+			)
+			if !l.unsupported.Load() {
+				part, err = l.batch(ctx, chunk)
+			}
+			if errors.Is(err, errBatchUnsupported) {
+				if l.unsupported.CompareAndSwap(false, true) {
+					o.log.Warnw("order gateway batch status lookup unsupported, falling back to single lookups")
+				}
+				mu.Lock()
+				single = append(single, chunk...)
+				mu.Unlock()
+				return
+			}
+
+			for _, id := range chunk {
+				r, ok := part[id]
+				switch {
+				case err != nil:
+					r = StatusResult{Err: err}
+				case !ok:
+					r = StatusResult{Err: errNoBatchStatus}
+				}
+				set(id, r)
+			}
+		})
+	}
+
+	runLimited(ctx, o.concurrency, len(single), func(i int) {
+		status, err := l.single(ctx, single[i])
+		set(single[i], StatusResult{Status: status, Err: err})
+	})
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// runLimited вызывает fn(0..n-1), не больше limit одновременно; после отмены ctx новые вызовы не начинаются
+func runLimited(ctx context.Context, limit, n int, fn func(i int)) {
+	sem := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+
+	for i := range n {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		}
+
+		wg.Go(func() {
+			defer func() { <-sem }()
+			fn(i)
+		})
+	}
+	wg.Wait()
+}
+
+func uniqueIDs(ids []string) []string {
+	seen := make(map[string]struct{}, len(ids))
+	out := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		out = append(out, id)
+	}
+	return out
+}
+
+func chunkIDs(ids []string, size int) [][]string {
+	var chunks [][]string
+	for len(ids) > size {
+		chunks = append(chunks, ids[:size:size])
+		ids = ids[size:]
+	}
+	if len(ids) > 0 {
+		chunks = append(chunks, ids)
+	}
+	return chunks
+}
@@ -0,0 +1,383 @@
+package order
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/Avito-courses/course-go-avito-israpilovsha/internal/metrics"
+)
+
+// CacheConfig — параметры кэша order-сервиса; нулевые поля заменяются значениями по умолчанию
+type CacheConfig struct {
+	// StatusTTL — сколько хранится статус заказа
+	StatusTTL time.Duration
+	// NotFoundTTL — сколько помнить, что заказа нет (404)
+	NotFoundTTL time.Duration
+	// OrdersTTL — сколько переиспользовать ответ FetchOrders для того же from и курсора; 0 — не кэшировать
+	OrdersTTL time.Duration
+	// MaxEntries — предел записей в каждом из кэшей, старые вытесняются первыми
+	MaxEntries int
+	// CallTimeout — предел объединённого вызова next. Такой вызов не отменяется вместе
+	// с контекстом того, кто его начал, чтобы остальные ждущие получили ответ; по умолчанию 30s
+	CallTimeout time.Duration
+}
+
+// pageKey — страница FetchOrders в кэше
+type pageKey struct {
+	from   int64
+	cursor string
+}
+
+type statusEntry struct {
+	status   string
+	notFound bool
+}
+
+// CachingGateway кэширует ответы order-сервиса и объединяет одновременные запросы одного заказа
+type CachingGateway struct {
+	next Gateway
+	cfg  CacheConfig
+	now  func() time.Time
+
+	statuses *lru[string, statusEntry]
+	orders   *lru[pageKey, OrdersPage]
+
+	statusCalls flight[string, statusEntry]
+	orderCalls  flight[pageKey, OrdersPage]
+}
+
+func NewCachingGateway(next Gateway, cfg CacheConfig) *CachingGateway {
+	if cfg.StatusTTL <= 0 {
+		cfg.StatusTTL = 2 * time.Second
+	}
+	if cfg.NotFoundTTL <= 0 {
+		cfg.NotFoundTTL = 5 * time.Second
+	}
+	if cfg.MaxEntries <= 0 {
+		cfg.MaxEntries = 10000
+	}
+	if cfg.CallTimeout <= 0 {
+		cfg.CallTimeout = 30 * time.Second
+	}
+
+	return &CachingGateway{
+		next:     next,
+		cfg:      cfg,
+		now:      time.Now,
+		statuses: newLRU[string, statusEntry](cfg.MaxEntries),
+		orders:   newLRU[pageKey, OrdersPage](cfg.MaxEntries),
+	}
+}
+
+func (g *CachingGateway) GetStatus(ctx context.Context, id string) (string, error) {
+	if e, ok := g.statuses.get(id, g.now()); ok {
+		metrics.OrderGatewayCacheTotal.WithLabelValues("get_status", "hit").Inc()
+		return e.status, e.err()
+	}
+
+	e, shared, err := g.statusCalls.do(ctx, id, g.cfg.CallTimeout, func(ctx context.Context) (statusEntry, error) {
+		gen := g.statuses.begin(id)
+		status, err := g.next.GetStatus(ctx, id)
+		switch {
+		case err == nil:
+			e := statusEntry{status: status}
+			g.statuses.finish(id, gen, e, g.now().Add(g.cfg.StatusTTL))
+			return e, nil
+		case errors.Is(err, ErrOrderNotFound):
+			e := statusEntry{notFound: true}
+			g.statuses.finish(id, gen, e, g.now().Add(g.cfg.NotFoundTTL))
+			return e, nil
+		default:
+			g.statuses.cancel(id)
+			return statusEntry{}, err
+		}
+	})
+	g.countMiss("get_status", shared)
+	if err != nil {
+		return "", err
+	}
+	return e.status, e.err()
+}
+
+// GetStatuses отдаёт закэшированные статусы, а остальные запрашивает одним вызовом next.
+// Одновременные пакетные запросы не объединяются.
+func (g *CachingGateway) GetStatuses(ctx context.Context, ids []string) (map[string]StatusResult, error) {
+	results := make(map[string]StatusResult, len(ids))
+	var misses []string
+
+	now := g.now()
+	for _, id := range ids {
+		if _, ok := results[id]; ok {
+			continue
+		}
+		if e, ok := g.statuses.get(id, now); ok {
+			metrics.OrderGatewayCacheTotal.WithLabelValues("get_statuses", "hit").Inc()
+			results[id] = StatusResult{Status: e.status, Err: e.err()}
+			continue
+		}
+		results[id] = StatusResult{}
+		misses = append(misses, id)
+	}
+	if len(misses) == 0 {
+		return results, nil
+	}
+	metrics.OrderGatewayCacheTotal.WithLabelValues("get_statuses", "miss").Add(float64(len(misses)))
+
+	gens := make([]uint64, len(misses))
+	for i, id := range misses {
+		gens[i] = g.statuses.begin(id)
+	}
+
+	fetched, err := g.next.GetStatuses(ctx, misses)
+	if err != nil {
+		for _, id := range misses {
+			g.statuses.cancel(id)
+		}
+		return nil, err
+	}
+
+	now = g.now()
+	for i, id := range misses {
+		r, ok := fetched[id]
+		if !ok {
+			r = StatusResult{Err: errNoBatchStatus}
+		}
+		switch {
+		case r.Err == nil:
+			g.statuses.finish(id, gens[i], statusEntry{status: r.Status}, now.Add(g.cfg.StatusTTL))
+		case errors.Is(r.Err, ErrOrderNotFound):
+			g.statuses.finish(id, gens[i], statusEntry{notFound: true}, now.Add(g.cfg.NotFoundTTL))
+		default:
+			g.statuses.cancel(id)
+		}
+		results[id] = r
+	}
+	return results, nil
+}
+
+func (g *CachingGateway) FetchOrders(ctx context.Context, from time.Time, cursor string) (OrdersPage, error) {
+	if g.cfg.OrdersTTL <= 0 {
+		return g.next.FetchOrders(ctx, from, cursor)
+	}
+
+	key := pageKey{from: from.UnixNano(), cursor: cursor}
+	if page, ok := g.orders.get(key, g.now()); ok {
+		metrics.OrderGatewayCacheTotal.WithLabelValues("fetch_orders", "hit").Inc()
+		return page, nil
+	}
+
+	page, shared, err := g.orderCalls.do(ctx, key, g.cfg.CallTimeout, func(ctx context.Context) (OrdersPage, error) {
+		page, err := g.next.FetchOrders(ctx, from, cursor)
+		if err == nil {
+			g.orders.put(key, page, g.now().Add(g.cfg.OrdersTTL))
+		}
+		return page, err
+	})
+	g.countMiss("fetch_orders", shared)
+	return page, err
+}
+
+// Invalidate забывает статус заказа — следующий GetStatus пойдёт в order-сервис.
+// Ответ запроса, начатого до Invalidate, в кэш уже не попадёт.
+func (g *CachingGateway) Invalidate(id string) {
+	g.statuses.remove(id)
+}
+
+func (g *CachingGateway) countMiss(method string, shared bool) {
+	result := "miss"
+	if shared {
+		result = "coalesced"
+	}
+	metrics.OrderGatewayCacheTotal.WithLabelValues(method, result).Inc()
+}
+
+func (e statusEntry) err() error {
+	if e.notFound {
+		return ErrOrderNotFound
+	}
+	return nil
+}
+
+// lru — ограниченный по размеру кэш с истечением записей
+type lru[K comparable, V any] struct {
+	mu    sync.Mutex
+	max   int
+	order *list.List
+	items map[K]*list.Element
+	// fills — поколения ключей, для которых идёт запрос; remove увеличивает поколение,
+	// и finish не записывает значение, полученное до удаления
+	fills map[K]*fill
+}
+
+type fill struct {
+	gen  uint64
+	refs int
+}
+
+type lruItem[K comparable, V any] struct {
+	key     K
+	value   V
+	expires time.Time
+}
+
+func newLRU[K comparable, V any](max int) *lru[K, V] {
+	return &lru[K, V]{
+		max:   max,
+		order: list.New(),
+		items: make(map[K]*list.Element),
+		fills: make(map[K]*fill),
+	}
+}
+
+func (c *lru[K, V]) get(key K, now time.Time) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var zero V
+	el, ok := c.items[key]
+	if !ok {
+		return zero, false
+	}
+
+	item := el.Value.(*lruItem[K, V])
+	if !now.Before(item.expires) {
+		c.order.Remove(el)
+		delete(c.items, key)
+		return zero, false
+	}
+
+	c.order.MoveToFront(el)
+	return item.value, true
+}
+
+func (c *lru[K, V]) put(key K, value V, expires time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.putLocked(key, value, expires)
+}
+
+func (c *lru[K, V]) putLocked(key K, value V, expires time.Time) {
+	if el, ok := c.items[key]; ok {
+		el.Value = &lruItem[K, V]{key: key, value: value, expires: expires}
+		c.order.MoveToFront(el)
+		return
+	}
+
+	c.items[key] = c.order.PushFront(&lruItem[K, V]{key: key, value: value, expires: expires})
+	for c.order.Len() > c.max {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*lruItem[K, V]).key)
+	}
+}
+
+func (c *lru[K, V]) remove(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if f, ok := c.fills[key]; ok {
+		f.gen++
+	}
+	if el, ok := c.items[key]; ok {
+		c.order.Remove(el)
+		delete(c.items, key)
+	}
+}
+
+// begin отмечает начало запроса значения key и возвращает текущее поколение ключа.
+// Каждый begin завершается finish или cancel.
+func (c *lru[K, V]) begin(key K) uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	f, ok := c.fills[key]
+	if !ok {
+		f = &fill{}
+		c.fills[key] = f
+	}
+	f.refs++
+	return f.gen
+}
+
+// finish записывает значение, если после begin ключ не удаляли
+func (c *lru[K, V]) finish(key K, gen uint64, value V, expires time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.release(key) == gen {
+		c.putLocked(key, value, expires)
+	}
+}
+
+// cancel завершает запрос без записи значения
+func (c *lru[K, V]) cancel(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.release(key)
+}
+
+func (c *lru[K, V]) release(key K) uint64 {
+	f := c.fills[key]
+	f.refs--
+	if f.refs == 0 {
+		delete(c.fills, key)
+	}
+	return f.gen
+}
+
+// flight объединяет одновременные вызовы с одним ключом в один запрос
+type flight[K comparable, V any] struct {
+	mu    sync.Mutex
+	calls map[K]*flightCall[V]
+}
+
+type flightCall[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+}
+
+// do выполняет fn или ждёт уже идущий вызов с тем же ключом; shared — результат получен чужим вызовом.
+// fn работает в отдельной горутине на контексте без отмены, но с timeout: отмена ctx любого
+// из вызвавших, включая первого, прекращает только его ожидание.
+func (f *flight[K, V]) do(
+	ctx context.Context,
+	key K,
+	timeout time.Duration,
+	fn func(ctx context.Context) (V, error),
+) (value V, shared bool, err error) {
+	f.mu.Lock()
+	call, shared := f.calls[key]
+	if !shared {
+		if f.calls == nil {
+			f.calls = make(map[K]*flightCall[V])
+		}
+		call = &flightCall[V]{done: make(chan struct{})}
+		f.calls[key] = call
+
+		go func() {
+			callCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+			defer cancel()
+
+			call.value, call.err = fn(callCtx)
+
+			f.mu.Lock()
+			delete(f.calls, key)
+			f.mu.Unlock()
+			close(call.done)
+		}()
+	}
+	f.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.value, shared, call.err
+	case <-ctx.Done():
+		return value, shared, ctx.Err()
+	}
+}
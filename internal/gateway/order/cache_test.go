@@ -0,0 +1,263 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type countingGateway struct {
+	calls   atomic.Int32
+	release chan struct{}
+	status  string
+	err     error
+	// requested — ids последнего GetStatuses
+	requested []string
+}
+
+func (g *countingGateway) FetchOrders(context.Context, time.Time, string) (OrdersPage, error) {
+	g.calls.Add(1)
+	return OrdersPage{Orders: []Order{{ID: "o-1"}}}, nil
+}
+
+func (g *countingGateway) GetStatus(ctx context.Context, _ string) (string, error) {
+	g.calls.Add(1)
+	if g.release != nil {
+		select {
+		case <-g.release:
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+	return g.status, g.err
+}
+
+func (g *countingGateway) GetStatuses(_ context.Context, ids []string) (map[string]StatusResult, error) {
+	g.calls.Add(1)
+	g.requested = ids
+	results := make(map[string]StatusResult, len(ids))
+	for _, id := range ids {
+		results[id] = StatusResult{Status: g.status, Err: g.err}
+	}
+	return results, nil
+}
+
+func TestCachingGatewayStatus(t *testing.T) {
+	tests := []struct {
+		name      string
+		gateway   *countingGateway
+		cfg       CacheConfig
+		advance   time.Duration
+		wantErr   error
+		wantCalls int32
+	}{
+		{
+			name:      "hit within ttl",
+			gateway:   &countingGateway{status: "created"},
+			cfg:       CacheConfig{StatusTTL: time.Minute},
+			advance:   30 * time.Second,
+			wantCalls: 1,
+		},
+		{
+			name:      "refetch after ttl",
+			gateway:   &countingGateway{status: "created"},
+			cfg:       CacheConfig{StatusTTL: time.Minute},
+			advance:   time.Minute,
+			wantCalls: 2,
+		},
+		{
+			name:      "not found is cached",
+			gateway:   &countingGateway{err: &StatusError{Code: 404}},
+			cfg:       CacheConfig{NotFoundTTL: time.Minute},
+			advance:   30 * time.Second,
+			wantErr:   ErrOrderNotFound,
+			wantCalls: 1,
+		},
+		{
+			name:      "upstream errors are not cached",
+			gateway:   &countingGateway{err: &StatusError{Code: 503}},
+			cfg:       CacheConfig{StatusTTL: time.Minute},
+			wantErr:   ErrUpstreamUnavailable,
+			wantCalls: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Unix(0, 0)
+			g := NewCachingGateway(tt.gateway, tt.cfg)
+			g.now = func() time.Time { return now }
+
+			for range 2 {
+				_, err := g.GetStatus(context.Background(), "o-1")
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("GetStatus() error = %v, want %v", err, tt.wantErr)
+				}
+				now = now.Add(tt.advance)
+			}
+
+			if got := tt.gateway.calls.Load(); got != tt.wantCalls {
+				t.Fatalf("order service calls = %d, want %d", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestCachingGatewayEvictsAndInvalidates(t *testing.T) {
+	gw := &countingGateway{status: "created"}
+	g := NewCachingGateway(gw, CacheConfig{StatusTTL: time.Minute, MaxEntries: 2})
+
+	for _, id := range []string{"a", "b", "c", "a"} {
+		_, _ = g.GetStatus(context.Background(), id)
+	}
+	// "a" вытеснен при добавлении "c"
+	if got := gw.calls.Load(); got != 4 {
+		t.Fatalf("calls = %d, want 4", got)
+	}
+
+	g.Invalidate("c")
+	_, _ = g.GetStatus(context.Background(), "c")
+	if got := gw.calls.Load(); got != 5 {
+		t.Fatalf("invalidated status must be refetched, calls = %d", got)
+	}
+}
+
+// TestCachingGatewayInvalidateDuringLookup — ответ запроса, начатого до Invalidate,
+// не возвращается в кэш
+func TestCachingGatewayInvalidateDuringLookup(t *testing.T) {
+	gw := &countingGateway{status: "created", release: make(chan struct{})}
+	g := NewCachingGateway(gw, CacheConfig{StatusTTL: time.Minute})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = g.GetStatus(context.Background(), "o-1")
+	}()
+
+	for gw.calls.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	g.Invalidate("o-1")
+	close(gw.release)
+	<-done
+
+	_, _ = g.GetStatus(context.Background(), "o-1")
+	if got := gw.calls.Load(); got != 2 {
+		t.Fatalf("status fetched before Invalidate must not be cached, calls = %d", got)
+	}
+	if len(g.statuses.fills) != 0 {
+		t.Fatalf("fills leaked: %v", g.statuses.fills)
+	}
+}
+
+func TestCachingGatewayCoalescesConcurrentLookups(t *testing.T) {
+	gw := &countingGateway{status: "created", release: make(chan struct{})}
+	g := NewCachingGateway(gw, CacheConfig{StatusTTL: time.Minute})
+
+	const callers = 10
+	var wg sync.WaitGroup
+	statuses := make([]string, callers)
+	for i := range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			statuses[i], _ = g.GetStatus(context.Background(), "o-1")
+		}()
+	}
+
+	// ждём, пока первый запрос дойдёт до order-сервиса, и даём остальным присоединиться
+	for gw.calls.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(gw.release)
+	wg.Wait()
+
+	if got := gw.calls.Load(); got != 1 {
+		t.Fatalf("calls = %d, want 1", got)
+	}
+	for i, s := range statuses {
+		if s != "created" {
+			t.Fatalf("caller %d got %q", i, s)
+		}
+	}
+}
+
+// TestCachingGatewayLeaderCancelDoesNotFailWaiters — отмена первого вызвавшего
+// не отменяет общий запрос для остальных
+func TestCachingGatewayLeaderCancelDoesNotFailWaiters(t *testing.T) {
+	gw := &countingGateway{status: "created", release: make(chan struct{})}
+	g := NewCachingGateway(gw, CacheConfig{StatusTTL: time.Minute})
+
+	leaderCtx, cancel := context.WithCancel(context.Background())
+	leaderErr := make(chan error, 1)
+	go func() {
+		_, err := g.GetStatus(leaderCtx, "o-1")
+		leaderErr <- err
+	}()
+
+	for gw.calls.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	waiter := make(chan string, 1)
+	go func() {
+		s, _ := g.GetStatus(context.Background(), "o-1")
+		waiter <- s
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	cancel()
+	if err := <-leaderErr; !errors.Is(err, context.Canceled) {
+		t.Fatalf("leader error = %v, want context.Canceled", err)
+	}
+
+	close(gw.release)
+	if s := <-waiter; s != "created" {
+		t.Fatalf("waiter got %q, want created", s)
+	}
+	if got := gw.calls.Load(); got != 1 {
+		t.Fatalf("calls = %d, want 1", got)
+	}
+}
+
+func TestCachingGatewayCallTimeout(t *testing.T) {
+	gw := &countingGateway{release: make(chan struct{})}
+	defer close(gw.release)
+	g := NewCachingGateway(gw, CacheConfig{CallTimeout: 10 * time.Millisecond})
+
+	if _, err := g.GetStatus(context.Background(), "o-1"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("GetStatus() error = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestCachingGatewayStatuses(t *testing.T) {
+	next := &countingGateway{status: "created"}
+	g := NewCachingGateway(next, CacheConfig{})
+
+	if _, err := g.GetStatus(context.Background(), "o-1"); err != nil {
+		t.Fatalf("GetStatus() error = %v", err)
+	}
+
+	results, err := g.GetStatuses(context.Background(), []string{"o-1", "o-2", "o-2"})
+	if err != nil {
+		t.Fatalf("GetStatuses() error = %v", err)
+	}
+	if len(results) != 2 || results["o-1"].Status != "created" || results["o-2"].Status != "created" {
+		t.Fatalf("unexpected results %+v", results)
+	}
+	if len(next.requested) != 1 || next.requested[0] != "o-2" {
+		t.Fatalf("requested %v from next, want only o-2", next.requested)
+	}
+
+	// оба статуса теперь в кэше
+	if _, err := g.GetStatuses(context.Background(), []string{"o-1", "o-2"}); err != nil {
+		t.Fatalf("GetStatuses() error = %v", err)
+	}
+	if got := next.calls.Load(); got != 2 {
+		t.Fatalf("next calls = %d, want 2", got)
+	}
+}
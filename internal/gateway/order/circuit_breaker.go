@@ -0,0 +1,243 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/Avito-courses/course-go-avito-israpilovsha/internal/metrics"
+)
+
+// ErrCircuitOpen — order-сервис считается недоступным, вызов отклонён без запроса
+var ErrCircuitOpen = errors.New("order gateway: circuit open")
+
+// BreakerState — состояние circuit breaker; значение экспортируется метрикой
+type BreakerState int
+
+const (
+	BreakerClosed BreakerState = iota
+	BreakerHalfOpen
+	BreakerOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerHalfOpen:
+		return "half_open"
+	default:
+		return "open"
+	}
+}
+
+// BreakerConfig — параметры circuit breaker; нулевые поля заменяются значениями по умолчанию
+type BreakerConfig struct {
+	// FailureRatio — доля ошибок в окне, после которой breaker открывается
+	FailureRatio float64
+	// MinRequests — минимум вызовов в окне, прежде чем оценивать долю ошибок
+	MinRequests int
+	// Window — окно подсчёта вызовов в закрытом состоянии
+	Window time.Duration
+	// CoolDown — сколько breaker остаётся открытым до пробных вызовов
+	CoolDown time.Duration
+	// HalfOpenRequests — сколько пробных вызовов подряд должны пройти, чтобы закрыться
+	HalfOpenRequests int
+}
+
+// CircuitBreaker — Gateway, который перестаёт обращаться к order-сервису,
+// пока тот стабильно отвечает ошибками
+type CircuitBreaker struct {
+	next Gateway
+	cfg  BreakerConfig
+	log  Logger
+	now  func() time.Time
+
+	mu          sync.Mutex
+	state       BreakerState
+	windowStart time.Time
+	requests    int
+	failures    int
+	openedAt    time.Time
+	// probes — пробные вызовы в полуоткрытом состоянии: запущенные и успешные
+	probes    int
+	successes int
+}
+
+func NewCircuitBreaker(next Gateway, cfg BreakerConfig, log Logger) *CircuitBreaker {
+	if cfg.FailureRatio <= 0 || cfg.FailureRatio > 1 {
+		cfg.FailureRatio = 0.5
+	}
+	if cfg.MinRequests <= 0 {
+		cfg.MinRequests = 10
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = 30 * time.Second
+	}
+	if cfg.CoolDown <= 0 {
+		cfg.CoolDown = 10 * time.Second
+	}
+	if cfg.HalfOpenRequests <= 0 {
+		cfg.HalfOpenRequests = 1
+	}
+	if log == nil {
+		log = noopLogger{}
+	}
+
+	b := &CircuitBreaker{next: next, cfg: cfg, log: log, now: time.Now}
+	b.windowStart = b.now()
+	metrics.OrderGatewayCircuitState.Set(float64(BreakerClosed))
+	return b
+}
+
+func (b *CircuitBreaker) FetchOrders(ctx context.Context, from time.Time, cursor string) (OrdersPage, error) {
+	if err := b.allow(); err != nil {
+		return OrdersPage{}, err
+	}
+
+	page, err := b.next.FetchOrders(ctx, from, cursor)
+	b.record(err)
+	return page, err
+}
+
+func (b *CircuitBreaker) GetStatus(ctx context.Context, id string) (string, error) {
+	if err := b.allow(); err != nil {
+		return "", err
+	}
+
+	status, err := b.next.GetStatus(ctx, id)
+	b.record(err)
+	return status, err
+}
+
+// GetStatuses считается одним вызовом: он неудачен, если order-сервис недоступен хотя бы для одного заказа
+func (b *CircuitBreaker) GetStatuses(ctx context.Context, ids []string) (map[string]StatusResult, error) {
+	if err := b.allow(); err != nil {
+		return nil, err
+	}
+
+	results, err := b.next.GetStatuses(ctx, ids)
+
+	failure := err
+	for _, r := range results {
+		if failure != nil {
+			break
+		}
+		if isBreakerFailure(r.Err) {
+			failure = r.Err
+		}
+	}
+	b.record(failure)
+	return results, err
+}
+
+// State возвращает текущее состояние с учётом истёкшего cool-down
+func (b *CircuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refresh()
+	return b.state
+}
+
+// allow решает, можно ли выполнить вызов; в полуоткрытом состоянии пропускает ограниченное число проб
+func (b *CircuitBreaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refresh()
+
+	switch b.state {
+	case BreakerOpen:
+		metrics.OrderGatewayCircuitRejectedTotal.Inc()
+		return ErrCircuitOpen
+	case BreakerHalfOpen:
+		if b.probes >= b.cfg.HalfOpenRequests {
+			metrics.OrderGatewayCircuitRejectedTotal.Inc()
+			return ErrCircuitOpen
+		}
+		b.probes++
+	}
+	return nil
+}
+
+func (b *CircuitBreaker) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if errors.Is(err, context.Canceled) {
+		// вызов отменили мы сами — он ничего не говорит о здоровье order-сервиса
+		if b.state == BreakerHalfOpen {
+			b.probes--
+		}
+		return
+	}
+	failed := isBreakerFailure(err)
+
+	switch b.state {
+	case BreakerHalfOpen:
+		if failed {
+			b.setState(BreakerOpen)
+			return
+		}
+		b.successes++
+		if b.successes >= b.cfg.HalfOpenRequests {
+			b.setState(BreakerClosed)
+		}
+
+	case BreakerClosed:
+		b.requests++
+		if failed {
+			b.failures++
+		}
+		if b.requests >= b.cfg.MinRequests &&
+			float64(b.failures)/float64(b.requests) >= b.cfg.FailureRatio {
+			b.setState(BreakerOpen)
+		}
+	}
+}
+
+// refresh сбрасывает истёкшее окно и переводит открытый breaker в полуоткрытый после cool-down
+func (b *CircuitBreaker) refresh() {
+	now := b.now()
+
+	switch b.state {
+	case BreakerClosed:
+		if now.Sub(b.windowStart) >= b.cfg.Window {
+			b.windowStart = now
+			b.requests, b.failures = 0, 0
+		}
+	case BreakerOpen:
+		if now.Sub(b.openedAt) >= b.cfg.CoolDown {
+			b.setState(BreakerHalfOpen)
+		}
+	}
+}
+
+func (b *CircuitBreaker) setState(state BreakerState) {
+	if b.state == state {
+		return
+	}
+
+	b.log.Warnw("order gateway circuit state changed", "from", b.state.String(), "to", state.String())
+	b.state = state
+	metrics.OrderGatewayCircuitState.Set(float64(state))
+
+	now := b.now()
+	switch state {
+	case BreakerOpen:
+		b.openedAt = now
+	case BreakerHalfOpen:
+		b.probes, b.successes = 0, 0
+	case BreakerClosed:
+		b.windowStart = now
+		b.requests, b.failures = 0, 0
+	}
+}
+
+// isBreakerFailure — ошибка говорит о недоступности order-сервиса.
+// Ответы 4xx (заказ не найден, неверный запрос) сервис дал штатно и breaker не открывают.
+func isBreakerFailure(err error) bool {
+	return errors.Is(err, ErrUpstreamUnavailable) || errors.Is(err, context.DeadlineExceeded)
+}
@@ -0,0 +1,158 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+type scriptedGateway struct {
+	errs  []error
+	calls int
+}
+
+func (g *scriptedGateway) FetchOrders(context.Context, time.Time, string) (OrdersPage, error) {
+	return OrdersPage{}, g.next()
+}
+
+func (g *scriptedGateway) GetStatus(context.Context, string) (string, error) {
+	if err := g.next(); err != nil {
+		return "", err
+	}
+	return "created", nil
+}
+
+// GetStatuses отдаёт очередную ошибку каждому заказу, как пачка с недоступным order-сервисом
+func (g *scriptedGateway) GetStatuses(_ context.Context, ids []string) (map[string]StatusResult, error) {
+	err := g.next()
+	results := make(map[string]StatusResult, len(ids))
+	for _, id := range ids {
+		results[id] = StatusResult{Status: "created", Err: err}
+	}
+	return results, nil
+}
+
+func (g *scriptedGateway) next() error {
+	g.calls++
+	if len(g.errs) == 0 {
+		return nil
+	}
+	err := g.errs[0]
+	g.errs = g.errs[1:]
+	return err
+}
+
+func TestCircuitBreakerTransitions(t *testing.T) {
+	errDown := &StatusError{Code: 503}
+	errMissing := fmt.Errorf("get status: %w", &StatusError{Code: 404})
+
+	tests := []struct {
+		name      string
+		errs      []error
+		advance   time.Duration
+		batch     bool
+		wantState BreakerState
+	}{
+		{
+			name:      "opens when failure ratio reached",
+			errs:      []error{errDown, nil, errDown, errDown},
+			wantState: BreakerOpen,
+		},
+		{
+			name:      "stays closed below min requests",
+			errs:      []error{errDown, errDown, errDown},
+			wantState: BreakerClosed,
+		},
+		{
+			name:      "client errors do not count as failures",
+			errs:      []error{errMissing, errMissing, errMissing, errMissing},
+			wantState: BreakerClosed,
+		},
+		{
+			name:      "canceled calls are ignored",
+			errs:      []error{context.Canceled, context.Canceled, context.Canceled, context.Canceled},
+			wantState: BreakerClosed,
+		},
+		{
+			name:      "batch with unavailable orders counts as failure",
+			errs:      []error{errDown, errDown, nil, errDown},
+			batch:     true,
+			wantState: BreakerOpen,
+		},
+		{
+			name:      "half-open after cool-down",
+			errs:      []error{errDown, errDown, errDown, errDown},
+			advance:   time.Minute,
+			wantState: BreakerHalfOpen,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Unix(0, 0)
+			gw := &scriptedGateway{errs: tt.errs}
+			b := NewCircuitBreaker(gw, BreakerConfig{
+				FailureRatio: 0.5,
+				MinRequests:  4,
+				Window:       time.Hour,
+				CoolDown:     time.Minute,
+			}, nil)
+			b.now = func() time.Time { return now }
+
+			for range tt.errs {
+				if tt.batch {
+					_, _ = b.GetStatuses(context.Background(), []string{"o-1", "o-2"})
+				} else {
+					_, _ = b.GetStatus(context.Background(), "o-1")
+				}
+			}
+			now = now.Add(tt.advance)
+
+			if got := b.State(); got != tt.wantState {
+				t.Fatalf("State() = %s, want %s", got, tt.wantState)
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerFailsFastAndRecovers(t *testing.T) {
+	now := time.Unix(0, 0)
+	errDown := fmt.Errorf("%w: connection refused", ErrUpstreamUnavailable)
+	gw := &scriptedGateway{errs: []error{errDown, errDown, errDown}}
+
+	b := NewCircuitBreaker(gw, BreakerConfig{
+		FailureRatio:     1,
+		MinRequests:      1,
+		CoolDown:         time.Minute,
+		HalfOpenRequests: 2,
+	}, nil)
+	b.now = func() time.Time { return now }
+
+	_, _ = b.GetStatus(context.Background(), "o-1")
+	if _, err := b.GetStatus(context.Background(), "o-1"); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected ErrCircuitOpen, got %v", err)
+	}
+	if gw.calls != 1 {
+		t.Fatalf("open breaker must not call order service, calls = %d", gw.calls)
+	}
+
+	// неудачная проба снова открывает breaker
+	now = now.Add(time.Minute)
+	_, _ = b.GetStatus(context.Background(), "o-1")
+	if b.State() != BreakerOpen {
+		t.Fatalf("failed probe must reopen breaker, state = %s", b.State())
+	}
+
+	now = now.Add(time.Minute)
+	gw.errs = nil
+	for range 2 {
+		if _, err := b.GetStatus(context.Background(), "o-1"); err != nil {
+			t.Fatalf("probe failed: %v", err)
+		}
+	}
+	if b.State() != BreakerClosed {
+		t.Fatalf("successful probes must close breaker, state = %s", b.State())
+	}
+}
@@ -10,7 +10,24 @@ type Order struct {
 	CreatedAt time.Time `json:"created_at"`
 }
 
+// StatusResult — статус одного заказа из пакетного запроса; Err — например ErrOrderNotFound
+type StatusResult struct {
+	Status string
+	Err    error
+}
+
+// OrdersPage — страница заказов по возрастанию created_at
+type OrdersPage struct {
+	Orders []Order
+	// Next — курсор следующей страницы; пустой — страница последняя
+	Next string
+}
+
 type Gateway interface {
-	FetchOrders(ctx context.Context, from time.Time) ([]Order, error)
+	// FetchOrders отдаёт одну страницу заказов, созданных после from; cursor — Next предыдущей
+	// страницы того же from, пустой — первая страница
+	FetchOrders(ctx context.Context, from time.Time, cursor string) (OrdersPage, error)
 	GetStatus(ctx context.Context, id string) (string, error)
+	// GetStatuses возвращает результат для каждого из ids; ошибка — только если вызов прерван целиком
+	GetStatuses(ctx context.Context, ids []string) (map[string]StatusResult, error)
 }
@@ -0,0 +1,52 @@
+package order
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+var (
+	// ErrOrderNotFound — order-сервис не знает такого заказа
+	ErrOrderNotFound = errors.New("order gateway: order not found")
+	// ErrUnauthorized — order-сервис отклонил учётные данные сервиса
+	ErrUnauthorized = errors.New("order gateway: unauthorized")
+	// ErrUpstreamUnavailable — order-сервис недоступен или перегружен (сеть, 429, 5xx)
+	ErrUpstreamUnavailable = errors.New("order gateway: upstream unavailable")
+)
+
+// StatusError — неуспешный HTTP-ответ order-сервиса.
+// Сопоставляется с ErrOrderNotFound, ErrUnauthorized и ErrUpstreamUnavailable через errors.Is.
+type StatusError struct {
+	Code int
+	Body string
+	// RetryAfter — пауза из заголовка Retry-After (429/503); 0 — не задана
+	RetryAfter time.Duration
+}
+
+func (e *StatusError) Error() string {
+	msg := fmt.Sprintf("order gateway: status %d %s", e.Code, http.StatusText(e.Code))
+	if e.Body != "" {
+		msg += ": " + e.Body
+	}
+	return msg
+}
+
+func (e *StatusError) Is(target error) bool {
+	switch target {
+	case ErrOrderNotFound:
+		return e.Code == http.StatusNotFound
+	case ErrUnauthorized:
+		return e.Code == http.StatusUnauthorized || e.Code == http.StatusForbidden
+	case ErrUpstreamUnavailable:
+		return e.Retryable()
+	default:
+		return false
+	}
+}
+
+// Retryable — ответ временный, запрос имеет смысл повторить
+func (e *StatusError) Retryable() bool {
+	return e.Code == http.StatusTooManyRequests || e.Code >= 500
+}
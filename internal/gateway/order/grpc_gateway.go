@@ -0,0 +1,226 @@
+package order
+
+//go:generate protoc --go_out=. --go_opt=module=github.com/Avito-courses/course-go-avito-israpilovsha/internal/gateway/order --go-grpc_out=. --go-grpc_opt=module=github.com/Avito-courses/course-go-avito-israpilovsha/internal/gateway/order order.proto
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/Avito-courses/course-go-avito-israpilovsha/internal/gateway/order/orderpb"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// nextCursorTrailer — курсор следующей страницы FetchOrders в trailer-метаданных потока;
+// пустой — страница последняя
+const nextCursorTrailer = "x-next-cursor"
+
+// RPCError — неуспешный gRPC-ответ order-сервиса.
+// Сопоставляется с ErrOrderNotFound, ErrUnauthorized и ErrUpstreamUnavailable через errors.Is.
+type RPCError struct {
+	Code    codes.Code
+	Message string
+	// RetryAfter — пауза из google.rpc.RetryInfo; 0 — не задана
+	RetryAfter time.Duration
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("order gateway: rpc %s: %s", e.Code, e.Message)
+}
+
+func (e *RPCError) Is(target error) bool {
+	switch target {
+	case ErrOrderNotFound:
+		return e.Code == codes.NotFound
+	case ErrUnauthorized:
+		return e.Code == codes.Unauthenticated || e.Code == codes.PermissionDenied
+	case ErrUpstreamUnavailable:
+		return e.Retryable()
+	default:
+		return false
+	}
+}
+
+// Retryable — ответ временный, вызов имеет смысл повторить
+func (e *RPCError) Retryable() bool {
+	switch e.Code {
+	case codes.Unavailable, codes.ResourceExhausted, codes.Aborted, codes.DeadlineExceeded:
+		return true
+	default:
+		return false
+	}
+}
+
+type grpcGateway struct {
+	options
+
+	client  orderpb.OrderServiceClient
+	noBatch atomic.Bool
+}
+
+// NewGRPCGateway — Gateway поверх gRPC API order-сервиса с теми же повторами и таймаутами, что и HTTP
+func NewGRPCGateway(conn grpc.ClientConnInterface, opts ...Option) Gateway {
+	return &grpcGateway{options: newOptions(opts), client: orderpb.NewOrderServiceClient(conn)}
+}
+
+// FetchOrders забирает страницу заказов потоком: limit — размер страницы, курсор следующей
+// страницы приходит в trailer x-next-cursor
+func (g *grpcGateway) FetchOrders(ctx context.Context, from time.Time, cursor string) (OrdersPage, error) {
+	var page OrdersPage
+	req := &orderpb.FetchOrdersRequest{From: timestamppb.New(from), Limit: int32(g.pageSize), Cursor: cursor}
+
+	err := g.call(ctx, orderpb.OrderService_FetchOrders_FullMethodName, req, func(ctx context.Context) error {
+		// поток, оборвавшийся на середине, запрашивается заново целиком
+		page = OrdersPage{}
+
+		stream, err := g.client.FetchOrders(ctx, req)
+		if err != nil {
+			return err
+		}
+
+		for {
+			m, err := stream.Recv()
+			if errors.Is(err, io.EOF) {
+				if next := stream.Trailer().Get(nextCursorTrailer); len(next) > 0 {
+					page.Next = next[0]
+				}
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			page.Orders = append(page.Orders, Order{ID: m.GetId(), CreatedAt: m.GetCreatedAt().AsTime()})
+		}
+	})
+	if err != nil {
+		return OrdersPage{}, err
+	}
+
+	if page.Next != "" && page.Next == cursor {
+		return OrdersPage{}, fmt.Errorf("order gateway: next cursor %q does not advance", page.Next)
+	}
+	return page, nil
+}
+
+func (g *grpcGateway) GetStatus(ctx context.Context, id string) (string, error) {
+	var resp *orderpb.GetStatusResponse
+	req := &orderpb.GetStatusRequest{OrderId: id}
+
+	err := g.call(ctx, orderpb.OrderService_GetStatus_FullMethodName, req, func(ctx context.Context) error {
+		var err error
+		resp, err = g.client.GetStatus(ctx, req)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return resp.GetStatus(), nil
+}
+
+// GetStatuses запрашивает статусы пачками через GetStatuses, а без него — по одному через GetStatus.
+// Заказы, которых нет в order-сервисе, получают ErrOrderNotFound в StatusResult.Err.
+func (g *grpcGateway) GetStatuses(ctx context.Context, ids []string) (map[string]StatusResult, error) {
+	return g.getStatuses(ctx, ids, batchLookup{
+		batch:       g.getStatusesBatch,
+		single:      g.GetStatus,
+		unsupported: &g.noBatch,
+	})
+}
+
+func (g *grpcGateway) getStatusesBatch(ctx context.Context, ids []string) (map[string]StatusResult, error) {
+	var resp *orderpb.GetStatusesResponse
+	req := &orderpb.GetStatusesRequest{OrderIds: ids}
+
+	err := g.call(ctx, orderpb.OrderService_GetStatuses_FullMethodName, req, func(ctx context.Context) error {
+		var err error
+		resp, err = g.client.GetStatuses(ctx, req)
+		return err
+	})
+	if rerr := (*RPCError)(nil); errors.As(err, &rerr) && rerr.Code == codes.Unimplemented {
+		return nil, errBatchUnsupported
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	results := make(map[string]StatusResult, len(ids))
+	for _, s := range resp.GetStatuses() {
+		results[s.GetOrderId()] = StatusResult{Status: s.GetStatus()}
+	}
+	for _, id := range resp.GetMissing() {
+		results[id] = StatusResult{Err: ErrOrderNotFound}
+	}
+
+	return results, nil
+}
+
+// call выполняет вызов method по политике повторов с таймаутом и подписью на каждую попытку.
+// Подписывается req в wire-формате protobuf — те же байты, которые proto-кодек отправит серверу.
+func (g *grpcGateway) call(ctx context.Context, method string, req proto.Message, fn func(ctx context.Context) error) error {
+	body, err := proto.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	return g.retry.run(ctx, g.log, g.now, func() error {
+		attemptCtx, cancel := context.WithTimeout(ctx, g.timeout)
+		defer cancel()
+
+		headers, err := g.authHeaders(ctx, "POST", method, body)
+		if err != nil {
+			return err
+		}
+		for k, v := range headers {
+			attemptCtx = metadata.AppendToOutgoingContext(attemptCtx, strings.ToLower(k), v)
+		}
+
+		err = fn(attemptCtx)
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		err = rpcError(err)
+		g.checkAuth(err)
+		return err
+	}, classifyGRPC)
+}
+
+// rpcError переводит gRPC status в RPCError
+func rpcError(err error) error {
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+
+	e := &RPCError{Code: st.Code(), Message: st.Message()}
+	for _, d := range st.Details() {
+		if ri, ok := d.(*errdetails.RetryInfo); ok && ri.GetRetryDelay() != nil {
+			e.RetryAfter = ri.GetRetryDelay().AsDuration()
+		}
+	}
+	return e
+}
+
+// classifyGRPC решает, повторять ли ошибку gRPC-вызова, и достаёт паузу из RetryInfo
+func classifyGRPC(err error) (bool, time.Duration) {
+	var re *RPCError
+	if errors.As(err, &re) {
+		return re.Retryable(), re.RetryAfter
+	}
+	// ошибка до самого вызова, например при получении OAuth2-токена по HTTP
+	return classifyHTTP(err)
+}
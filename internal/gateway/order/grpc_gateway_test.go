@@ -0,0 +1,304 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Avito-courses/course-go-avito-israpilovsha/internal/gateway/order/orderpb"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// fakeOrderService — in-process реализация order.v1.OrderService для тестов
+type fakeOrderService struct {
+	orderpb.UnimplementedOrderServiceServer
+
+	statuses map[string]string
+	orders   []Order
+	// unavailable — сколько первых вызовов GetStatus ответят UNAVAILABLE с RetryInfo
+	unavailable atomic.Int32
+	calls       atomic.Int32
+	// noBatch — сервис старой версии без GetStatuses
+	noBatch    bool
+	batchCalls atomic.Int32
+	// interceptor — проверка вызовов на стороне сервера, например подписи
+	interceptor grpc.UnaryServerInterceptor
+}
+
+func (s *fakeOrderService) GetStatus(_ context.Context, req *orderpb.GetStatusRequest) (*orderpb.GetStatusResponse, error) {
+	s.calls.Add(1)
+	if s.unavailable.Add(-1) >= 0 {
+		st, _ := status.New(codes.Unavailable, "overloaded").
+			WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(time.Millisecond)})
+		return nil, st.Err()
+	}
+
+	id := req.GetOrderId()
+	switch id {
+	case "forbidden":
+		return nil, status.Error(codes.PermissionDenied, "no access")
+	}
+
+	st, ok := s.statuses[id]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "order not found")
+	}
+	return &orderpb.GetStatusResponse{OrderId: id, Status: st}, nil
+}
+
+func (s *fakeOrderService) GetStatuses(_ context.Context, req *orderpb.GetStatusesRequest) (*orderpb.GetStatusesResponse, error) {
+	s.batchCalls.Add(1)
+	if s.noBatch {
+		return nil, status.Error(codes.Unimplemented, "unknown method GetStatuses")
+	}
+
+	resp := &orderpb.GetStatusesResponse{}
+	for _, id := range req.GetOrderIds() {
+		if st, ok := s.statuses[id]; ok {
+			resp.Statuses = append(resp.Statuses, &orderpb.GetStatusResponse{OrderId: id, Status: st})
+		} else if id != "lost" {
+			resp.Missing = append(resp.Missing, id)
+		}
+	}
+	return resp, nil
+}
+
+func (s *fakeOrderService) FetchOrders(req *orderpb.FetchOrdersRequest, stream grpc.ServerStreamingServer[orderpb.Order]) error {
+	from := req.GetFrom().AsTime()
+
+	var orders []Order
+	for _, o := range s.orders {
+		if o.CreatedAt.After(from) {
+			orders = append(orders, o)
+		}
+	}
+	// курсор — offset следующей страницы, как у фейкового order-сервиса
+	offset, _ := strconv.Atoi(req.GetCursor())
+	orders = orders[min(offset, len(orders)):]
+	if limit := int(req.GetLimit()); limit > 0 && len(orders) > limit {
+		orders = orders[:limit]
+		stream.SetTrailer(metadata.Pairs(nextCursorTrailer, strconv.Itoa(offset+limit)))
+	}
+
+	for _, o := range orders {
+		if err := stream.Send(&orderpb.Order{Id: o.ID, CreatedAt: timestamppb.New(o.CreatedAt)}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func newBufconnGateway(t *testing.T, svc *fakeOrderService, opts ...Option) Gateway {
+	t.Helper()
+
+	lis := bufconn.Listen(1 << 20)
+	var serverOpts []grpc.ServerOption
+	if svc.interceptor != nil {
+		serverOpts = append(serverOpts, grpc.UnaryInterceptor(svc.interceptor))
+	}
+	srv := grpc.NewServer(serverOpts...)
+	orderpb.RegisterOrderServiceServer(srv, svc)
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("dial bufconn: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return NewGRPCGateway(conn, append([]Option{WithRetryPolicy(RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   time.Millisecond,
+		MaxDelay:    time.Millisecond,
+	})}, opts...)...)
+}
+
+func TestGRPCGateway_GetStatus(t *testing.T) {
+	tests := []struct {
+		name        string
+		id          string
+		unavailable int32
+		want        string
+		wantErr     error
+		wantCalls   int32
+	}{
+		{name: "ok", id: "o-1", want: "created", wantCalls: 1},
+		{name: "retries unavailable", id: "o-1", unavailable: 2, want: "created", wantCalls: 3},
+		{name: "gives up after max attempts", id: "o-1", unavailable: 5, wantErr: ErrUpstreamUnavailable, wantCalls: 3},
+		{name: "not found", id: "missing", wantErr: ErrOrderNotFound, wantCalls: 1},
+		{name: "permission denied", id: "forbidden", wantErr: ErrUnauthorized, wantCalls: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &fakeOrderService{statuses: map[string]string{"o-1": "created"}}
+			svc.unavailable.Store(tt.unavailable)
+			gw := newBufconnGateway(t, svc)
+
+			got, err := gw.GetStatus(context.Background(), tt.id)
+			if !errors.Is(err, tt.wantErr) || got != tt.want {
+				t.Fatalf("GetStatus() = %q, %v; want %q, %v", got, err, tt.want, tt.wantErr)
+			}
+			if calls := svc.calls.Load(); calls != tt.wantCalls {
+				t.Fatalf("calls = %d, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestGRPCGateway_FetchOrdersStream(t *testing.T) {
+	base := time.Date(2026, 1, 1, 0, 0, 0, 500, time.UTC)
+	svc := &fakeOrderService{orders: []Order{
+		{ID: "o-1", CreatedAt: base},
+		{ID: "o-2", CreatedAt: base.Add(time.Second)},
+		{ID: "o-3", CreatedAt: base.Add(2 * time.Second)},
+	}}
+	gw := newBufconnGateway(t, svc)
+
+	page, err := gw.FetchOrders(context.Background(), base, "")
+	if err != nil {
+		t.Fatalf("FetchOrders() error = %v", err)
+	}
+	if len(page.Orders) != 2 || page.Orders[0].ID != "o-2" || !page.Orders[1].CreatedAt.Equal(base.Add(2*time.Second)) {
+		t.Fatalf("unexpected orders %+v", page.Orders)
+	}
+}
+
+func TestGRPCGateway_FetchOrdersPages(t *testing.T) {
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	svc := &fakeOrderService{orders: []Order{
+		{ID: "o-1", CreatedAt: base.Add(time.Second)},
+		{ID: "o-2", CreatedAt: base.Add(2 * time.Second)},
+		{ID: "o-3", CreatedAt: base.Add(3 * time.Second)},
+	}}
+	gw := newBufconnGateway(t, svc, WithPageSize(2))
+
+	first, err := gw.FetchOrders(context.Background(), base, "")
+	if err != nil {
+		t.Fatalf("FetchOrders() error = %v", err)
+	}
+	if len(first.Orders) != 2 || first.Next != "2" {
+		t.Fatalf("unexpected first page %+v", first)
+	}
+
+	last, err := gw.FetchOrders(context.Background(), base, first.Next)
+	if err != nil {
+		t.Fatalf("FetchOrders() error = %v", err)
+	}
+	if len(last.Orders) != 1 || last.Orders[0].ID != "o-3" || last.Next != "" {
+		t.Fatalf("unexpected last page %+v", last)
+	}
+}
+
+func TestGRPCGateway_GetStatuses(t *testing.T) {
+	tests := []struct {
+		name           string
+		noBatch        bool
+		wantBatchCalls int32
+		wantSingle     int32
+	}{
+		// 3 уникальных заказа пачками по 2 — два вызова на каждый GetStatuses
+		{name: "batch endpoint in chunks", wantBatchCalls: 4},
+		// первый вызов узнаёт, что GetStatuses нет, дальше статусы запрашиваются по одному
+		{name: "falls back to single lookups", noBatch: true, wantBatchCalls: 1, wantSingle: 6},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &fakeOrderService{statuses: map[string]string{"o-1": "created", "o-2": "cancelled"}, noBatch: tt.noBatch}
+			gw := newBufconnGateway(t, svc, WithBatch(2, 1))
+
+			ids := []string{"o-1", "o-2", "missing", "o-1"}
+			for range 2 {
+				results, err := gw.GetStatuses(context.Background(), ids)
+				if err != nil {
+					t.Fatalf("GetStatuses() error = %v", err)
+				}
+				if len(results) != 3 || results["o-1"].Status != "created" || results["o-2"].Status != "cancelled" {
+					t.Fatalf("unexpected statuses %+v", results)
+				}
+				if !errors.Is(results["missing"].Err, ErrOrderNotFound) {
+					t.Fatalf("expected ErrOrderNotFound for missing, got %v", results["missing"].Err)
+				}
+			}
+
+			if got := svc.batchCalls.Load(); got != tt.wantBatchCalls {
+				t.Fatalf("batch calls = %d, want %d", got, tt.wantBatchCalls)
+			}
+			if got := svc.calls.Load(); got != tt.wantSingle {
+				t.Fatalf("single calls = %d, want %d", got, tt.wantSingle)
+			}
+		})
+	}
+}
+
+func TestGRPCGateway_GetStatusesAbsentOrder(t *testing.T) {
+	svc := &fakeOrderService{statuses: map[string]string{"o-1": "created"}}
+	gw := newBufconnGateway(t, svc)
+
+	results, err := gw.GetStatuses(context.Background(), []string{"o-1", "lost"})
+	if err != nil {
+		t.Fatalf("GetStatuses() error = %v", err)
+	}
+	if results["lost"].Err == nil {
+		t.Fatal("expected error for order absent from the response")
+	}
+}
+
+// TestGRPCGateway_HMACSignsRequest — подпись покрывает сообщение запроса,
+// поэтому её нельзя повторить с другим order_id
+func TestGRPCGateway_HMACSignsRequest(t *testing.T) {
+	signer := NewHMACSigner("key-1", []byte("secret"))
+
+	var signatures []string
+	svc := &fakeOrderService{statuses: map[string]string{"o-1": "created", "o-2": "cancelled"}}
+	svc.interceptor = func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		get := func(key string) string {
+			if v := md.Get(key); len(v) > 0 {
+				return v[0]
+			}
+			return ""
+		}
+
+		body, err := proto.Marshal(req.(proto.Message))
+		if err != nil {
+			return nil, err
+		}
+		sig := get(HeaderAuthSignature)
+		want := signer.Signature("POST", info.FullMethod, get(HeaderAuthTimestamp), body)
+		if sig != want {
+			return nil, status.Error(codes.Unauthenticated, "bad signature")
+		}
+		signatures = append(signatures, sig)
+		return handler(ctx, req)
+	}
+	gw := newBufconnGateway(t, svc, WithSigner(signer))
+
+	for _, id := range []string{"o-1", "o-2"} {
+		if _, err := gw.GetStatus(context.Background(), id); err != nil {
+			t.Fatalf("GetStatus(%s) error = %v", id, err)
+		}
+	}
+
+	if len(signatures) != 2 || signatures[0] == signatures[1] {
+		t.Fatalf("expected distinct signatures per order, got %v", signatures)
+	}
+}
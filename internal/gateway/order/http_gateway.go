@@ -1,6 +1,7 @@
 package order
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
@@ -10,95 +11,91 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"syscall"
 	"time"
-
-	"github.com/Avito-courses/course-go-avito-israpilovsha/internal/metrics"
 )
 
-var errRetryableHTTP = errors.New("retryable http status")
-
-type Logger interface {
-	Warnw(msg string, keysAndValues ...any)
-}
-
-type noopLogger struct{}
+// maxErrorBody — сколько байт тела неуспешного ответа сохранять в StatusError
+const maxErrorBody = 4 << 10
 
-func (noopLogger) Warnw(string, ...any) {}
+// nextCursorHeader — курсор следующей страницы FetchOrders; пустой — страница последняя
+const nextCursorHeader = "X-Next-Cursor"
 
 type httpGateway struct {
+	options
+
 	baseURL string
 	client  *http.Client
-	log     Logger
-
-	maxRetries int
-	baseDelay  time.Duration
+	noBatch atomic.Bool
 }
 
-func NewHTTPGateway(baseURL string) Gateway {
-	return &httpGateway{
-		baseURL:    baseURL,
-		client:     &http.Client{Timeout: 5 * time.Second},
-		log:        noopLogger{},
-		maxRetries: 4,
-		baseDelay:  100 * time.Millisecond,
-	}
-}
+func NewHTTPGateway(baseURL string, opts ...Option) Gateway {
+	o := newOptions(opts)
 
-func WithLogger(g Gateway, log Logger) Gateway {
-	hg, ok := g.(*httpGateway)
-	if !ok {
-		return g
+	return &httpGateway{
+		options: o,
+		baseURL: baseURL,
+		client:  &http.Client{Timeout: o.timeout},
 	}
-	hg.log = log
-	return hg
 }
 
-func (g *httpGateway) FetchOrders(ctx context.Context, from time.Time) ([]Order, error) {
+// FetchOrders забирает страницу заказов: limit — размер страницы, курсор следующей страницы
+// приходит в заголовке X-Next-Cursor. Сервис без постраничной выдачи отдаёт все заказы
+// одним ответом без курсора.
+func (g *httpGateway) FetchOrders(ctx context.Context, from time.Time, cursor string) (OrdersPage, error) {
 	u, err := url.Parse(g.baseURL)
 	if err != nil {
-		return nil, err
+		return OrdersPage{}, err
 	}
 
 	u.Path = "/public/api/v1/orders"
 	q := u.Query()
 	q.Set("from", from.UTC().Format(time.RFC3339Nano))
+	if g.pageSize > 0 {
+		q.Set("limit", strconv.Itoa(g.pageSize))
+	}
+	if cursor != "" {
+		q.Set("cursor", cursor)
+	}
 	u.RawQuery = q.Encode()
 
-	var orders []Order
-
-	err = g.doWithRetry(ctx, func() (*http.Response, error) {
+	var page OrdersPage
+	err = g.doWithRetry(ctx, func() (*http.Request, error) {
 		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
 		if err != nil {
 			return nil, err
 		}
 		req.Header.Set("Accept", "application/json")
-		req.Header.Set("X-Bypass-Auth", "true")
-		return g.client.Do(req)
+		return req, nil
 	}, func(resp *http.Response) error {
 		defer func() { _ = resp.Body.Close() }()
 
-		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
-			return fmt.Errorf("%w: status %s", errRetryableHTTP, resp.Status)
-		}
-
 		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-			return fmt.Errorf("order gateway: status %s", resp.Status)
+			return statusError(resp)
 		}
 
-		return json.NewDecoder(resp.Body).Decode(&orders)
+		page = OrdersPage{}
+		if err := json.NewDecoder(resp.Body).Decode(&page.Orders); err != nil {
+			return fmt.Errorf("order gateway: decode orders: %w", err)
+		}
+		page.Next = resp.Header.Get(nextCursorHeader)
+		return nil
 	})
-
 	if err != nil {
-		return nil, err
+		return OrdersPage{}, err
 	}
 
-	return orders, nil
+	if page.Next != "" && page.Next == cursor {
+		return OrdersPage{}, fmt.Errorf("order gateway: next cursor %q does not advance", page.Next)
+	}
+	return page, nil
 }
 
 func (g *httpGateway) GetStatus(ctx context.Context, id string) (string, error) {
 	u, err := url.Parse(g.baseURL)
-	g.log.Warnw("gateway GetStatus called", "order_id", id, "url", u.String())
 	if err != nil {
 		return "", err
 	}
@@ -107,23 +104,18 @@ func (g *httpGateway) GetStatus(ctx context.Context, id string) (string, error)
 
 	var status string
 
-	err = g.doWithRetry(ctx, func() (*http.Response, error) {
+	err = g.doWithRetry(ctx, func() (*http.Request, error) {
 		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
 		if err != nil {
 			return nil, err
 		}
 		req.Header.Set("Accept", "application/json")
-		req.Header.Set("X-Bypass-Auth", "true")
-		return g.client.Do(req)
+		return req, nil
 	}, func(resp *http.Response) error {
 		defer func() { _ = resp.Body.Close() }()
 
-		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
-			return fmt.Errorf("%w: status %s", errRetryableHTTP, resp.Status)
-		}
-
 		if resp.StatusCode != http.StatusOK {
-			return fmt.Errorf("order gateway: status %s", resp.Status)
+			return statusError(resp)
 		}
 
 		var res struct {
@@ -131,7 +123,7 @@ func (g *httpGateway) GetStatus(ctx context.Context, id string) (string, error)
 			Status  string `json:"status"`
 		}
 		if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
-			return err
+			return fmt.Errorf("order gateway: decode status: %w", err)
 		}
 		status = res.Status
 		return nil
@@ -144,53 +136,147 @@ func (g *httpGateway) GetStatus(ctx context.Context, id string) (string, error)
 	return status, nil
 }
 
+// GetStatuses запрашивает статусы пачками через POST /public/api/v1/orders/statuses.
+// Если order-сервис его не знает (404, 405, 501), статусы запрашиваются по одному.
+func (g *httpGateway) GetStatuses(ctx context.Context, ids []string) (map[string]StatusResult, error) {
+	return g.getStatuses(ctx, ids, batchLookup{
+		batch:       g.getStatusesBatch,
+		single:      g.GetStatus,
+		unsupported: &g.noBatch,
+	})
+}
+
+func (g *httpGateway) getStatusesBatch(ctx context.Context, ids []string) (map[string]StatusResult, error) {
+	u, err := url.Parse(g.baseURL)
+	if err != nil {
+		return nil, err
+	}
+	u.Path = "/public/api/v1/orders/statuses"
+
+	body, err := json.Marshal(struct {
+		OrderIDs []string `json:"order_ids"`
+	}{OrderIDs: ids})
+	if err != nil {
+		return nil, err
+	}
+
+	var res struct {
+		Statuses []struct {
+			OrderID string `json:"order_id"`
+			Status  string `json:"status"`
+		} `json:"statuses"`
+		// Missing — заказы, которых нет в order-сервисе
+		Missing []string `json:"missing"`
+	}
+
+	err = g.doWithRetry(ctx, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "application/json")
+		req.Header.Set("Content-Type", "application/json")
+		return req, nil
+	}, func(resp *http.Response) error {
+		defer func() { _ = resp.Body.Close() }()
+
+		switch resp.StatusCode {
+		case http.StatusOK:
+		case http.StatusNotFound, http.StatusMethodNotAllowed, http.StatusNotImplemented:
+			return errBatchUnsupported
+		default:
+			return statusError(resp)
+		}
+
+		if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+			return fmt.Errorf("order gateway: decode statuses: %w", err)
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	results := make(map[string]StatusResult, len(ids))
+	for _, s := range res.Statuses {
+		results[s.OrderID] = StatusResult{Status: s.Status}
+	}
+	for _, id := range res.Missing {
+		results[id] = StatusResult{Err: ErrOrderNotFound}
+	}
+
+	return results, nil
+}
+
+// doWithRetry подписывает и выполняет запрос по политике повторов.
+// Повторяются сетевые ошибки, 429 и 5xx; каждая попытка подписывается заново.
 func (g *httpGateway) doWithRetry(
 	ctx context.Context,
-	do func() (*http.Response, error),
+	newRequest func() (*http.Request, error),
 	handle func(resp *http.Response) error,
 ) error {
-	delay := g.baseDelay
-
-	var lastErr error
-
-	for attempt := 0; attempt < g.maxRetries; attempt++ {
-		resp, err := do()
-		if err == nil {
-			if hErr := handle(resp); hErr == nil {
-				return nil
-			} else {
-				lastErr = hErr
-				if !errors.Is(hErr, errRetryableHTTP) {
-					return hErr
-				}
+	return g.retry.run(ctx, g.log, g.now, func() error {
+		req, err := newRequest()
+		if err != nil {
+			return err
+		}
+
+		var body []byte
+		if req.GetBody != nil {
+			rc, err := req.GetBody()
+			if err != nil {
+				return err
 			}
-		} else {
-			lastErr = err
-			if !isRetryableNetErr(err) {
+			body, err = io.ReadAll(rc)
+			_ = rc.Close()
+			if err != nil {
 				return err
 			}
 		}
 
-		metrics.GatewayRetriesTotal.Inc()
-		g.log.Warnw("gateway retry",
-			"attempt", attempt+1,
-			"max", g.maxRetries,
-			"err", lastErr,
-			"delay", delay.String(),
-		)
+		headers, err := g.authHeaders(ctx, req.Method, req.URL.RequestURI(), body)
+		if err != nil {
+			return err
+		}
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
 
-		timer := time.NewTimer(delay)
-		select {
-		case <-ctx.Done():
-			timer.Stop()
-			return ctx.Err()
-		case <-timer.C:
+		resp, err := g.client.Do(req)
+		if err != nil {
+			if ctx.Err() != nil {
+				return err
+			}
+			return fmt.Errorf("%w: %w", ErrUpstreamUnavailable, err)
 		}
 
-		delay *= 2
+		err = handle(resp)
+		g.checkAuth(err)
+		return err
+	}, classifyHTTP)
+}
+
+// classifyHTTP решает, повторять ли ошибку HTTP-вызова, и достаёт Retry-After
+func classifyHTTP(err error) (bool, time.Duration) {
+	var se *StatusError
+	if errors.As(err, &se) {
+		return se.Retryable(), se.RetryAfter
+	}
+	if errors.Is(err, ErrUpstreamUnavailable) {
+		return isRetryableNetErr(err), 0
 	}
+	return false, 0
+}
 
-	return lastErr
+// statusError читает начало тела неуспешного ответа и Retry-After в StatusError
+func statusError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	return &StatusError{
+		Code:       resp.StatusCode,
+		Body:       strings.TrimSpace(string(body)),
+		RetryAfter: parseRetryAfter(resp.Header, time.Now()),
+	}
 }
 
 func isRetryableNetErr(err error) bool {
@@ -2,8 +2,12 @@ package order
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -40,8 +44,8 @@ func TestGateway_RetryOn429_IncrementsMetricAndEventuallySucceeds(t *testing.T)
 	gw := NewHTTPGateway(srv.URL)
 
 	hg := gw.(*httpGateway)
-	hg.maxRetries = 5
-	hg.baseDelay = 1 * time.Millisecond
+	hg.retry.MaxAttempts = 5
+	hg.retry.BaseDelay = 1 * time.Millisecond
 	log := &testLogger{}
 	WithLogger(gw, log)
 
@@ -75,8 +79,8 @@ func TestGateway_NoRetryOn400(t *testing.T) {
 
 	gw := NewHTTPGateway(srv.URL)
 	hg := gw.(*httpGateway)
-	hg.maxRetries = 5
-	hg.baseDelay = 1 * time.Millisecond
+	hg.retry.MaxAttempts = 5
+	hg.retry.BaseDelay = 1 * time.Millisecond
 
 	_, err := gw.GetStatus(context.Background(), "x")
 	if err == nil {
@@ -93,8 +97,8 @@ func TestGateway_RetryOnNetworkError(t *testing.T) {
 
 	gw := NewHTTPGateway("http://127.0.0.1:1")
 	hg := gw.(*httpGateway)
-	hg.maxRetries = 3
-	hg.baseDelay = 1 * time.Millisecond
+	hg.retry.MaxAttempts = 3
+	hg.retry.BaseDelay = 1 * time.Millisecond
 
 	_, err := gw.GetStatus(context.Background(), "x")
 	if err == nil {
@@ -133,15 +137,15 @@ func TestGateway_FetchOrders_RetryAndDecode(t *testing.T) {
 
 	gw := NewHTTPGateway(srv.URL)
 	hg := gw.(*httpGateway)
-	hg.maxRetries = 5
-	hg.baseDelay = 1 * time.Millisecond
+	hg.retry.MaxAttempts = 5
+	hg.retry.BaseDelay = 1 * time.Millisecond
 
-	orders, err := gw.FetchOrders(context.Background(), time.Now())
+	page, err := gw.FetchOrders(context.Background(), time.Now(), "")
 	if err != nil {
 		t.Fatalf("expected success, got err: %v", err)
 	}
-	if len(orders) != 2 {
-		t.Fatalf("expected 2 orders, got %d", len(orders))
+	if len(page.Orders) != 2 || page.Next != "" {
+		t.Fatalf("expected 2 orders on the last page, got %+v", page)
 	}
 
 	retries := testutil.ToFloat64(metrics.GatewayRetriesTotal)
@@ -149,3 +153,260 @@ func TestGateway_FetchOrders_RetryAndDecode(t *testing.T) {
 		t.Fatalf("expected retries >=1, got %v", retries)
 	}
 }
+
+func TestGateway_TypedErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		code     int
+		body     string
+		sentinel error
+	}{
+		{name: "not found", code: http.StatusNotFound, body: `{"error":"not_found"}`, sentinel: ErrOrderNotFound},
+		{name: "unauthorized", code: http.StatusUnauthorized, sentinel: ErrUnauthorized},
+		{name: "forbidden", code: http.StatusForbidden, sentinel: ErrUnauthorized},
+		{name: "unavailable after retries", code: http.StatusServiceUnavailable, sentinel: ErrUpstreamUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.code)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+
+			gw := NewHTTPGateway(srv.URL)
+			hg := gw.(*httpGateway)
+			hg.retry.MaxAttempts = 2
+			hg.retry.BaseDelay = time.Millisecond
+
+			_, err := gw.GetStatus(context.Background(), "x")
+			if !errors.Is(err, tt.sentinel) {
+				t.Fatalf("expected %v, got %v", tt.sentinel, err)
+			}
+
+			var se *StatusError
+			if !errors.As(err, &se) || se.Code != tt.code || se.Body != tt.body {
+				t.Fatalf("expected StatusError{%d, %q}, got %#v", tt.code, tt.body, err)
+			}
+		})
+	}
+}
+
+func TestGateway_NetworkErrorIsUpstreamUnavailable(t *testing.T) {
+	gw := NewHTTPGateway("http://127.0.0.1:1")
+	hg := gw.(*httpGateway)
+	hg.retry.MaxAttempts = 1
+
+	_, err := gw.FetchOrders(context.Background(), time.Now(), "")
+	if !errors.Is(err, ErrUpstreamUnavailable) {
+		t.Fatalf("expected ErrUpstreamUnavailable, got %v", err)
+	}
+}
+
+func TestRetryPolicy_Delay(t *testing.T) {
+	p := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: 300 * time.Millisecond}
+
+	tests := []struct {
+		attempt    int
+		retryAfter time.Duration
+		want       time.Duration
+	}{
+		{attempt: 1, want: 100 * time.Millisecond},
+		{attempt: 2, want: 200 * time.Millisecond},
+		{attempt: 3, want: 300 * time.Millisecond},
+		{attempt: 10, want: 300 * time.Millisecond},
+		{attempt: 1, retryAfter: 5 * time.Second, want: 5 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := p.Delay(tt.attempt, tt.retryAfter); got != tt.want {
+			t.Fatalf("Delay(%d, %v) = %v, want %v", tt.attempt, tt.retryAfter, got, tt.want)
+		}
+	}
+
+	p.Jitter = true
+	for range 100 {
+		if d := p.Delay(3, 0); d < 0 || d >= 300*time.Millisecond {
+			t.Fatalf("jittered delay %v out of [0, 300ms)", d)
+		}
+	}
+}
+
+func TestGateway_HonorsRetryAfter(t *testing.T) {
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&hits, 1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`{"order_id":"x","status":"created"}`))
+	}))
+	defer srv.Close()
+
+	gw := NewHTTPGateway(srv.URL, WithRetryPolicy(RetryPolicy{
+		MaxAttempts: 2,
+		BaseDelay:   time.Millisecond,
+		MaxDelay:    time.Millisecond,
+		Budget:      5 * time.Second,
+	}))
+
+	start := time.Now()
+	if _, err := gw.GetStatus(context.Background(), "x"); err != nil {
+		t.Fatalf("expected success, got err: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Fatalf("expected to wait Retry-After, waited %v", elapsed)
+	}
+}
+
+func TestGateway_StopsWhenBudgetExhausted(t *testing.T) {
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.Header().Set("Retry-After", "30")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	gw := NewHTTPGateway(srv.URL, WithRetryPolicy(RetryPolicy{
+		MaxAttempts: 5,
+		BaseDelay:   time.Millisecond,
+		Budget:      time.Second,
+	}))
+
+	_, err := gw.FetchOrders(context.Background(), time.Now(), "")
+
+	var se *StatusError
+	if !errors.As(err, &se) || se.RetryAfter != 30*time.Second {
+		t.Fatalf("expected StatusError with RetryAfter=30s, got %v", err)
+	}
+	if atomic.LoadInt32(&hits) != 1 {
+		t.Fatalf("retry beyond budget must not be attempted, hits = %d", hits)
+	}
+}
+
+func TestGateway_Timeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer srv.Close()
+
+	gw := NewHTTPGateway(srv.URL,
+		WithTimeout(20*time.Millisecond),
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 1}),
+	)
+
+	if _, err := gw.GetStatus(context.Background(), "x"); !errors.Is(err, ErrUpstreamUnavailable) {
+		t.Fatalf("expected ErrUpstreamUnavailable on timeout, got %v", err)
+	}
+}
+
+func TestGateway_FetchOrdersPaginates(t *testing.T) {
+	pages := map[string]string{
+		"":   `[{"id":"o1","created_at":"2026-01-01T00:00:00Z"},{"id":"o2","created_at":"2026-01-01T00:00:01Z"}]`,
+		"c2": `[{"id":"o3","created_at":"2026-01-01T00:00:02Z"}]`,
+	}
+	var limits []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cursor := r.URL.Query().Get("cursor")
+		limits = append(limits, r.URL.Query().Get("limit"))
+		if cursor == "" {
+			w.Header().Set(nextCursorHeader, "c2")
+		}
+		_, _ = w.Write([]byte(pages[cursor]))
+	}))
+	defer srv.Close()
+
+	gw := NewHTTPGateway(srv.URL, WithPageSize(2))
+
+	first, err := gw.FetchOrders(context.Background(), time.Now(), "")
+	if err != nil {
+		t.Fatalf("FetchOrders() error = %v", err)
+	}
+	if len(first.Orders) != 2 || first.Next != "c2" {
+		t.Fatalf("unexpected first page %+v", first)
+	}
+
+	last, err := gw.FetchOrders(context.Background(), time.Now(), first.Next)
+	if err != nil {
+		t.Fatalf("FetchOrders() error = %v", err)
+	}
+	if len(last.Orders) != 1 || last.Orders[0].ID != "o3" || last.Next != "" {
+		t.Fatalf("unexpected last page %+v", last)
+	}
+	if len(limits) != 2 || limits[0] != "2" || limits[1] != "2" {
+		t.Fatalf("limits = %v, want [2 2]", limits)
+	}
+}
+
+func TestGateway_GetStatuses(t *testing.T) {
+	tests := []struct {
+		name        string
+		batch       bool
+		wantBatches int32
+		wantSingles int32
+	}{
+		// 3 уникальных заказа пачками по 2
+		{name: "batch endpoint", batch: true, wantBatches: 2},
+		{name: "falls back without batch endpoint", wantBatches: 1, wantSingles: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var batches, singles atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.URL.Path == "/public/api/v1/orders/statuses":
+					batches.Add(1)
+					if !tt.batch {
+						w.WriteHeader(http.StatusNotFound)
+						return
+					}
+					var req struct {
+						OrderIDs []string `json:"order_ids"`
+					}
+					_ = json.NewDecoder(r.Body).Decode(&req)
+
+					var statuses []string
+					var missing []string
+					for _, id := range req.OrderIDs {
+						if id == "missing" {
+							missing = append(missing, `"missing"`)
+						} else {
+							statuses = append(statuses, fmt.Sprintf(`{"order_id":%q,"status":"created"}`, id))
+						}
+					}
+					_, _ = fmt.Fprintf(w, `{"statuses":[%s],"missing":[%s]}`,
+						strings.Join(statuses, ","), strings.Join(missing, ","))
+
+				case strings.HasSuffix(r.URL.Path, "/missing/status"):
+					singles.Add(1)
+					w.WriteHeader(http.StatusNotFound)
+
+				default:
+					singles.Add(1)
+					_, _ = w.Write([]byte(`{"status":"created"}`))
+				}
+			}))
+			defer srv.Close()
+
+			gw := NewHTTPGateway(srv.URL, WithBatch(2, 1))
+
+			results, err := gw.GetStatuses(context.Background(), []string{"o1", "o2", "missing", "o1"})
+			if err != nil {
+				t.Fatalf("GetStatuses() error = %v", err)
+			}
+			if len(results) != 3 || results["o1"].Status != "created" || results["o2"].Status != "created" {
+				t.Fatalf("unexpected results %+v", results)
+			}
+			if !errors.Is(results["missing"].Err, ErrOrderNotFound) {
+				t.Fatalf("expected ErrOrderNotFound for missing, got %v", results["missing"].Err)
+			}
+			if batches.Load() != tt.wantBatches || singles.Load() != tt.wantSingles {
+				t.Fatalf("batch calls = %d, single calls = %d; want %d, %d",
+					batches.Load(), singles.Load(), tt.wantBatches, tt.wantSingles)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/Avito-courses/course-go-avito-israpilovsha/internal/metrics"
+)
+
+// Операции Gateway в метриках
+const (
+	opFetchOrders = "fetch_orders"
+	opGetStatus   = "get_status"
+	opGetStatuses = "get_statuses"
+)
+
+// Исходы вызова в метриках
+const (
+	outcomeOK        = "ok"
+	outcomeNotFound  = "not_found"
+	outcomeRetryable = "retryable"
+	outcomeFatal     = "fatal"
+	outcomeCanceled  = "canceled"
+)
+
+// InstrumentedGateway снимает метрики вызовов next: длительность по операции и исходу,
+// число запросов к order-сервису на вызов и вызовы в процессе.
+// Запросы считает RetryPolicy.run, поэтому их видно у любой реализации на его основе;
+// для остальных запросом считается сам вызов.
+type InstrumentedGateway struct {
+	next Gateway
+}
+
+func NewInstrumentedGateway(next Gateway) *InstrumentedGateway {
+	return &InstrumentedGateway{next: next}
+}
+
+func (g *InstrumentedGateway) FetchOrders(ctx context.Context, from time.Time, cursor string) (OrdersPage, error) {
+	var page OrdersPage
+	err := observe(ctx, opFetchOrders, func(ctx context.Context) (err error) {
+		page, err = g.next.FetchOrders(ctx, from, cursor)
+		return err
+	})
+	return page, err
+}
+
+func (g *InstrumentedGateway) GetStatus(ctx context.Context, id string) (string, error) {
+	var status string
+	err := observe(ctx, opGetStatus, func(ctx context.Context) (err error) {
+		status, err = g.next.GetStatus(ctx, id)
+		return err
+	})
+	return status, err
+}
+
+// GetStatuses учитывается одним вызовом: попытки — все запросы пачек или одиночных статусов
+func (g *InstrumentedGateway) GetStatuses(ctx context.Context, ids []string) (map[string]StatusResult, error) {
+	var results map[string]StatusResult
+	err := observe(ctx, opGetStatuses, func(ctx context.Context) (err error) {
+		results, err = g.next.GetStatuses(ctx, ids)
+		return err
+	})
+	return results, err
+}
+
+// observe выполняет call и записывает его длительность, исход и число запросов к order-сервису
+func observe(ctx context.Context, op string, call func(ctx context.Context) error) error {
+	ctx, attempts := withAttemptCounter(ctx)
+
+	inFlight := metrics.OrderGatewayInFlight.WithLabelValues(op)
+	inFlight.Inc()
+	defer inFlight.Dec()
+
+	start := time.Now()
+	err := call(ctx)
+
+	metrics.OrderGatewayRequestSeconds.WithLabelValues(op, outcome(err)).Observe(time.Since(start).Seconds())
+	metrics.OrderGatewayAttempts.WithLabelValues(op).Observe(float64(max(attempts.Load(), 1)))
+	return err
+}
+
+func outcome(err error) string {
+	switch {
+	case err == nil:
+		return outcomeOK
+	case errors.Is(err, ErrOrderNotFound):
+		return outcomeNotFound
+	case errors.Is(err, context.Canceled):
+		return outcomeCanceled
+	case errors.Is(err, ErrUpstreamUnavailable),
+		errors.Is(err, ErrCircuitOpen),
+		errors.Is(err, context.DeadlineExceeded):
+		return outcomeRetryable
+	default:
+		return outcomeFatal
+	}
+}
+
+type attemptsKey struct{}
+
+// withAttemptCounter кладёт в ctx счётчик запросов, который увеличивает RetryPolicy.run
+func withAttemptCounter(ctx context.Context) (context.Context, *atomic.Int64) {
+	c := new(atomic.Int64)
+	return context.WithValue(ctx, attemptsKey{}, c), c
+}
+
+func countAttempt(ctx context.Context) {
+	if c, ok := ctx.Value(attemptsKey{}).(*atomic.Int64); ok {
+		c.Add(1)
+	}
+}
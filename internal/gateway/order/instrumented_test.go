@@ -0,0 +1,97 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/Avito-courses/course-go-avito-israpilovsha/internal/metrics"
+)
+
+// histogramSamples возвращает число наблюдений и их сумму
+func histogramSamples(t *testing.T, o prometheus.Observer) (uint64, float64) {
+	t.Helper()
+
+	var m dto.Metric
+	if err := o.(prometheus.Metric).Write(&m); err != nil {
+		t.Fatalf("read histogram: %v", err)
+	}
+	return m.GetHistogram().GetSampleCount(), m.GetHistogram().GetSampleSum()
+}
+
+func TestInstrumentedGateway_Outcomes(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		wantOutcome string
+	}{
+		{name: "ok", wantOutcome: outcomeOK},
+		{name: "not found", err: &StatusError{Code: 404}, wantOutcome: outcomeNotFound},
+		{name: "retryable", err: &StatusError{Code: 503}, wantOutcome: outcomeRetryable},
+		{name: "circuit open", err: ErrCircuitOpen, wantOutcome: outcomeRetryable},
+		{name: "fatal", err: &StatusError{Code: 400}, wantOutcome: outcomeFatal},
+		{name: "canceled", err: context.Canceled, wantOutcome: outcomeCanceled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			latency := metrics.OrderGatewayRequestSeconds.WithLabelValues(opGetStatus, tt.wantOutcome)
+			before, _ := histogramSamples(t, latency)
+
+			g := NewInstrumentedGateway(&scriptedGateway{errs: []error{tt.err}})
+			if _, err := g.GetStatus(context.Background(), "o-1"); !errors.Is(err, tt.err) {
+				t.Fatalf("GetStatus() error = %v, want %v", err, tt.err)
+			}
+
+			if after, _ := histogramSamples(t, latency); after != before+1 {
+				t.Fatalf("latency samples for %s = %d, want %d", tt.wantOutcome, after, before+1)
+			}
+		})
+	}
+}
+
+func TestInstrumentedGateway_CountsAttempts(t *testing.T) {
+	var hits atomic.Int32
+	inFlight := metrics.OrderGatewayInFlight.WithLabelValues(opGetStatus)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := testutil.ToFloat64(inFlight); got != 1 {
+			t.Errorf("in-flight during call = %v, want 1", got)
+		}
+		if hits.Add(1) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`{"order_id":"o-1","status":"created"}`))
+	}))
+	defer srv.Close()
+
+	g := NewInstrumentedGateway(NewHTTPGateway(srv.URL, WithRetryPolicy(RetryPolicy{
+		MaxAttempts: 5,
+		BaseDelay:   time.Millisecond,
+		MaxDelay:    time.Millisecond,
+	})))
+
+	attempts := metrics.OrderGatewayAttempts.WithLabelValues(opGetStatus)
+	beforeCount, beforeSum := histogramSamples(t, attempts)
+
+	if _, err := g.GetStatus(context.Background(), "o-1"); err != nil {
+		t.Fatalf("GetStatus() error = %v", err)
+	}
+
+	count, sum := histogramSamples(t, attempts)
+	if count != beforeCount+1 || sum-beforeSum != 3 {
+		t.Fatalf("attempts: %d samples, sum +%v; want 1 sample of 3", count-beforeCount, sum-beforeSum)
+	}
+	if got := testutil.ToFloat64(inFlight); got != 0 {
+		t.Fatalf("in-flight after call = %v, want 0", got)
+	}
+}
@@ -0,0 +1,123 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+type Logger interface {
+	Warnw(msg string, keysAndValues ...any)
+}
+
+type noopLogger struct{}
+
+func (noopLogger) Warnw(string, ...any) {}
+
+// options — общие настройки HTTP- и gRPC-реализаций Gateway
+type options struct {
+	log     Logger
+	retry   RetryPolicy
+	timeout time.Duration
+	signer  Signer
+	now     func() time.Time
+
+	// pageSize — limit страницы FetchOrders; 0 — все заказы одной страницей
+	pageSize int
+	// batchSize и concurrency — размер пачки и параллелизм GetStatuses
+	batchSize   int
+	concurrency int
+}
+
+type Option func(*options)
+
+// WithRetryPolicy задаёт повторы неудачных вызовов; по умолчанию DefaultRetryPolicy
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(o *options) {
+		if p.MaxAttempts > 0 {
+			o.retry = p
+		}
+	}
+}
+
+// WithTimeout задаёт таймаут одной попытки; по умолчанию 5s
+func WithTimeout(d time.Duration) Option {
+	return func(o *options) {
+		if d > 0 {
+			o.timeout = d
+		}
+	}
+}
+
+// WithSigner задаёт аутентификацию вызовов; без него запросы уходят без авторизации
+func WithSigner(s Signer) Option {
+	return func(o *options) {
+		o.signer = s
+	}
+}
+
+// WithPageSize задаёт размер страницы FetchOrders; по умолчанию 500, 0 — забирать всё одним ответом
+func WithPageSize(n int) Option {
+	return func(o *options) {
+		if n >= 0 {
+			o.pageSize = n
+		}
+	}
+}
+
+// WithBatch задаёт размер пачки пакетного GetStatuses и число одновременных запросов;
+// по умолчанию 100 и 8
+func WithBatch(size, concurrency int) Option {
+	return func(o *options) {
+		if size > 0 {
+			o.batchSize = size
+		}
+		if concurrency > 0 {
+			o.concurrency = concurrency
+		}
+	}
+}
+
+func newOptions(opts []Option) options {
+	o := options{
+		log:         noopLogger{},
+		retry:       DefaultRetryPolicy(),
+		timeout:     5 * time.Second,
+		now:         time.Now,
+		pageSize:    500,
+		batchSize:   100,
+		concurrency: 8,
+	}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
+
+// WithLogger подключает логгер к HTTP- или gRPC-реализации; декораторы возвращаются как есть
+func WithLogger(g Gateway, log Logger) Gateway {
+	switch gw := g.(type) {
+	case *httpGateway:
+		gw.log = log
+	case *grpcGateway:
+		gw.log = log
+	}
+	return g
+}
+
+// authHeaders возвращает заголовки аутентификации для вызова; без Signer — ничего
+func (o *options) authHeaders(ctx context.Context, method, target string, body []byte) (map[string]string, error) {
+	if o.signer == nil {
+		return nil, nil
+	}
+	return o.signer.Headers(ctx, method, target, body)
+}
+
+// checkAuth сбрасывает кэшированный токен, если order-сервис его отклонил
+func (o *options) checkAuth(err error) {
+	if inv, ok := o.signer.(tokenInvalidator); ok && errors.Is(err, ErrUnauthorized) {
+		inv.Invalidate()
+	}
+}
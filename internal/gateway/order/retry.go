@@ -0,0 +1,131 @@
+package order
+
+import (
+	"context"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Avito-courses/course-go-avito-israpilovsha/internal/metrics"
+)
+
+// RetryPolicy — повторы вызовов order-сервиса: экспоненциальная пауза с full jitter,
+// ограниченная MaxDelay, общий бюджет времени и учёт Retry-After
+type RetryPolicy struct {
+	// MaxAttempts — всего попыток, включая первую
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	// Jitter — пауза выбирается случайно из [0, delay), чтобы клиенты не повторяли синхронно
+	Jitter bool
+	// Budget — предел времени на все попытки и паузы; 0 — без предела
+	Budget time.Duration
+}
+
+// DefaultRetryPolicy — политика по умолчанию для NewHTTPGateway
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 4,
+		BaseDelay:   100 * time.Millisecond,
+		MaxDelay:    2 * time.Second,
+		Jitter:      true,
+		Budget:      10 * time.Second,
+	}
+}
+
+// Delay возвращает паузу перед попыткой attempt+1 (attempt считается с 1).
+// Retry-After от сервиса важнее расчётной паузы и не обрезается MaxDelay.
+func (p RetryPolicy) Delay(attempt int, retryAfter time.Duration) time.Duration {
+	if retryAfter > 0 {
+		return retryAfter
+	}
+
+	d := p.BaseDelay
+	for i := 1; i < attempt && (p.MaxDelay <= 0 || d < p.MaxDelay); i++ {
+		d *= 2
+	}
+	if p.MaxDelay > 0 {
+		d = min(d, p.MaxDelay)
+	}
+
+	if p.Jitter && d > 0 {
+		d = rand.N(d)
+	}
+	return d
+}
+
+// run выполняет call по политике. classify решает, повторять ли ошибку, и возвращает
+// паузу, запрошенную сервисом; если следующая пауза не укладывается в бюджет,
+// возвращается последняя ошибка.
+func (p RetryPolicy) run(
+	ctx context.Context,
+	log Logger,
+	now func() time.Time,
+	call func() error,
+	classify func(error) (bool, time.Duration),
+) error {
+	var deadline time.Time
+	if p.Budget > 0 {
+		deadline = now().Add(p.Budget)
+	}
+
+	for attempt := 1; ; attempt++ {
+		countAttempt(ctx)
+		err := call()
+		if err == nil {
+			return nil
+		}
+
+		retryable, retryAfter := classify(err)
+		if !retryable || attempt >= p.MaxAttempts {
+			return err
+		}
+
+		delay := p.Delay(attempt, retryAfter)
+		if !deadline.IsZero() && now().Add(delay).After(deadline) {
+			log.Warnw("gateway retry budget exhausted",
+				"attempt", attempt,
+				"err", err,
+				"delay", delay.String(),
+			)
+			return err
+		}
+
+		metrics.GatewayRetriesTotal.Inc()
+		log.Warnw("gateway retry",
+			"attempt", attempt,
+			"max", p.MaxAttempts,
+			"err", err,
+			"delay", delay.String(),
+		)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// parseRetryAfter разбирает Retry-After в секундах или HTTP-дате; 0 — заголовка нет или он некорректен
+func parseRetryAfter(h http.Header, now time.Time) time.Duration {
+	raw := h.Get("Retry-After")
+	if raw == "" {
+		return 0
+	}
+
+	if secs, err := strconv.Atoi(raw); err == nil {
+		if secs <= 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+
+	if at, err := http.ParseTime(raw); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+	return 0
+}
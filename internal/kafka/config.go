@@ -0,0 +1,126 @@
+package kafka
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+
+	"github.com/IBM/sarama"
+
+	"github.com/Avito-courses/course-go-avito-israpilovsha/internal/server/config"
+)
+
+// NewConfig собирает настройки sarama из конфига сервиса: версию, client id, SASL, TLS
+// и параметры группы. Настройки producer-а вызывающий дополняет сам.
+func NewConfig(cfg config.KafkaConfig) (*sarama.Config, error) {
+	sc := sarama.NewConfig()
+	sc.ClientID = cfg.ClientID
+
+	version, err := sarama.ParseKafkaVersion(cfg.Version)
+	if err != nil {
+		return nil, err
+	}
+	sc.Version = version
+
+	switch cfg.InitialOffset {
+	case config.OffsetOldest:
+		sc.Consumer.Offsets.Initial = sarama.OffsetOldest
+	default:
+		sc.Consumer.Offsets.Initial = sarama.OffsetNewest
+	}
+
+	switch cfg.BalanceStrategy {
+	case config.BalanceRoundRobin:
+		sc.Consumer.Group.Rebalance.GroupStrategies = []sarama.BalanceStrategy{sarama.NewBalanceStrategyRoundRobin()}
+	case config.BalanceSticky:
+		sc.Consumer.Group.Rebalance.GroupStrategies = []sarama.BalanceStrategy{sarama.NewBalanceStrategySticky()}
+	default:
+		sc.Consumer.Group.Rebalance.GroupStrategies = []sarama.BalanceStrategy{sarama.NewBalanceStrategyRange()}
+	}
+
+	if cfg.SessionTimeout > 0 {
+		sc.Consumer.Group.Session.Timeout = cfg.SessionTimeout
+	}
+	if cfg.HeartbeatInterval > 0 {
+		sc.Consumer.Group.Heartbeat.Interval = cfg.HeartbeatInterval
+	}
+
+	if err := applySASL(sc, cfg.SASL); err != nil {
+		return nil, err
+	}
+	if err := applyTLS(sc, cfg.TLS); err != nil {
+		return nil, err
+	}
+
+	if err := sc.Validate(); err != nil {
+		return nil, fmt.Errorf("kafka config: %w", err)
+	}
+	return sc, nil
+}
+
+func applySASL(sc *sarama.Config, cfg config.KafkaSASLConfig) error {
+	if cfg.Mechanism == "" {
+		return nil
+	}
+
+	sc.Net.SASL.Enable = true
+	sc.Net.SASL.Handshake = true
+	sc.Net.SASL.User = cfg.User
+	sc.Net.SASL.Password = cfg.Password
+
+	switch cfg.Mechanism {
+	case config.SASLPlain:
+		sc.Net.SASL.Mechanism = sarama.SASLTypePlaintext
+	case config.SASLScramSHA256:
+		sc.Net.SASL.Mechanism = sarama.SASLTypeSCRAMSHA256
+		sc.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient {
+			return &XDGSCRAMClient{HashGeneratorFcn: scramSHA256}
+		}
+	case config.SASLScramSHA512:
+		sc.Net.SASL.Mechanism = sarama.SASLTypeSCRAMSHA512
+		sc.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient {
+			return &XDGSCRAMClient{HashGeneratorFcn: scramSHA512}
+		}
+	default:
+		return fmt.Errorf("unsupported SASL mechanism %q", cfg.Mechanism)
+	}
+
+	return nil
+}
+
+func applyTLS(sc *sarama.Config, cfg config.KafkaTLSConfig) error {
+	if !cfg.Enabled {
+		return nil
+	}
+
+	tlsCfg := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+	}
+
+	if cfg.CAFile != "" {
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return fmt.Errorf("read kafka CA: %w", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("kafka CA %s: no certificates found", cfg.CAFile)
+		}
+		tlsCfg.RootCAs = pool
+	}
+
+	if cfg.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return fmt.Errorf("load kafka client certificate: %w", err)
+		}
+		tlsCfg.Certificates = []tls.Certificate{cert}
+	}
+
+	sc.Net.TLS.Enable = true
+	sc.Net.TLS.Config = tlsCfg
+	return nil
+}
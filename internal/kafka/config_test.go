@@ -0,0 +1,55 @@
+package kafka
+
+import (
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+
+	"github.com/Avito-courses/course-go-avito-israpilovsha/internal/server/config"
+)
+
+func TestNewConfig(t *testing.T) {
+	sc, err := NewConfig(config.KafkaConfig{
+		ClientID:          "courier-test",
+		Version:           "3.6.0",
+		InitialOffset:     config.OffsetOldest,
+		BalanceStrategy:   config.BalanceSticky,
+		SessionTimeout:    30 * time.Second,
+		HeartbeatInterval: 5 * time.Second,
+		SASL: config.KafkaSASLConfig{
+			Mechanism: config.SASLScramSHA512,
+			User:      "courier",
+			Password:  "secret",
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewConfig() error = %v", err)
+	}
+
+	if sc.ClientID != "courier-test" || !sc.Version.IsAtLeast(sarama.V3_6_0_0) {
+		t.Fatalf("unexpected client %q / version %s", sc.ClientID, sc.Version)
+	}
+	if sc.Consumer.Offsets.Initial != sarama.OffsetOldest {
+		t.Fatalf("expected oldest initial offset")
+	}
+	if got := sc.Consumer.Group.Rebalance.GroupStrategies[0].Name(); got != sarama.StickyBalanceStrategyName {
+		t.Fatalf("balance strategy = %s, want sticky", got)
+	}
+	if sc.Consumer.Group.Session.Timeout != 30*time.Second || sc.Consumer.Group.Heartbeat.Interval != 5*time.Second {
+		t.Fatalf("unexpected group timeouts")
+	}
+	if !sc.Net.SASL.Enable || sc.Net.SASL.Mechanism != sarama.SASLTypeSCRAMSHA512 || sc.Net.SASL.SCRAMClientGeneratorFunc == nil {
+		t.Fatalf("SCRAM-SHA-512 is not configured: %+v", sc.Net.SASL)
+	}
+}
+
+func TestNewConfigMissingCA(t *testing.T) {
+	_, err := NewConfig(config.KafkaConfig{
+		Version: "2.6.0",
+		TLS:     config.KafkaTLSConfig{Enabled: true, CAFile: "/nonexistent/ca.pem"},
+	})
+	if err == nil {
+		t.Fatal("expected error for missing CA file")
+	}
+}
@@ -0,0 +1,67 @@
+package kafka
+
+import (
+	"strings"
+	"testing"
+)
+
+// Пример обмена из RFC 7677, раздел 3
+func TestXDGSCRAMClientSHA256(t *testing.T) {
+	c := &XDGSCRAMClient{HashGeneratorFcn: scramSHA256, nonce: func() string { return "rOprNGfwEbeRWgbNEkqO" }}
+
+	if err := c.Begin("user", "pencil", ""); err != nil {
+		t.Fatalf("Begin() error = %v", err)
+	}
+
+	first, err := c.Step("")
+	if err != nil || first != "n,,n=user,r=rOprNGfwEbeRWgbNEkqO" {
+		t.Fatalf("client-first = %q, %v", first, err)
+	}
+
+	final, err := c.Step("r=rOprNGfwEbeRWgbNEkqO%hvYDpWUa2RaTCAfuxFIlj)hNlF$k0,s=W22ZaJ0SNY7soEsUEjb6gQ==,i=4096")
+	want := "c=biws,r=rOprNGfwEbeRWgbNEkqO%hvYDpWUa2RaTCAfuxFIlj)hNlF$k0,p=dHzbZapWIk4jUhN+Ute9ytag9zjfMHgsqmmiz7AndVQ="
+	if err != nil || final != want {
+		t.Fatalf("client-final = %q, %v", final, err)
+	}
+
+	if _, err := c.Step("v=6rriTRBi23WpRR/wtup+mMhUZUn/dB5nLTJRsjl95G4="); err != nil {
+		t.Fatalf("server-final: %v", err)
+	}
+	if !c.Done() {
+		t.Fatal("expected conversation to be done")
+	}
+}
+
+func TestXDGSCRAMClientRejectsBadServerSignature(t *testing.T) {
+	c := &XDGSCRAMClient{HashGeneratorFcn: scramSHA256, nonce: func() string { return "rOprNGfwEbeRWgbNEkqO" }}
+	_ = c.Begin("user", "pencil", "")
+	_, _ = c.Step("")
+	_, _ = c.Step("r=rOprNGfwEbeRWgbNEkqO%hvYDpWUa2RaTCAfuxFIlj)hNlF$k0,s=W22ZaJ0SNY7soEsUEjb6gQ==,i=4096")
+
+	if _, err := c.Step("v=AAAA"); err == nil {
+		t.Fatal("expected signature mismatch")
+	}
+	if c.Valid() {
+		t.Fatal("conversation must not be valid after a failed verification")
+	}
+}
+
+func TestXDGSCRAMClientFreshNonceOnBegin(t *testing.T) {
+	c := &XDGSCRAMClient{HashGeneratorFcn: scramSHA512}
+
+	nonce := func() string {
+		if err := c.Begin("user", "pencil", ""); err != nil {
+			t.Fatalf("Begin() error = %v", err)
+		}
+		first, err := c.Step("")
+		if err != nil {
+			t.Fatalf("client-first: %v", err)
+		}
+		_, r, _ := strings.Cut(first, ",r=")
+		return r
+	}
+
+	if a, b := nonce(), nonce(); a == "" || a == b {
+		t.Fatalf("nonces %q and %q must be fresh per conversation", a, b)
+	}
+}
@@ -14,4 +14,46 @@ var (
 		Name:      "gateway_retries_total",
 		Help:      "Total number of retries when calling external service-order",
 	})
+
+	// OrderGatewayCircuitState — состояние circuit breaker order-сервиса: 0 closed, 1 half-open, 2 open
+	OrderGatewayCircuitState = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "courier",
+		Name:      "order_gateway_circuit_state",
+		Help:      "Order service circuit breaker state: 0 closed, 1 half-open, 2 open",
+	})
+
+	OrderGatewayCircuitRejectedTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "courier",
+		Name:      "order_gateway_circuit_rejected_total",
+		Help:      "Order service calls rejected by the open circuit breaker",
+	})
+
+	// OrderGatewayCacheTotal — обращения к кэшу order-сервиса: hit, miss или coalesced (ждал чужой запрос)
+	OrderGatewayCacheTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "courier",
+		Name:      "order_gateway_cache_requests_total",
+		Help:      "Order service cache lookups by method and result",
+	}, []string{"method", "result"})
+
+	// OrderGatewayRequestSeconds — длительность вызова order-сервиса вместе с повторами.
+	// outcome: ok, not_found, retryable, fatal, canceled
+	OrderGatewayRequestSeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "courier",
+		Name:      "order_gateway_request_duration_seconds",
+		Help:      "Order service call latency including retries by operation and outcome",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation", "outcome"})
+
+	OrderGatewayAttempts = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "courier",
+		Name:      "order_gateway_attempts",
+		Help:      "Requests made to order service per gateway call",
+		Buckets:   []float64{1, 2, 3, 4, 6, 8, 16, 32, 64},
+	}, []string{"operation"})
+
+	OrderGatewayInFlight = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "courier",
+		Name:      "order_gateway_in_flight_requests",
+		Help:      "Order service calls in progress by operation",
+	}, []string{"operation"})
 )
@@ -0,0 +1,91 @@
+package metrics
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	PendingOrdersDepth = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "courier",
+		Name:      "pending_orders",
+		Help:      "Number of orders waiting for an available courier",
+	})
+
+	PendingOrderWaitSeconds = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "courier",
+		Name:      "pending_order_wait_seconds",
+		Help:      "Time an order spent in the pending queue before a courier was assigned",
+		Buckets:   prometheus.ExponentialBuckets(1, 2, 13),
+	})
+
+	DeliveriesExpiredTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "courier",
+		Name:      "deliveries_expired_total",
+		Help:      "Deliveries marked as expired after missing their deadline",
+	})
+
+	// DeliveriesReassignedTotal — судьба заказов после просрочки: reassigned или queued
+	DeliveriesReassignedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "courier",
+		Name:      "deliveries_reassigned_total",
+		Help:      "Orders handed over after an expired delivery, by outcome",
+	}, []string{"outcome"})
+
+	AutoReleaseErrorsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "courier",
+		Name:      "delivery_auto_release_errors_total",
+		Help:      "Failed runs of the expired delivery release job",
+	})
+
+	OutboxPublishedTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "courier",
+		Name:      "outbox_published_total",
+		Help:      "Outbox events published to Kafka",
+	})
+
+	OutboxPublishErrorsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "courier",
+		Name:      "outbox_publish_errors_total",
+		Help:      "Failed outbox relay runs",
+	})
+
+	OrderEventsDuplicateTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "courier",
+		Name:      "order_events_duplicate_total",
+		Help:      "Redelivered order events skipped by the consumer",
+	})
+
+	OrderEventsStaleTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "courier",
+		Name:      "order_events_stale_total",
+		Help:      "Order events skipped because a newer order version was already applied",
+	})
+
+	OrderStatusVerifyFailuresTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "courier",
+		Name:      "order_status_verify_failures_total",
+		Help:      "Order status verifications that failed and fell back to the event status",
+	})
+
+	OrdersFetchedTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "courier",
+		Name:      "order_fetcher_orders_total",
+		Help:      "New orders fetched by polling the order service",
+	})
+
+	OrderFetchAssignFailuresTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "courier",
+		Name:      "order_fetcher_assign_failures_total",
+		Help:      "Fetched orders that failed to be assigned",
+	})
+
+	OrderFetchSkippedTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "courier",
+		Name:      "order_fetcher_skipped_total",
+		Help:      "Fetched orders skipped after an assignment error that retries cannot fix",
+	})
+
+	OrderFetchErrorsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "courier",
+		Name:      "order_fetcher_errors_total",
+		Help:      "Failed order service polls",
+	})
+)
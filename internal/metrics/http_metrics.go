@@ -29,4 +29,36 @@ func Register() {
 
 	prometheus.MustRegister(RateLimitExceededTotal)
 	prometheus.MustRegister(GatewayRetriesTotal)
+	prometheus.MustRegister(OrderGatewayCircuitState)
+	prometheus.MustRegister(OrderGatewayCircuitRejectedTotal)
+	prometheus.MustRegister(OrderGatewayCacheTotal)
+	prometheus.MustRegister(OrderGatewayRequestSeconds)
+	prometheus.MustRegister(OrderGatewayAttempts)
+	prometheus.MustRegister(OrderGatewayInFlight)
+
+	prometheus.MustRegister(PendingOrdersDepth)
+	prometheus.MustRegister(PendingOrderWaitSeconds)
+
+	prometheus.MustRegister(DeliveriesExpiredTotal)
+	prometheus.MustRegister(DeliveriesReassignedTotal)
+	prometheus.MustRegister(AutoReleaseErrorsTotal)
+
+	prometheus.MustRegister(OutboxPublishedTotal)
+	prometheus.MustRegister(OutboxPublishErrorsTotal)
+
+	prometheus.MustRegister(OrderEventsDuplicateTotal)
+	prometheus.MustRegister(OrderEventsStaleTotal)
+	prometheus.MustRegister(OrderStatusVerifyFailuresTotal)
+
+	prometheus.MustRegister(OrdersFetchedTotal)
+	prometheus.MustRegister(OrderFetchAssignFailuresTotal)
+	prometheus.MustRegister(OrderFetchSkippedTotal)
+	prometheus.MustRegister(OrderFetchErrorsTotal)
+
+	prometheus.MustRegister(KafkaMessagesConsumedTotal)
+	prometheus.MustRegister(KafkaMessagesFailedTotal)
+	prometheus.MustRegister(KafkaMessagesSkippedTotal)
+	prometheus.MustRegister(KafkaProcessingSeconds)
+	prometheus.MustRegister(KafkaConsumerLag)
+	prometheus.MustRegister(KafkaRebalancesTotal)
 }
@@ -0,0 +1,45 @@
+package metrics
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	KafkaMessagesConsumedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "courier",
+		Name:      "kafka_messages_consumed_total",
+		Help:      "Order messages read from Kafka",
+	}, []string{"topic", "partition"})
+
+	// KafkaMessagesFailedTotal — сообщения, не обработанные после всех попыток (dead-letter или drop)
+	KafkaMessagesFailedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "courier",
+		Name:      "kafka_messages_failed_total",
+		Help:      "Order messages that failed processing after all retries",
+	}, []string{"topic", "partition"})
+
+	// KafkaMessagesSkippedTotal — дубли, устаревшие события, неизвестные заказы и статусы без действия
+	KafkaMessagesSkippedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "courier",
+		Name:      "kafka_messages_skipped_total",
+		Help:      "Order messages processed without changing any delivery",
+	}, []string{"topic", "partition"})
+
+	KafkaProcessingSeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "courier",
+		Name:      "kafka_message_processing_seconds",
+		Help:      "Order message processing time including retries, by resulting action",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"action"})
+
+	// KafkaConsumerLag — high-water mark партиции минус следующий коммитируемый offset
+	KafkaConsumerLag = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "courier",
+		Name:      "kafka_consumer_lag",
+		Help:      "Messages in a partition not yet committed by the consumer group",
+	}, []string{"topic", "partition"})
+
+	KafkaRebalancesTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "courier",
+		Name:      "kafka_rebalances_total",
+		Help:      "Consumer group sessions started after a rebalance",
+	})
+)
@@ -0,0 +1,73 @@
+package model
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+)
+
+// Типы событий, которые сервис публикует для других команд
+const (
+	EventDeliveryAssigned     = "delivery.assigned"
+	EventDeliveryPickedUp     = "delivery.picked_up"
+	EventDeliveryCompleted    = "delivery.completed"
+	EventDeliveryCancelled    = "delivery.cancelled"
+	EventDeliveryExpired      = "delivery.expired"
+	EventCourierStatusChanged = "courier.status_changed"
+)
+
+// Event — запись outbox. Key определяет партицию Kafka:
+// события с одним ключом публикуются в порядке записи.
+type Event struct {
+	ID          int64           `json:"id"`
+	Type        string          `json:"type"`
+	Key         string          `json:"key"`
+	Payload     json.RawMessage `json:"payload"`
+	CreatedAt   time.Time       `json:"created_at"`
+	PublishedAt *time.Time      `json:"published_at,omitempty"`
+}
+
+// Envelope — то, что уходит в Kafka: идентификатор для дедупликации на стороне потребителя,
+// тип события и его данные
+type Envelope struct {
+	ID         int64           `json:"id"`
+	Type       string          `json:"type"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Data       json.RawMessage `json:"data"`
+}
+
+type DeliveryPayload struct {
+	DeliveryID int64      `json:"delivery_id"`
+	OrderID    string     `json:"order_id"`
+	CourierID  int64      `json:"courier_id"`
+	Status     string     `json:"status"`
+	Reason     string     `json:"reason,omitempty"`
+	Deadline   *time.Time `json:"deadline,omitempty"`
+}
+
+type CourierStatusPayload struct {
+	CourierID int64  `json:"courier_id"`
+	From      string `json:"from,omitempty"`
+	To        string `json:"to"`
+	OrderID   string `json:"order_id,omitempty"`
+}
+
+// NewEvent сериализует payload в событие outbox
+func NewEvent(eventType, key string, payload any, at time.Time) (*Event, error) {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Event{
+		Type:      eventType,
+		Key:       key,
+		Payload:   raw,
+		CreatedAt: at,
+	}, nil
+}
+
+// CourierKey — ключ событий курьера: события одного курьера идут по порядку
+func CourierKey(courierID int64) string {
+	return "courier-" + strconv.FormatInt(courierID, 10)
+}
@@ -0,0 +1,27 @@
+package repository
+
+//go:generate mockgen -source=outbox_repository.go -destination=mock_outbox_repository.go -package=repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/Avito-courses/course-go-avito-israpilovsha/internal/outbox/model"
+)
+
+// OutboxWriter — часть OutboxRepository, через которую сервисы пишут события
+type OutboxWriter interface {
+	// Add пишет событие; вызывается в транзакции изменения, которое оно описывает
+	Add(ctx context.Context, e *model.Event) error
+}
+
+type OutboxRepository interface {
+	OutboxWriter
+
+	WithTx(ctx context.Context, fn func(txCtx context.Context) error) error
+	// TryLock берёт advisory-блокировку релея до конца транзакции
+	TryLock(ctx context.Context) (bool, error)
+	FetchUnpublished(ctx context.Context, limit int) ([]*model.Event, error)
+	MarkPublished(ctx context.Context, ids []int64, at time.Time) error
+	DeletePublishedBefore(ctx context.Context, before time.Time) (int64, error)
+}
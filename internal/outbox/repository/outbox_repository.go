@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/Avito-courses/course-go-avito-israpilovsha/internal/db"
+	"github.com/Avito-courses/course-go-avito-israpilovsha/internal/outbox/model"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// relayLockKey — ключ advisory-блокировки: публикует только один инстанс,
+// иначе события одного заказа могли бы уйти не по порядку
+const relayLockKey int64 = 0x6f7574626f78
+
+type OutboxPostgresRepository struct {
+	DB *db.Database
+}
+
+func NewOutboxRepository(database *db.Database) *OutboxPostgresRepository {
+	return &OutboxPostgresRepository{DB: database}
+}
+
+func (r *OutboxPostgresRepository) queryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	if tx, ok := db.TxFromContext(ctx); ok {
+		return tx.QueryRow(ctx, sql, args...)
+	}
+	return r.DB.Pool.QueryRow(ctx, sql, args...)
+}
+
+func (r *OutboxPostgresRepository) query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	if tx, ok := db.TxFromContext(ctx); ok {
+		return tx.Query(ctx, sql, args...)
+	}
+	return r.DB.Pool.Query(ctx, sql, args...)
+}
+
+func (r *OutboxPostgresRepository) exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	if tx, ok := db.TxFromContext(ctx); ok {
+		return tx.Exec(ctx, sql, args...)
+	}
+	return r.DB.Pool.Exec(ctx, sql, args...)
+}
+
+func (r *OutboxPostgresRepository) WithTx(ctx context.Context, fn func(txCtx context.Context) error) error {
+	return r.DB.WithTx(ctx, fn)
+}
+
+func (r *OutboxPostgresRepository) Add(ctx context.Context, e *model.Event) error {
+	const query = `
+        INSERT INTO outbox (event_type, event_key, payload, created_at)
+        VALUES ($1, $2, $3, $4)
+        RETURNING id;
+    `
+
+	return r.queryRow(ctx, query, e.Type, e.Key, []byte(e.Payload), e.CreatedAt).Scan(&e.ID)
+}
+
+func (r *OutboxPostgresRepository) TryLock(ctx context.Context) (bool, error) {
+	var locked bool
+	err := r.queryRow(ctx, `SELECT pg_try_advisory_xact_lock($1);`, relayLockKey).Scan(&locked)
+	return locked, err
+}
+
+// FetchUnpublished возвращает неопубликованные события в порядке записи
+func (r *OutboxPostgresRepository) FetchUnpublished(ctx context.Context, limit int) ([]*model.Event, error) {
+	const query = `
+        SELECT id, event_type, event_key, payload, created_at
+        FROM outbox
+        WHERE published_at IS NULL
+        ORDER BY id ASC
+        LIMIT $1;
+    `
+
+	rows, err := r.query(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := make([]*model.Event, 0)
+	for rows.Next() {
+		e := &model.Event{}
+		if err := rows.Scan(&e.ID, &e.Type, &e.Key, &e.Payload, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		list = append(list, e)
+	}
+
+	return list, rows.Err()
+}
+
+func (r *OutboxPostgresRepository) MarkPublished(ctx context.Context, ids []int64, at time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	_, err := r.exec(ctx, `UPDATE outbox SET published_at = $2 WHERE id = ANY($1);`, ids, at)
+	return err
+}
+
+func (r *OutboxPostgresRepository) DeletePublishedBefore(ctx context.Context, before time.Time) (int64, error) {
+	cmd, err := r.exec(ctx, `DELETE FROM outbox WHERE published_at < $1;`, before)
+	if err != nil {
+		return 0, err
+	}
+	return cmd.RowsAffected(), nil
+}
@@ -4,6 +4,7 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -11,11 +12,20 @@ import (
 )
 
 type Config struct {
+	// Env — окружение (APP_ENV); dev разрешает небезопасные настройки для локальной разработки
+	Env              string
 	Port             string
 	OrderServiceHost string
+	OrderClient      OrderClientConfig
 	Postgres         PostgresConfig
 	Delivery         DeliveryConfig
 	Kafka            KafkaConfig
+	Outbox           OutboxConfig
+	OrderFetcher     OrderFetcherConfig
+	OrderBreaker     CircuitBreakerConfig
+	OrderCache       OrderCacheConfig
+	// AdminToken — bearer-токен ручек /admin; пустой закрывает их для всех
+	AdminToken string
 }
 
 type PostgresConfig struct {
@@ -28,13 +38,117 @@ type PostgresConfig struct {
 
 type DeliveryConfig struct {
 	TickerInterval time.Duration
+	// QueueDrainInterval — как часто перепроверять очередь заказов без курьера
+	QueueDrainInterval time.Duration
+	// AssignmentStrategy — имя стратегии выбора курьера (least_loaded, round_robin, ...)
+	AssignmentStrategy string
+	// LoadWindow — окно подсчёта загрузки курьера; 0 — за всё время
+	LoadWindow time.Duration
+	// DeadlinePolicyReloadInterval — как часто перечитывать политику дедлайнов из БД
+	DeadlinePolicyReloadInterval time.Duration
+	// ReassignExpired — переназначать заказ другому курьеру после просрочки доставки
+	ReassignExpired bool
 }
 
-type KafkaConfig struct {
+// OutboxConfig — публикация событий сервиса в Kafka; пустой Topic выключает outbox
+type OutboxConfig struct {
+	Topic        string
+	BatchSize    int
+	PollInterval time.Duration
+	Retention    time.Duration
+}
+
+// Режимы опроса order-сервиса
+const (
+	FetcherOff      = "off"
+	FetcherAlways   = "always"
+	FetcherFallback = "fallback"
+)
+
+// OrderFetcherConfig — опрос order-сервиса как запасной путь к Kafka
+type OrderFetcherConfig struct {
+	// Mode: off, always или fallback — только пока Kafka выключена или без сессии дольше FallbackAfter
+	Mode          string
+	Period        time.Duration
+	FallbackAfter time.Duration
+}
+
+// Протоколы обращения к order-сервису
+const (
+	OrderTransportHTTP = "http"
+	OrderTransportGRPC = "grpc"
+)
+
+// OrderClientConfig — протокол, таймаут и повторы вызовов order-сервиса
+type OrderClientConfig struct {
+	// Transport: http (ORDER_SERVICE_HOST) или grpc (ORDER_SERVICE_GRPC_ADDR)
+	Transport string
+	GRPCAddr  string
+	// GRPCTLS включает TLS для gRPC; GRPCCAFile — CA order-сервиса, пусто — системные корневые сертификаты
+	GRPCTLS    bool
+	GRPCCAFile string
+	Timeout    time.Duration
+	// MaxAttempts — всего попыток; пауза растёт от BaseDelay до MaxDelay, Retry-After важнее
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	Jitter      bool
+	// RetryBudget — предел времени на все попытки одного вызова; 0 — без предела
+	RetryBudget time.Duration
+	// PageSize — размер страницы FetchOrders; 0 — все заказы одним ответом
+	PageSize int
+	// BatchSize и Concurrency — размер пачки пакетного запроса статусов и число одновременных запросов
+	BatchSize   int
+	Concurrency int
+	Auth        OrderAuthConfig
+}
+
+const EnvDev = "dev"
+
+// Способы аутентификации в order-сервисе
+const (
+	OrderAuthNone   = "none"
+	OrderAuthBearer = "bearer"
+	OrderAuthOAuth2 = "oauth2"
+	OrderAuthHMAC   = "hmac"
+	// OrderAuthBypass — заголовок X-Bypass-Auth песочницы, только при APP_ENV=dev
+	OrderAuthBypass = "bypass"
+)
+
+// OrderAuthConfig — аутентификация вызовов order-сервиса
+type OrderAuthConfig struct {
+	Mode  string
+	Token string
+	// OAuth2 client credentials
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	// HMAC-подпись запросов
+	HMACKeyID  string
+	HMACSecret string
+}
+
+// CircuitBreakerConfig — circuit breaker вокруг order-сервиса
+type CircuitBreakerConfig struct {
 	Enabled bool
-	Brokers []string
-	Topic   string
-	GroupID string
+	// FailureRatio — доля ошибок за Window (не меньше MinRequests вызовов), открывающая breaker
+	FailureRatio float64
+	MinRequests  int
+	Window       time.Duration
+	// CoolDown — сколько breaker открыт до пробных вызовов; HalfOpenRequests успешных проб закрывают его
+	CoolDown         time.Duration
+	HalfOpenRequests int
+}
+
+// OrderCacheConfig — кэш ответов order-сервиса
+type OrderCacheConfig struct {
+	Enabled     bool
+	StatusTTL   time.Duration
+	NotFoundTTL time.Duration
+	// OrdersTTL — переиспользование ответа FetchOrders; 0 — не кэшировать
+	OrdersTTL  time.Duration
+	MaxEntries int
 }
 
 func MustLoad() *Config {
@@ -53,6 +167,37 @@ func MustLoad() *Config {
 		panic("invalid DELIVERY_TICKER_INTERVAL: " + err.Error())
 	}
 
+	drainRaw := os.Getenv("DELIVERY_QUEUE_DRAIN_INTERVAL")
+	if drainRaw == "" {
+		drainRaw = "30s"
+	}
+
+	drainInterval, err := time.ParseDuration(drainRaw)
+	if err != nil {
+		panic("invalid DELIVERY_QUEUE_DRAIN_INTERVAL: " + err.Error())
+	}
+
+	loadWindow := time.Duration(0)
+	if raw := os.Getenv("DELIVERY_LOAD_WINDOW"); raw != "" {
+		loadWindow, err = time.ParseDuration(raw)
+		if err != nil {
+			panic("invalid DELIVERY_LOAD_WINDOW: " + err.Error())
+		}
+	}
+
+	policyReloadRaw := os.Getenv("DELIVERY_DEADLINE_POLICY_RELOAD_INTERVAL")
+	if policyReloadRaw == "" {
+		policyReloadRaw = "1m"
+	}
+
+	policyReloadInterval, err := time.ParseDuration(policyReloadRaw)
+	if err != nil {
+		panic("invalid DELIVERY_DEADLINE_POLICY_RELOAD_INTERVAL: " + err.Error())
+	}
+	if policyReloadInterval <= 0 {
+		panic("invalid DELIVERY_DEADLINE_POLICY_RELOAD_INTERVAL: must be positive")
+	}
+
 	pg := PostgresConfig{
 		Host:     os.Getenv("POSTGRES_HOST"),
 		Port:     os.Getenv("POSTGRES_PORT"),
@@ -60,19 +205,62 @@ func MustLoad() *Config {
 		Password: os.Getenv("POSTGRES_PASSWORD"),
 		DBName:   os.Getenv("POSTGRES_DB"),
 	}
-	kafkaEnabled := os.Getenv("KAFKA_ENABLED") == "true"
+	kafka := LoadKafka()
 
-	brokersRaw := os.Getenv("KAFKA_BROKERS")
-	var brokers []string
-	if brokersRaw != "" {
-		brokers = strings.Split(brokersRaw, ",")
+	outbox := OutboxConfig{
+		Topic:        os.Getenv("OUTBOX_TOPIC"),
+		BatchSize:    mustPositiveInt("OUTBOX_BATCH_SIZE", 100),
+		PollInterval: mustDuration("OUTBOX_POLL_INTERVAL", "1s"),
+		Retention:    mustDuration("OUTBOX_RETENTION", "72h"),
 	}
 
-	kafka := KafkaConfig{
-		Enabled: kafkaEnabled,
-		Brokers: brokers,
-		Topic:   os.Getenv("KAFKA_TOPIC"),
-		GroupID: os.Getenv("KAFKA_GROUP_ID"),
+	fetcher := OrderFetcherConfig{
+		Mode:          strings.ToLower(os.Getenv("ORDER_FETCHER_MODE")),
+		Period:        mustDuration("ORDER_FETCHER_PERIOD", "5s"),
+		FallbackAfter: mustDuration("ORDER_FETCHER_FALLBACK_AFTER", "30s"),
+	}
+	switch fetcher.Mode {
+	case "":
+		fetcher.Mode = FetcherOff
+	case FetcherOff, FetcherAlways, FetcherFallback:
+	default:
+		panic("invalid ORDER_FETCHER_MODE: must be off, always or fallback")
+	}
+
+	client := OrderClientConfig{
+		Transport:   strings.ToLower(envOr("ORDER_SERVICE_TRANSPORT", OrderTransportHTTP)),
+		GRPCAddr:    os.Getenv("ORDER_SERVICE_GRPC_ADDR"),
+		GRPCTLS:     os.Getenv("ORDER_SERVICE_GRPC_TLS") == "true",
+		GRPCCAFile:  os.Getenv("ORDER_SERVICE_GRPC_CA_FILE"),
+		Timeout:     mustDuration("ORDER_SERVICE_TIMEOUT", "5s"),
+		MaxAttempts: mustPositiveInt("ORDER_RETRY_MAX_ATTEMPTS", 4),
+		BaseDelay:   mustDuration("ORDER_RETRY_BASE_DELAY", "100ms"),
+		MaxDelay:    mustDuration("ORDER_RETRY_MAX_DELAY", "2s"),
+		Jitter:      os.Getenv("ORDER_RETRY_JITTER") != "false",
+		RetryBudget: mustDuration("ORDER_RETRY_BUDGET", "10s"),
+		PageSize:    mustNonNegativeInt("ORDER_FETCH_PAGE_SIZE", 500),
+		BatchSize:   mustPositiveInt("ORDER_STATUS_BATCH_SIZE", 100),
+		Concurrency: mustPositiveInt("ORDER_STATUS_CONCURRENCY", 8),
+	}
+
+	env := strings.ToLower(envOr("APP_ENV", "production"))
+	client.Auth = loadOrderAuth(env)
+
+	breaker := CircuitBreakerConfig{
+		Enabled:          os.Getenv("ORDER_BREAKER_ENABLED") != "false",
+		FailureRatio:     mustRatio("ORDER_BREAKER_FAILURE_RATIO", 0.5),
+		MinRequests:      mustPositiveInt("ORDER_BREAKER_MIN_REQUESTS", 10),
+		Window:           mustDuration("ORDER_BREAKER_WINDOW", "30s"),
+		CoolDown:         mustDuration("ORDER_BREAKER_COOLDOWN", "10s"),
+		HalfOpenRequests: mustPositiveInt("ORDER_BREAKER_HALF_OPEN_REQUESTS", 1),
+	}
+
+	cache := OrderCacheConfig{
+		Enabled:     os.Getenv("ORDER_CACHE_ENABLED") == "true",
+		StatusTTL:   mustDuration("ORDER_CACHE_STATUS_TTL", "2s"),
+		NotFoundTTL: mustDuration("ORDER_CACHE_NOT_FOUND_TTL", "5s"),
+		OrdersTTL:   mustDuration("ORDER_CACHE_ORDERS_TTL", "0s"),
+		MaxEntries:  mustPositiveInt("ORDER_CACHE_MAX_ENTRIES", 10000),
 	}
 
 	flag.StringVar(&port, "port", port, "Server port")
@@ -82,33 +270,175 @@ func MustLoad() *Config {
 		port = "8080"
 	}
 
-	if orderServiceHost == "" {
-		panic("ORDER_SERVICE_HOST is required")
-	}
-	if kafka.Enabled {
-		if len(kafka.Brokers) == 0 {
-			panic("KAFKA_BROKERS is required when KAFKA_ENABLED=true")
-		}
-		if kafka.Topic == "" {
-			panic("KAFKA_TOPIC is required when KAFKA_ENABLED=true")
+	switch client.Transport {
+	case OrderTransportHTTP:
+		if orderServiceHost == "" {
+			panic("ORDER_SERVICE_HOST is required")
 		}
-		if kafka.GroupID == "" {
-			panic("KAFKA_GROUP_ID is required when KAFKA_ENABLED=true")
+	case OrderTransportGRPC:
+		if client.GRPCAddr == "" {
+			panic("ORDER_SERVICE_GRPC_ADDR is required when ORDER_SERVICE_TRANSPORT=grpc")
 		}
+	default:
+		panic("invalid ORDER_SERVICE_TRANSPORT: must be http or grpc")
+	}
+	if client.GRPCCAFile != "" && !client.GRPCTLS {
+		panic("ORDER_SERVICE_GRPC_CA_FILE requires ORDER_SERVICE_GRPC_TLS=true")
+	}
+	// токены и подписи не должны уходить в открытом виде за пределами локальной разработки
+	if sendsCredentials(client.Auth.Mode) && !secureOrderTransport(client, orderServiceHost) && env != EnvDev {
+		panic("ORDER_AUTH_MODE=" + client.Auth.Mode + " requires TLS to the order service " +
+			"(https ORDER_SERVICE_HOST or ORDER_SERVICE_GRPC_TLS=true) unless APP_ENV=dev")
+	}
+	if outbox.Topic != "" && len(kafka.Brokers) == 0 {
+		panic("KAFKA_BROKERS is required when OUTBOX_TOPIC is set")
 	}
 
 	return &Config{
+		Env:              env,
 		Port:             port,
+		AdminToken:       os.Getenv("ADMIN_TOKEN"),
 		OrderServiceHost: orderServiceHost,
+		OrderClient:      client,
 		Postgres:         pg,
-		Delivery:         DeliveryConfig{TickerInterval: tickerInterval},
-		Kafka:            kafka,
+		Delivery: DeliveryConfig{
+			TickerInterval:     tickerInterval,
+			QueueDrainInterval: drainInterval,
+			AssignmentStrategy: os.Getenv("DELIVERY_ASSIGNMENT_STRATEGY"),
+			LoadWindow:         loadWindow,
+
+			DeadlinePolicyReloadInterval: policyReloadInterval,
+			ReassignExpired:              os.Getenv("DELIVERY_REASSIGN_EXPIRED") == "true",
+		},
+		Kafka:        kafka,
+		Outbox:       outbox,
+		OrderFetcher: fetcher,
+		OrderBreaker: breaker,
+		OrderCache:   cache,
 	}
 }
 
+// mustDuration читает длительность из env, подставляя def, если переменная не задана
+func mustDuration(key, def string) time.Duration {
+	raw := os.Getenv(key)
+	if raw == "" {
+		raw = def
+	}
+
+	d, err := time.ParseDuration(raw)
+	if err != nil {
+		panic("invalid " + key + ": " + err.Error())
+	}
+	return d
+}
+
 func (p PostgresConfig) DSN() string {
 	return fmt.Sprintf(
 		"postgres://%s:%s@%s:%s/%s?sslmode=disable",
 		p.User, p.Password, p.Host, p.Port, p.DBName,
 	)
 }
+
+// mustPositiveInt читает положительное целое из env, подставляя def, если переменная не задана
+func mustPositiveInt(key string, def int) int {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return def
+	}
+
+	v, err := strconv.Atoi(raw)
+	if err != nil || v < 1 {
+		panic("invalid " + key + ": must be a positive integer")
+	}
+	return v
+}
+
+// mustNonNegativeInt читает неотрицательное целое из env, подставляя def, если переменная не задана
+func mustNonNegativeInt(key string, def int) int {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return def
+	}
+
+	v, err := strconv.Atoi(raw)
+	if err != nil || v < 0 {
+		panic("invalid " + key + ": must be a non-negative integer")
+	}
+	return v
+}
+
+// splitList разбирает список через запятую, пропуская пустые элементы
+func splitList(raw string) []string {
+	var out []string
+	for _, item := range strings.Split(raw, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// mustRatio читает долю из (0, 1] из env, подставляя def, если переменная не задана
+func mustRatio(key string, def float64) float64 {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return def
+	}
+
+	v, err := strconv.ParseFloat(raw, 64)
+	if err != nil || v <= 0 || v > 1 {
+		panic("invalid " + key + ": must be a number in (0, 1]")
+	}
+	return v
+}
+
+// sendsCredentials — способ аутентификации передаёт order-сервису токен или подпись
+func sendsCredentials(mode string) bool {
+	return mode != OrderAuthNone && mode != OrderAuthBypass
+}
+
+// secureOrderTransport — вызовы order-сервиса идут по TLS
+func secureOrderTransport(client OrderClientConfig, orderServiceHost string) bool {
+	if client.Transport == OrderTransportGRPC {
+		return client.GRPCTLS
+	}
+	return strings.HasPrefix(strings.ToLower(orderServiceHost), "https://")
+}
+
+// loadOrderAuth читает аутентификацию order-сервиса и паникует при неполных настройках
+func loadOrderAuth(env string) OrderAuthConfig {
+	auth := OrderAuthConfig{
+		Mode:         strings.ToLower(envOr("ORDER_AUTH_MODE", OrderAuthNone)),
+		Token:        os.Getenv("ORDER_AUTH_TOKEN"),
+		TokenURL:     os.Getenv("ORDER_AUTH_TOKEN_URL"),
+		ClientID:     os.Getenv("ORDER_AUTH_CLIENT_ID"),
+		ClientSecret: os.Getenv("ORDER_AUTH_CLIENT_SECRET"),
+		Scopes:       splitList(os.Getenv("ORDER_AUTH_SCOPES")),
+		HMACKeyID:    os.Getenv("ORDER_AUTH_HMAC_KEY_ID"),
+		HMACSecret:   os.Getenv("ORDER_AUTH_HMAC_SECRET"),
+	}
+
+	switch auth.Mode {
+	case OrderAuthNone:
+	case OrderAuthBearer:
+		if auth.Token == "" {
+			panic("ORDER_AUTH_TOKEN is required when ORDER_AUTH_MODE=bearer")
+		}
+	case OrderAuthOAuth2:
+		if auth.TokenURL == "" || auth.ClientID == "" || auth.ClientSecret == "" {
+			panic("ORDER_AUTH_TOKEN_URL, ORDER_AUTH_CLIENT_ID and ORDER_AUTH_CLIENT_SECRET are required when ORDER_AUTH_MODE=oauth2")
+		}
+	case OrderAuthHMAC:
+		if auth.HMACKeyID == "" || auth.HMACSecret == "" {
+			panic("ORDER_AUTH_HMAC_KEY_ID and ORDER_AUTH_HMAC_SECRET are required when ORDER_AUTH_MODE=hmac")
+		}
+	case OrderAuthBypass:
+		if env != EnvDev {
+			panic("ORDER_AUTH_MODE=bypass is allowed only with APP_ENV=dev")
+		}
+	default:
+		panic("invalid ORDER_AUTH_MODE: must be none, bearer, oauth2, hmac or bypass")
+	}
+
+	return auth
+}
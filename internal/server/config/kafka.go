@@ -0,0 +1,180 @@
+package config
+
+import (
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+// Начальный offset для группы без сохранённых offset-ов
+const (
+	OffsetOldest = "oldest"
+	OffsetNewest = "newest"
+)
+
+// Стратегии распределения партиций между участниками группы
+const (
+	BalanceRange      = "range"
+	BalanceRoundRobin = "roundrobin"
+	BalanceSticky     = "sticky"
+)
+
+// Механизмы SASL-аутентификации
+const (
+	SASLPlain       = "PLAIN"
+	SASLScramSHA256 = "SCRAM-SHA-256"
+	SASLScramSHA512 = "SCRAM-SHA-512"
+)
+
+type KafkaConfig struct {
+	Enabled bool
+	Brokers []string
+	// Topics — топики событий заказов; KAFKA_TOPIC через запятую
+	Topics  []string
+	GroupID string
+	// ClientID — имя клиента в логах и квотах брокера
+	ClientID string
+	// Version — версия протокола Kafka, например 2.6.0
+	Version string
+
+	InitialOffset     string
+	BalanceStrategy   string
+	SessionTimeout    time.Duration
+	HeartbeatInterval time.Duration
+
+	SASL KafkaSASLConfig
+	TLS  KafkaTLSConfig
+
+	// DLQTopic — топик для сообщений, которые не удалось обработать; пусто — не публиковать
+	DLQTopic        string
+	MaxAttempts     int
+	RetryBackoff    time.Duration
+	MaxRetryBackoff time.Duration
+	// ProcessedRetention — сколько хранить ключи обработанных событий для дедупликации
+	ProcessedRetention time.Duration
+	// EventMode — откуда брать статус заказа: lookup, event или event_verify
+	EventMode string
+	// Concurrency — число параллельных обработчиков партиции
+	Concurrency int
+}
+
+// KafkaSASLConfig — аутентификация в брокере; пустой Mechanism выключает SASL
+type KafkaSASLConfig struct {
+	Mechanism string
+	User      string
+	Password  string
+}
+
+type KafkaTLSConfig struct {
+	Enabled bool
+	// CAFile — сертификат CA брокеров; пусто — системные корневые сертификаты
+	CAFile string
+	// CertFile и KeyFile — клиентский сертификат для mTLS
+	CertFile           string
+	KeyFile            string
+	InsecureSkipVerify bool
+}
+
+// LoadKafka читает настройки Kafka из env и паникует на некорректных значениях
+func LoadKafka() KafkaConfig {
+	k := KafkaConfig{
+		Enabled:  os.Getenv("KAFKA_ENABLED") == "true",
+		Brokers:  splitList(os.Getenv("KAFKA_BROKERS")),
+		Topics:   splitList(os.Getenv("KAFKA_TOPIC")),
+		GroupID:  os.Getenv("KAFKA_GROUP_ID"),
+		ClientID: envOr("KAFKA_CLIENT_ID", "service-courier"),
+		Version:  envOr("KAFKA_VERSION", sarama.V2_6_0_0.String()),
+
+		InitialOffset:     strings.ToLower(envOr("KAFKA_INITIAL_OFFSET", OffsetNewest)),
+		BalanceStrategy:   strings.ToLower(envOr("KAFKA_BALANCE_STRATEGY", BalanceRange)),
+		SessionTimeout:    mustDuration("KAFKA_SESSION_TIMEOUT", "10s"),
+		HeartbeatInterval: mustDuration("KAFKA_HEARTBEAT_INTERVAL", "3s"),
+
+		SASL: KafkaSASLConfig{
+			Mechanism: strings.ToUpper(os.Getenv("KAFKA_SASL_MECHANISM")),
+			User:      os.Getenv("KAFKA_SASL_USER"),
+			Password:  os.Getenv("KAFKA_SASL_PASSWORD"),
+		},
+		TLS: KafkaTLSConfig{
+			Enabled:            os.Getenv("KAFKA_TLS_ENABLED") == "true",
+			CAFile:             os.Getenv("KAFKA_TLS_CA_FILE"),
+			CertFile:           os.Getenv("KAFKA_TLS_CERT_FILE"),
+			KeyFile:            os.Getenv("KAFKA_TLS_KEY_FILE"),
+			InsecureSkipVerify: os.Getenv("KAFKA_TLS_INSECURE_SKIP_VERIFY") == "true",
+		},
+
+		DLQTopic:        os.Getenv("KAFKA_DLQ_TOPIC"),
+		MaxAttempts:     mustPositiveInt("KAFKA_MAX_ATTEMPTS", 3),
+		RetryBackoff:    mustDuration("KAFKA_RETRY_BACKOFF", "200ms"),
+		MaxRetryBackoff: mustDuration("KAFKA_MAX_RETRY_BACKOFF", "5s"),
+
+		ProcessedRetention: mustDuration("KAFKA_PROCESSED_EVENTS_RETENTION", "168h"),
+		EventMode:          os.Getenv("ORDER_EVENT_MODE"),
+		Concurrency:        mustPositiveInt("KAFKA_CONCURRENCY", 8),
+	}
+
+	// настройки подключения проверяются всегда: ими пользуется и producer outbox
+	if _, err := sarama.ParseKafkaVersion(k.Version); err != nil {
+		panic("invalid KAFKA_VERSION: " + err.Error())
+	}
+
+	switch k.SASL.Mechanism {
+	case "":
+	case SASLPlain, SASLScramSHA256, SASLScramSHA512:
+		if k.SASL.User == "" || k.SASL.Password == "" {
+			panic("KAFKA_SASL_USER and KAFKA_SASL_PASSWORD are required when KAFKA_SASL_MECHANISM is set")
+		}
+	default:
+		panic("invalid KAFKA_SASL_MECHANISM: must be PLAIN, SCRAM-SHA-256 or SCRAM-SHA-512")
+	}
+
+	if (k.TLS.CertFile == "") != (k.TLS.KeyFile == "") {
+		panic("KAFKA_TLS_CERT_FILE and KAFKA_TLS_KEY_FILE must be set together")
+	}
+
+	if !k.Enabled {
+		return k
+	}
+
+	if len(k.Brokers) == 0 {
+		panic("KAFKA_BROKERS is required when KAFKA_ENABLED=true")
+	}
+	if len(k.Topics) == 0 {
+		panic("KAFKA_TOPIC is required when KAFKA_ENABLED=true")
+	}
+	if k.GroupID == "" {
+		panic("KAFKA_GROUP_ID is required when KAFKA_ENABLED=true")
+	}
+	if k.DLQTopic != "" && slices.Contains(k.Topics, k.DLQTopic) {
+		panic("KAFKA_DLQ_TOPIC must differ from KAFKA_TOPIC")
+	}
+
+	switch k.InitialOffset {
+	case OffsetOldest, OffsetNewest:
+	default:
+		panic("invalid KAFKA_INITIAL_OFFSET: must be oldest or newest")
+	}
+
+	switch k.BalanceStrategy {
+	case BalanceRange, BalanceRoundRobin, BalanceSticky:
+	default:
+		panic("invalid KAFKA_BALANCE_STRATEGY: must be range, roundrobin or sticky")
+	}
+
+	// брокер считает участника мёртвым, если за session timeout не было heartbeat
+	if k.HeartbeatInterval <= 0 || k.HeartbeatInterval*3 > k.SessionTimeout {
+		panic("KAFKA_HEARTBEAT_INTERVAL must be positive and at most a third of KAFKA_SESSION_TIMEOUT")
+	}
+
+	return k
+}
+
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
@@ -2,6 +2,7 @@ package worker
 
 import (
 	"context"
+	"sync"
 	"time"
 
 	"github.com/IBM/sarama"
@@ -10,22 +11,27 @@ import (
 
 type KafkaConsumer struct {
 	group   sarama.ConsumerGroup
-	topic   string
+	topics  []string
 	handler sarama.ConsumerGroupHandler
 	log     *zap.SugaredLogger
+
+	mu sync.Mutex
+	// unhealthySince — с какого момента нет активной сессии; нулевое — сессия идёт
+	unhealthySince time.Time
 }
 
 func NewKafkaConsumer(
 	group sarama.ConsumerGroup,
-	topic string,
+	topics []string,
 	handler sarama.ConsumerGroupHandler,
 	log *zap.SugaredLogger,
 ) *KafkaConsumer {
 	return &KafkaConsumer{
-		group:   group,
-		topic:   topic,
-		handler: handler,
-		log:     log,
+		group:          group,
+		topics:         topics,
+		handler:        handler,
+		log:            log,
+		unhealthySince: time.Now(),
 	}
 }
 
@@ -33,9 +39,12 @@ func (c *KafkaConsumer) Start(ctx context.Context) {
 	go func() {
 		defer func() { _ = c.group.Close() }()
 
+		handler := &sessionTracker{ConsumerGroupHandler: c.handler, consumer: c}
+
 		for {
-			if err := c.group.Consume(ctx, []string{c.topic}, c.handler); err != nil {
+			if err := c.group.Consume(ctx, c.topics, handler); err != nil {
 				c.log.Error("Kafka consume error", zap.Error(err))
+				c.setHealthy(false)
 
 				select {
 				case <-time.After(time.Second):
@@ -50,3 +59,45 @@ func (c *KafkaConsumer) Start(ctx context.Context) {
 		}
 	}()
 }
+
+// UnhealthyFor возвращает, сколько consumer уже без активной сессии группы; 0 — сессия идёт
+func (c *KafkaConsumer) UnhealthyFor() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.unhealthySince.IsZero() {
+		return 0
+	}
+	return time.Since(c.unhealthySince)
+}
+
+func (c *KafkaConsumer) setHealthy(healthy bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch {
+	case healthy:
+		c.unhealthySince = time.Time{}
+	case c.unhealthySince.IsZero():
+		c.unhealthySince = time.Now()
+	}
+}
+
+// sessionTracker отмечает начало и конец сессии группы для UnhealthyFor
+type sessionTracker struct {
+	sarama.ConsumerGroupHandler
+	consumer *KafkaConsumer
+}
+
+func (t *sessionTracker) Setup(s sarama.ConsumerGroupSession) error {
+	if err := t.ConsumerGroupHandler.Setup(s); err != nil {
+		return err
+	}
+	t.consumer.setHealthy(true)
+	return nil
+}
+
+func (t *sessionTracker) Cleanup(s sarama.ConsumerGroupSession) error {
+	t.consumer.setHealthy(false)
+	return t.ConsumerGroupHandler.Cleanup(s)
+}
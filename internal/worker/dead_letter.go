@@ -0,0 +1,69 @@
+package worker
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+// Заголовки, которые добавляются к сообщению при публикации в dead-letter топик
+const (
+	HeaderDLQError             = "x-dlq-error"
+	HeaderDLQAttempts          = "x-dlq-attempts"
+	HeaderDLQFailedAt          = "x-dlq-failed-at"
+	HeaderDLQOriginalTopic     = "x-original-topic"
+	HeaderDLQOriginalPartition = "x-original-partition"
+	HeaderDLQOriginalOffset    = "x-original-offset"
+)
+
+// DeadLetterPublisher отправляет сообщение, которое не удалось обработать, в отдельный топик
+type DeadLetterPublisher interface {
+	Publish(ctx context.Context, msg *sarama.ConsumerMessage, cause error, attempts int) error
+}
+
+type KafkaDeadLetter struct {
+	producer sarama.SyncProducer
+	topic    string
+}
+
+func NewKafkaDeadLetter(producer sarama.SyncProducer, topic string) *KafkaDeadLetter {
+	return &KafkaDeadLetter{producer: producer, topic: topic}
+}
+
+// Publish сохраняет ключ, тело и заголовки исходного сообщения и добавляет к ним
+// причину ошибки, число попыток и исходные топик/партицию/offset
+func (d *KafkaDeadLetter) Publish(_ context.Context, msg *sarama.ConsumerMessage, cause error, attempts int) error {
+	headers := make([]sarama.RecordHeader, 0, len(msg.Headers)+6)
+	for _, h := range msg.Headers {
+		if h != nil {
+			headers = append(headers, *h)
+		}
+	}
+
+	headers = append(headers,
+		header(HeaderDLQError, cause.Error()),
+		header(HeaderDLQAttempts, strconv.Itoa(attempts)),
+		header(HeaderDLQFailedAt, time.Now().UTC().Format(time.RFC3339Nano)),
+		header(HeaderDLQOriginalTopic, msg.Topic),
+		header(HeaderDLQOriginalPartition, strconv.FormatInt(int64(msg.Partition), 10)),
+		header(HeaderDLQOriginalOffset, strconv.FormatInt(msg.Offset, 10)),
+	)
+
+	out := &sarama.ProducerMessage{
+		Topic:   d.topic,
+		Value:   sarama.ByteEncoder(msg.Value),
+		Headers: headers,
+	}
+	if msg.Key != nil {
+		out.Key = sarama.ByteEncoder(msg.Key)
+	}
+
+	_, _, err := d.producer.SendMessage(out)
+	return err
+}
+
+func header(key, value string) sarama.RecordHeader {
+	return sarama.RecordHeader{Key: []byte(key), Value: []byte(value)}
+}
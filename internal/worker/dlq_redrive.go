@@ -0,0 +1,155 @@
+package worker
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/IBM/sarama"
+	"go.uber.org/zap"
+)
+
+// HeaderRedriveCount — сколько раз сообщение уже возвращалось из dead-letter топика
+const HeaderRedriveCount = "x-redrive-count"
+
+// DLQRedriver читает dead-letter топик и публикует сообщения обратно в основной топик.
+// Служебные x-dlq-* заголовки снимаются, счётчик возвратов увеличивается.
+type DLQRedriver struct {
+	producer sarama.SyncProducer
+	topic    string
+	limit    int64
+	dryRun   bool
+	log      *zap.SugaredLogger
+
+	count    atomic.Int64
+	lastSeen atomic.Int64
+
+	stopOnce sync.Once
+	stop     func()
+}
+
+// NewDLQRedriver создаёт обработчик; limit = 0 — без ограничения, stop вызывается при достижении limit
+func NewDLQRedriver(
+	producer sarama.SyncProducer,
+	topic string,
+	limit int,
+	dryRun bool,
+	stop func(),
+	log *zap.SugaredLogger,
+) *DLQRedriver {
+	r := &DLQRedriver{
+		producer: producer,
+		topic:    topic,
+		limit:    int64(limit),
+		dryRun:   dryRun,
+		stop:     stop,
+		log:      log,
+	}
+	r.lastSeen.Store(time.Now().UnixNano())
+	return r
+}
+
+// Redriven — сколько сообщений возвращено в основной топик
+func (r *DLQRedriver) Redriven() int64 {
+	return min(r.count.Load(), r.limitOrMax())
+}
+
+// IdleFor — сколько времени не приходило новых сообщений
+func (r *DLQRedriver) IdleFor() time.Duration {
+	return time.Since(time.Unix(0, r.lastSeen.Load()))
+}
+
+func (r *DLQRedriver) Setup(sarama.ConsumerGroupSession) error {
+	return nil
+}
+
+func (r *DLQRedriver) Cleanup(sarama.ConsumerGroupSession) error {
+	return nil
+}
+
+func (r *DLQRedriver) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	for msg := range claim.Messages() {
+		r.lastSeen.Store(time.Now().UnixNano())
+
+		if r.count.Add(1) > r.limitOrMax() {
+			r.stopOnce.Do(r.stop)
+			return nil
+		}
+
+		out := RedriveMessage(msg, r.topic)
+
+		if r.dryRun {
+			r.log.Infow("dlq message (dry run)",
+				"partition", msg.Partition,
+				"offset", msg.Offset,
+				"error", headerValue(msg, HeaderDLQError),
+				"value", string(msg.Value),
+			)
+			continue
+		}
+
+		if _, _, err := r.producer.SendMessage(out); err != nil {
+			r.count.Add(-1)
+			return err
+		}
+
+		session.MarkMessage(msg, "")
+		r.log.Infow("dlq message redriven",
+			"partition", msg.Partition,
+			"offset", msg.Offset,
+			"topic", r.topic,
+		)
+	}
+	return nil
+}
+
+func (r *DLQRedriver) limitOrMax() int64 {
+	if r.limit <= 0 {
+		return 1<<63 - 1
+	}
+	return r.limit
+}
+
+// RedriveMessage готовит сообщение из dead-letter топика к повторной публикации в topic
+func RedriveMessage(msg *sarama.ConsumerMessage, topic string) *sarama.ProducerMessage {
+	redrives := 0
+	headers := make([]sarama.RecordHeader, 0, len(msg.Headers)+1)
+
+	for _, h := range msg.Headers {
+		if h == nil {
+			continue
+		}
+
+		key := string(h.Key)
+		switch {
+		case key == HeaderRedriveCount:
+			redrives, _ = strconv.Atoi(string(h.Value))
+		case strings.HasPrefix(key, "x-dlq-"), strings.HasPrefix(key, "x-original-"):
+		default:
+			headers = append(headers, *h)
+		}
+	}
+
+	headers = append(headers, header(HeaderRedriveCount, strconv.Itoa(redrives+1)))
+
+	out := &sarama.ProducerMessage{
+		Topic:   topic,
+		Value:   sarama.ByteEncoder(msg.Value),
+		Headers: headers,
+	}
+	if msg.Key != nil {
+		out.Key = sarama.ByteEncoder(msg.Key)
+	}
+	return out
+}
+
+func headerValue(msg *sarama.ConsumerMessage, key string) string {
+	for _, h := range msg.Headers {
+		if h != nil && string(h.Key) == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
@@ -1,6 +1,7 @@
 package worker
 
 type Logger interface {
+	Debugw(msg string, keysAndValues ...any)
 	Warnw(msg string, keysAndValues ...any)
 	Errorw(msg string, keysAndValues ...any)
 }
@@ -0,0 +1,77 @@
+package worker
+
+import (
+	"sync"
+
+	"github.com/IBM/sarama"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// offsetTracker помечает сообщения партиции только сплошным префиксом:
+// сообщение помечается, когда обработаны и оно, и все более ранние.
+// Иначе после падения потерялись бы необработанные сообщения перед помеченным.
+type offsetTracker struct {
+	mu      sync.Mutex
+	session sarama.ConsumerGroupSession
+	claim   sarama.ConsumerGroupClaim
+	pending []*trackedMessage
+	// next — offset, который будет закоммичен следующим; -1 — ещё ничего не помечено
+	next int64
+	// lag обновляется под mu, чтобы устаревшее значение не перезаписало свежее
+	lag prometheus.Gauge
+}
+
+type trackedMessage struct {
+	msg  *sarama.ConsumerMessage
+	done bool
+}
+
+func newOffsetTracker(
+	session sarama.ConsumerGroupSession,
+	claim sarama.ConsumerGroupClaim,
+	lag prometheus.Gauge,
+) *offsetTracker {
+	return &offsetTracker{session: session, claim: claim, next: -1, lag: lag}
+}
+
+// add регистрирует сообщение; вызывается в порядке чтения из партиции
+func (t *offsetTracker) add(msg *sarama.ConsumerMessage) *trackedMessage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	m := &trackedMessage{msg: msg}
+	t.pending = append(t.pending, m)
+	t.updateLag()
+	return m
+}
+
+// done отмечает сообщение обработанным и помечает готовый префикс
+func (t *offsetTracker) done(m *trackedMessage) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	m.done = true
+
+	n := 0
+	for n < len(t.pending) && t.pending[n].done {
+		t.session.MarkMessage(t.pending[n].msg, "")
+		t.next = t.pending[n].msg.Offset + 1
+		n++
+	}
+
+	clear(t.pending[:n])
+	t.pending = t.pending[n:]
+	t.updateLag()
+}
+
+// updateLag выставляет, сколько сообщений партиции до high-water mark ещё не закоммичено
+func (t *offsetTracker) updateLag() {
+	next := t.next
+	if len(t.pending) > 0 {
+		next = t.pending[0].msg.Offset
+	}
+	if next < 0 {
+		return
+	}
+	t.lag.Set(float64(max(t.claim.HighWaterMarkOffset()-next, 0)))
+}
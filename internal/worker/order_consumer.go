@@ -1,24 +1,113 @@
 package worker
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"sync"
+	"time"
 
 	"github.com/IBM/sarama"
+
+	"github.com/Avito-courses/course-go-avito-israpilovsha/internal/gateway/order"
+	"github.com/Avito-courses/course-go-avito-israpilovsha/internal/metrics"
+)
+
+const (
+	defaultMaxAttempts  = 3
+	defaultRetryBackoff = 200 * time.Millisecond
+	defaultMaxBackoff   = 5 * time.Second
+	defaultCircuitPause = time.Second
+
+	// laneBuffer — сколько сообщений может ждать в очереди одного обработчика
+	laneBuffer = 32
 )
 
 type OrderConsumer struct {
 	log       Logger
 	processor OrderProcessor
+	dlq       DeadLetterPublisher
+
+	maxAttempts int
+	backoff     time.Duration
+	maxBackoff  time.Duration
+
+	// concurrency — число параллельных обработчиков одной партиции
+	concurrency int
+	// circuitPause — пауза обработчика, пока circuit breaker order-сервиса открыт
+	circuitPause time.Duration
+}
+
+type ConsumerOption func(*OrderConsumer)
+
+// WithRetry задаёт число попыток обработки сообщения и экспоненциальную паузу между ними
+func WithRetry(maxAttempts int, backoff, maxBackoff time.Duration) ConsumerOption {
+	return func(c *OrderConsumer) {
+		if maxAttempts > 0 {
+			c.maxAttempts = maxAttempts
+		}
+		if backoff > 0 {
+			c.backoff = backoff
+		}
+		if maxBackoff > 0 {
+			c.maxBackoff = maxBackoff
+		}
+	}
+}
+
+// WithDeadLetter включает публикацию необработанных сообщений в dead-letter топик.
+// Без него такие сообщения только логируются и пропускаются.
+func WithDeadLetter(dlq DeadLetterPublisher) ConsumerOption {
+	return func(c *OrderConsumer) {
+		c.dlq = dlq
+	}
 }
 
-func NewOrderConsumer(p OrderProcessor, log Logger) *OrderConsumer {
-	return &OrderConsumer{
-		processor: p,
-		log:       log,
+// WithConcurrency задаёт число параллельных обработчиков партиции.
+// События одного заказа всегда попадают к одному обработчику и идут по порядку.
+func WithConcurrency(n int) ConsumerOption {
+	return func(c *OrderConsumer) {
+		if n > 0 {
+			c.concurrency = n
+		}
 	}
 }
 
+// WithCircuitPause задаёт, как часто перепроверять order-сервис при открытом circuit breaker.
+// Пока breaker открыт, сообщение не тратит попытки и не уходит в dead-letter топик.
+func WithCircuitPause(d time.Duration) ConsumerOption {
+	return func(c *OrderConsumer) {
+		if d > 0 {
+			c.circuitPause = d
+		}
+	}
+}
+
+func NewOrderConsumer(p OrderProcessor, log Logger, opts ...ConsumerOption) *OrderConsumer {
+	c := &OrderConsumer{
+		processor:   p,
+		log:         log,
+		maxAttempts: defaultMaxAttempts,
+		backoff:     defaultRetryBackoff,
+		maxBackoff:  defaultMaxBackoff,
+		concurrency: 1,
+
+		circuitPause: defaultCircuitPause,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// Setup вызывается при каждой новой сессии группы, то есть после каждого ребаланса
 func (c *OrderConsumer) Setup(sarama.ConsumerGroupSession) error {
+	metrics.KafkaRebalancesTotal.Inc()
 	return nil
 }
 
@@ -26,24 +115,195 @@ func (c *OrderConsumer) Cleanup(sarama.ConsumerGroupSession) error {
 	return nil
 }
 
+// ConsumeClaim раздаёт сообщения партиции обработчикам по order_id:
+// события разных заказов обрабатываются параллельно, одного заказа — по порядку.
+// Сообщение помечается только после успешной обработки или отправки в dead-letter топик
+// и только вместе со всеми более ранними; остальное будет прочитано снова после ребаланса.
 func (c *OrderConsumer) ConsumeClaim(
 	session sarama.ConsumerGroupSession,
 	claim sarama.ConsumerGroupClaim,
 ) error {
+	ctx := session.Context()
+	topic, partition := claim.Topic(), strconv.Itoa(int(claim.Partition()))
+	tracker := newOffsetTracker(session, claim, metrics.KafkaConsumerLag.WithLabelValues(topic, partition))
+	// партиция может уйти к другому экземпляру — его лаг здесь больше не актуален
+	defer metrics.KafkaConsumerLag.DeleteLabelValues(topic, partition)
+
+	lanes := make([]chan *trackedMessage, c.concurrency)
+	var wg sync.WaitGroup
+	for i := range lanes {
+		lanes[i] = make(chan *trackedMessage, laneBuffer)
+
+		wg.Add(1)
+		go func(lane <-chan *trackedMessage) {
+			defer wg.Done()
+			for m := range lane {
+				// после отмены сессии сообщения не обрабатываются и не помечаются
+				if ctx.Err() == nil && c.process(ctx, m.msg) {
+					tracker.done(m)
+				}
+			}
+		}(lanes[i])
+	}
+
+	defer func() {
+		for _, lane := range lanes {
+			close(lane)
+		}
+		wg.Wait()
+	}()
+
 	for msg := range claim.Messages() {
-		var ev OrderEvent
-		if err := json.Unmarshal(msg.Value, &ev); err != nil {
-			c.log.Warnw("bad kafka message", "err", err)
-			session.MarkMessage(msg, "")
-			continue
+		m := tracker.add(msg)
+		metrics.KafkaMessagesConsumedTotal.WithLabelValues(topic, partition).Inc()
+
+		select {
+		case lanes[c.lane(msg)] <- m:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+	return nil
+}
+
+// process обрабатывает одно сообщение; false — сообщение нельзя помечать
+func (c *OrderConsumer) process(ctx context.Context, msg *sarama.ConsumerMessage) bool {
+	c.log.Debugw("Received Kafka message", "value", string(msg.Value))
+
+	start := time.Now()
+	action, attempts, err := c.handle(ctx, msg)
+	if err != nil && ctx.Err() != nil {
+		// сессия завершилась — сообщение обработает следующий владелец партиции
+		return false
+	}
+	metrics.KafkaProcessingSeconds.WithLabelValues(string(action)).Observe(time.Since(start).Seconds())
+
+	labels := []string{msg.Topic, strconv.Itoa(int(msg.Partition))}
+	switch {
+	case err != nil:
+		metrics.KafkaMessagesFailedTotal.WithLabelValues(labels...).Inc()
+		return c.deadLetter(ctx, msg, err, attempts)
+	case !action.Applied():
+		metrics.KafkaMessagesSkippedTotal.WithLabelValues(labels...).Inc()
+	}
+	return true
+}
+
+// lane выбирает обработчик по order_id; битые сообщения идут в первый
+func (c *OrderConsumer) lane(msg *sarama.ConsumerMessage) int {
+	if c.concurrency == 1 {
+		return 0
+	}
+
+	var ev struct {
+		OrderID string `json:"order_id"`
+	}
+	if err := json.Unmarshal(msg.Value, &ev); err != nil {
+		return 0
+	}
+
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(ev.OrderID))
+	return int(h.Sum32() % uint32(c.concurrency))
+}
+
+// handle разбирает и обрабатывает сообщение с повторами.
+// Битый JSON не повторяется — он не станет корректным со временем.
+func (c *OrderConsumer) handle(ctx context.Context, msg *sarama.ConsumerMessage) (Action, int, error) {
+	var ev OrderEvent
+	if err := json.Unmarshal(msg.Value, &ev); err != nil {
+		return ActionFailed, 0, fmt.Errorf("malformed order event: %w", err)
+	}
+	ev.source = messageSource(msg.Topic, msg.Partition, msg.Offset)
+
+	var err error
+	for attempt := 1; attempt <= c.maxAttempts; {
+		var action Action
+		if action, err = c.processor.Process(ctx, ev); err == nil {
+			return action, attempt, nil
 		}
-		c.log.Warnw("Received Kafka message", "value", string(msg.Value))
-		if err := c.processor.Process(session.Context(), ev); err != nil {
-			c.log.Errorw("order event failed", "order", ev.OrderID, "err", err)
+
+		if errors.Is(err, order.ErrCircuitOpen) {
+			// order-сервис недоступен — ждём его, не расходуя попытки
+			c.log.Debugw("order service circuit open, pausing", "order", ev.OrderID)
+			if !sleepCtx(ctx, c.circuitPause) {
+				return ActionFailed, attempt, ctx.Err()
+			}
 			continue
 		}
 
-		session.MarkMessage(msg, "")
+		c.log.Warnw("order event failed",
+			"order", ev.OrderID,
+			"attempt", attempt,
+			"err", err,
+		)
+
+		if attempt == c.maxAttempts {
+			break
+		}
+		if !sleepCtx(ctx, c.retryDelay(attempt)) {
+			return ActionFailed, attempt, ctx.Err()
+		}
+		attempt++
+	}
+
+	return ActionFailed, c.maxAttempts, err
+}
+
+// deadLetter отправляет сообщение в dead-letter топик, повторяя отправку, пока она не пройдёт.
+// Возвращает false, если сессия завершилась раньше — тогда сообщение нельзя помечать.
+func (c *OrderConsumer) deadLetter(ctx context.Context, msg *sarama.ConsumerMessage, cause error, attempts int) bool {
+	if c.dlq == nil {
+		c.log.Errorw("order event dropped",
+			"partition", msg.Partition,
+			"offset", msg.Offset,
+			"attempts", attempts,
+			"err", cause,
+		)
+		return true
+	}
+
+	for try := 1; ; try++ {
+		err := c.dlq.Publish(ctx, msg, cause, attempts)
+		if err == nil {
+			c.log.Warnw("order event sent to dead-letter topic",
+				"partition", msg.Partition,
+				"offset", msg.Offset,
+				"attempts", attempts,
+				"err", cause,
+			)
+			return true
+		}
+
+		c.log.Errorw("dead-letter publish failed",
+			"partition", msg.Partition,
+			"offset", msg.Offset,
+			"err", err,
+		)
+
+		if !sleepCtx(ctx, c.retryDelay(try)) {
+			return false
+		}
+	}
+}
+
+func (c *OrderConsumer) retryDelay(attempt int) time.Duration {
+	d := c.backoff
+	for i := 1; i < attempt && d < c.maxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, c.maxBackoff)
+}
+
+// sleepCtx ждёт d и возвращает false, если контекст отменён раньше
+func sleepCtx(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
 	}
-	return nil
 }
@@ -0,0 +1,441 @@
+package worker_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/IBM/sarama/mocks"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/Avito-courses/course-go-avito-israpilovsha/internal/gateway/order"
+	"github.com/Avito-courses/course-go-avito-israpilovsha/internal/metrics"
+	"github.com/Avito-courses/course-go-avito-israpilovsha/internal/worker"
+)
+
+type nopLogger struct{}
+
+func (nopLogger) Debugw(string, ...any) {}
+func (nopLogger) Warnw(string, ...any)  {}
+func (nopLogger) Errorw(string, ...any) {}
+
+type fakeSession struct {
+	sarama.ConsumerGroupSession
+	ctx context.Context
+
+	mu     sync.Mutex
+	marked []int64
+}
+
+func (s *fakeSession) Context() context.Context { return s.ctx }
+
+func (s *fakeSession) MarkMessage(msg *sarama.ConsumerMessage, _ string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.marked = append(s.marked, msg.Offset)
+}
+
+type fakeClaim struct {
+	sarama.ConsumerGroupClaim
+	ch            chan *sarama.ConsumerMessage
+	topic         string
+	highWaterMark int64
+}
+
+func (c *fakeClaim) Messages() <-chan *sarama.ConsumerMessage { return c.ch }
+func (c *fakeClaim) Topic() string                            { return c.topic }
+func (c *fakeClaim) Partition() int32                         { return 0 }
+func (c *fakeClaim) HighWaterMarkOffset() int64               { return c.highWaterMark }
+
+func newClaim(msgs ...*sarama.ConsumerMessage) *fakeClaim {
+	ch := make(chan *sarama.ConsumerMessage, len(msgs))
+	for _, m := range msgs {
+		ch <- m
+	}
+	close(ch)
+	return &fakeClaim{ch: ch}
+}
+
+type processorFunc func(ctx context.Context, ev worker.OrderEvent) error
+
+func (f processorFunc) Process(ctx context.Context, ev worker.OrderEvent) (worker.Action, error) {
+	if err := f(ctx, ev); err != nil {
+		return worker.ActionFailed, err
+	}
+	return worker.ActionAssign, nil
+}
+
+type recordingDLQ struct {
+	mu       sync.Mutex
+	failures int
+	offsets  []int64
+	attempts []int
+}
+
+func (d *recordingDLQ) Publish(_ context.Context, msg *sarama.ConsumerMessage, _ error, attempts int) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.failures > 0 {
+		d.failures--
+		return errors.New("broker unavailable")
+	}
+	d.offsets = append(d.offsets, msg.Offset)
+	d.attempts = append(d.attempts, attempts)
+	return nil
+}
+
+func TestOrderConsumerRetriesThenDeadLetters(t *testing.T) {
+	t.Parallel()
+
+	calls := map[string]int{}
+	processor := processorFunc(func(_ context.Context, ev worker.OrderEvent) error {
+		calls[ev.OrderID]++
+		switch ev.OrderID {
+		case "flaky":
+			if calls[ev.OrderID] < 2 {
+				return errors.New("temporary")
+			}
+			return nil
+		case "broken":
+			return errors.New("permanent")
+		default:
+			return nil
+		}
+	})
+
+	dlq := &recordingDLQ{failures: 1}
+	consumer := worker.NewOrderConsumer(processor, nopLogger{},
+		worker.WithRetry(3, time.Millisecond, 2*time.Millisecond),
+		worker.WithDeadLetter(dlq),
+	)
+
+	session := &fakeSession{ctx: context.Background()}
+	claim := newClaim(
+		&sarama.ConsumerMessage{Offset: 1, Value: []byte(`{"order_id":"ok","status":"created"}`)},
+		&sarama.ConsumerMessage{Offset: 2, Value: []byte(`{"order_id":"flaky","status":"created"}`)},
+		&sarama.ConsumerMessage{Offset: 3, Value: []byte(`{"order_id":"broken","status":"created"}`)},
+		&sarama.ConsumerMessage{Offset: 4, Value: []byte(`not json`)},
+	)
+
+	if err := consumer.ConsumeClaim(session, claim); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if calls["flaky"] != 2 || calls["broken"] != 3 {
+		t.Fatalf("unexpected attempts %v", calls)
+	}
+	if len(session.marked) != 4 {
+		t.Fatalf("expected all 4 messages marked, got %v", session.marked)
+	}
+	if len(dlq.offsets) != 2 || dlq.offsets[0] != 3 || dlq.offsets[1] != 4 {
+		t.Fatalf("expected offsets 3 and 4 in dlq, got %v", dlq.offsets)
+	}
+	// битый JSON не обрабатывается повторно
+	if dlq.attempts[0] != 3 || dlq.attempts[1] != 0 {
+		t.Fatalf("unexpected dlq attempts %v", dlq.attempts)
+	}
+}
+
+func TestOrderConsumerDoesNotMarkOnShutdown(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	processor := processorFunc(func(context.Context, worker.OrderEvent) error {
+		cancel()
+		return errors.New("db down")
+	})
+
+	consumer := worker.NewOrderConsumer(processor, nopLogger{},
+		worker.WithRetry(5, time.Second, time.Second),
+		worker.WithDeadLetter(&recordingDLQ{}),
+	)
+
+	session := &fakeSession{ctx: ctx}
+	claim := newClaim(&sarama.ConsumerMessage{Offset: 7, Value: []byte(`{"order_id":"a"}`)})
+
+	if err := consumer.ConsumeClaim(session, claim); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(session.marked) != 0 {
+		t.Fatalf("message must stay unmarked, got %v", session.marked)
+	}
+}
+
+func TestKafkaDeadLetterHeaders(t *testing.T) {
+	t.Parallel()
+
+	producer := mocks.NewSyncProducer(t, nil)
+	producer.ExpectSendMessageWithMessageCheckerFunctionAndSucceed(func(m *sarama.ProducerMessage) error {
+		got := map[string]string{}
+		for _, h := range m.Headers {
+			got[string(h.Key)] = string(h.Value)
+		}
+
+		want := map[string]string{
+			"trace-id":                        "abc",
+			worker.HeaderDLQError:             "boom",
+			worker.HeaderDLQAttempts:          "3",
+			worker.HeaderDLQOriginalTopic:     "orders",
+			worker.HeaderDLQOriginalPartition: "2",
+			worker.HeaderDLQOriginalOffset:    "42",
+		}
+		for k, v := range want {
+			if got[k] != v {
+				return errors.New("header " + k + " = " + got[k] + ", want " + v)
+			}
+		}
+		if m.Topic != "orders.dlq" {
+			return errors.New("wrong topic " + m.Topic)
+		}
+		return nil
+	})
+
+	dlq := worker.NewKafkaDeadLetter(producer, "orders.dlq")
+	msg := &sarama.ConsumerMessage{
+		Topic:     "orders",
+		Partition: 2,
+		Offset:    42,
+		Key:       []byte("order-1"),
+		Value:     []byte(`{}`),
+		Headers:   []*sarama.RecordHeader{{Key: []byte("trace-id"), Value: []byte("abc")}},
+	}
+
+	if err := dlq.Publish(context.Background(), msg, errors.New("boom"), 3); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := producer.Close(); err != nil {
+		t.Fatalf("producer expectations: %v", err)
+	}
+}
+
+func TestRedriveMessage(t *testing.T) {
+	t.Parallel()
+
+	msg := &sarama.ConsumerMessage{
+		Key:   []byte("order-1"),
+		Value: []byte(`{"order_id":"order-1"}`),
+		Headers: []*sarama.RecordHeader{
+			{Key: []byte("trace-id"), Value: []byte("abc")},
+			{Key: []byte(worker.HeaderDLQError), Value: []byte("boom")},
+			{Key: []byte(worker.HeaderDLQOriginalOffset), Value: []byte("42")},
+			{Key: []byte(worker.HeaderRedriveCount), Value: []byte("1")},
+		},
+	}
+
+	out := worker.RedriveMessage(msg, "orders")
+
+	if out.Topic != "orders" {
+		t.Fatalf("expected topic orders, got %s", out.Topic)
+	}
+
+	got := map[string]string{}
+	for _, h := range out.Headers {
+		got[string(h.Key)] = string(h.Value)
+	}
+	if len(got) != 2 || got["trace-id"] != "abc" || got[worker.HeaderRedriveCount] != "2" {
+		t.Fatalf("unexpected headers %v", got)
+	}
+}
+
+func TestOrderEventDedupKey(t *testing.T) {
+	ev := worker.OrderEvent{EventID: "e-1", OrderID: "o-1"}
+	if got := ev.DedupKey(); got != "event:e-1" {
+		t.Fatalf("DedupKey() = %q, want event:e-1", got)
+	}
+
+	if got := (worker.OrderEvent{OrderID: "o-1"}).DedupKey(); got != "" {
+		t.Fatalf("DedupKey() without source = %q, want empty", got)
+	}
+}
+
+// laneOf повторяет выбор обработчика в OrderConsumer
+func laneOf(orderID string, lanes int) uint32 {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(orderID))
+	return h.Sum32() % uint32(lanes)
+}
+
+func TestOrderConsumerParallelKeepsOrderAndMarksContiguously(t *testing.T) {
+	t.Parallel()
+
+	const lanes = 4
+	slow := "slow"
+	fast := "fast-0"
+	for i := 1; laneOf(fast, lanes) == laneOf(slow, lanes); i++ {
+		fast = "fast-" + strconv.Itoa(i)
+	}
+
+	fastDone := make(chan struct{})
+
+	var (
+		mu   sync.Mutex
+		seen = map[string][]int64{}
+	)
+	processor := processorFunc(func(ctx context.Context, ev worker.OrderEvent) error {
+		if ev.OrderID == slow && ev.Version == 1 {
+			// первое событие slow ждёт, пока fast полностью обработается в другом обработчике
+			select {
+			case <-fastDone:
+			case <-time.After(time.Second):
+				return errors.New("events were not processed in parallel")
+			}
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		seen[ev.OrderID] = append(seen[ev.OrderID], ev.Version)
+		if ev.OrderID == fast && len(seen[fast]) == 2 {
+			close(fastDone)
+		}
+		return nil
+	})
+
+	consumer := worker.NewOrderConsumer(processor, nopLogger{},
+		worker.WithRetry(1, time.Millisecond, time.Millisecond),
+		worker.WithConcurrency(lanes),
+	)
+
+	msg := func(offset int64, orderID string, version int) *sarama.ConsumerMessage {
+		return &sarama.ConsumerMessage{
+			Offset: offset,
+			Value:  []byte(`{"order_id":"` + orderID + `","version":` + strconv.Itoa(version) + `}`),
+		}
+	}
+
+	session := &fakeSession{ctx: context.Background()}
+	claim := newClaim(
+		msg(1, slow, 1),
+		msg(2, fast, 1),
+		msg(3, slow, 2),
+		msg(4, fast, 2),
+	)
+
+	if err := consumer.ConsumeClaim(session, claim); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !slices.Equal(seen[slow], []int64{1, 2}) || !slices.Equal(seen[fast], []int64{1, 2}) {
+		t.Fatalf("per-order order broken: %v", seen)
+	}
+	if !slices.Equal(session.marked, []int64{1, 2, 3, 4}) {
+		t.Fatalf("expected offsets marked in order, got %v", session.marked)
+	}
+}
+
+type actionProcessor struct {
+	actions map[string]worker.Action
+	onEvent func(ev worker.OrderEvent)
+}
+
+func (p actionProcessor) Process(_ context.Context, ev worker.OrderEvent) (worker.Action, error) {
+	p.onEvent(ev)
+	if action, ok := p.actions[ev.OrderID]; ok {
+		return action, nil
+	}
+	return worker.ActionFailed, errors.New("unknown order")
+}
+
+func TestOrderConsumerMetrics(t *testing.T) {
+	t.Parallel()
+
+	const topic = "orders-metrics-test"
+
+	lag := -1.0
+	processor := actionProcessor{
+		actions: map[string]worker.Action{
+			"a": worker.ActionAssign,
+			"b": worker.ActionIgnored,
+			"c": worker.ActionSkipped,
+		},
+		onEvent: func(ev worker.OrderEvent) {
+			if ev.OrderID == "c" {
+				// a и b уже закоммичены, следующий offset — 12
+				lag = testutil.ToFloat64(metrics.KafkaConsumerLag.WithLabelValues(topic, "0"))
+			}
+		},
+	}
+	consumer := worker.NewOrderConsumer(processor, nopLogger{}, worker.WithRetry(1, time.Millisecond, time.Millisecond))
+
+	session := &fakeSession{ctx: context.Background()}
+	claim := newClaim(
+		&sarama.ConsumerMessage{Topic: topic, Offset: 10, Value: []byte(`{"order_id":"a"}`)},
+		&sarama.ConsumerMessage{Topic: topic, Offset: 11, Value: []byte(`{"order_id":"b"}`)},
+		&sarama.ConsumerMessage{Topic: topic, Offset: 12, Value: []byte(`{"order_id":"c"}`)},
+		&sarama.ConsumerMessage{Topic: topic, Offset: 13, Value: []byte(`{"order_id":"x"}`)},
+	)
+	claim.topic = topic
+	claim.highWaterMark = 20
+
+	// счётчики глобальные, поэтому сравниваем прирост
+	before := map[string]float64{
+		"consumed": testutil.ToFloat64(metrics.KafkaMessagesConsumedTotal.WithLabelValues(topic, "0")),
+		"skipped":  testutil.ToFloat64(metrics.KafkaMessagesSkippedTotal.WithLabelValues(topic, "0")),
+		"failed":   testutil.ToFloat64(metrics.KafkaMessagesFailedTotal.WithLabelValues(topic, "0")),
+	}
+
+	if err := consumer.ConsumeClaim(session, claim); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if lag != 8 {
+		t.Fatalf("lag while processing offset 12 = %v, want 8", lag)
+	}
+
+	for name, tc := range map[string]struct {
+		counter *prometheus.CounterVec
+		want    float64
+	}{
+		"consumed": {metrics.KafkaMessagesConsumedTotal, 4},
+		"skipped":  {metrics.KafkaMessagesSkippedTotal, 2},
+		"failed":   {metrics.KafkaMessagesFailedTotal, 1},
+	} {
+		got := testutil.ToFloat64(tc.counter.WithLabelValues(topic, "0")) - before[name]
+		if got != tc.want {
+			t.Fatalf("%s = %v, want %v", name, got, tc.want)
+		}
+	}
+}
+
+func TestOrderConsumerPausesWhileCircuitOpen(t *testing.T) {
+	t.Parallel()
+
+	calls := 0
+	processor := processorFunc(func(context.Context, worker.OrderEvent) error {
+		calls++
+		if calls <= 3 {
+			return fmt.Errorf("lookup status: %w", order.ErrCircuitOpen)
+		}
+		return nil
+	})
+
+	dlq := &recordingDLQ{}
+	consumer := worker.NewOrderConsumer(processor, nopLogger{},
+		worker.WithRetry(2, time.Millisecond, time.Millisecond),
+		worker.WithDeadLetter(dlq),
+		worker.WithCircuitPause(time.Millisecond),
+	)
+
+	session := &fakeSession{ctx: context.Background()}
+	claim := newClaim(&sarama.ConsumerMessage{Offset: 5, Value: []byte(`{"order_id":"a"}`)})
+
+	if err := consumer.ConsumeClaim(session, claim); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// три отказа breaker не расходуют две попытки обработки
+	if calls != 4 || len(dlq.offsets) != 0 {
+		t.Fatalf("calls = %d, dlq = %v; want 4 calls and empty dlq", calls, dlq.offsets)
+	}
+	if !slices.Equal(session.marked, []int64{5}) {
+		t.Fatalf("expected offset 5 marked, got %v", session.marked)
+	}
+}
@@ -1,6 +1,40 @@
 package worker
 
+import (
+	"fmt"
+	"time"
+
+	deliveryModel "github.com/Avito-courses/course-go-avito-israpilovsha/internal/delivery/model"
+)
+
 type OrderEvent struct {
+	// EventID — идентификатор события от order-сервиса; по нему отбрасываются повторы
+	EventID string `json:"event_id,omitempty"`
 	OrderID string `json:"order_id"`
 	Status  string `json:"status"`
+	// Version монотонно растёт в пределах заказа
+	Version int64 `json:"version,omitempty"`
+	// UpdatedAt — время изменения заказа; сравнивается вместо Version, если тот не передан
+	UpdatedAt time.Time `json:"updated_at,omitempty"`
+
+	// source — координаты сообщения в Kafka, если у события нет EventID
+	source string
+}
+
+// DedupKey — ключ, по которому событие считается обработанным:
+// EventID, а для событий без него — topic/partition/offset сообщения
+func (e OrderEvent) DedupKey() string {
+	if e.EventID != "" {
+		return "event:" + e.EventID
+	}
+	return e.source
+}
+
+// OrderVersion — версия заказа для отбрасывания устаревших событий; нулевая — версии нет
+func (e OrderEvent) OrderVersion() deliveryModel.OrderVersion {
+	return deliveryModel.OrderVersion{Number: max(e.Version, 0), ChangedAt: e.UpdatedAt}
+}
+
+func messageSource(topic string, partition int32, offset int64) string {
+	return fmt.Sprintf("kafka:%s/%d/%d", topic, partition, offset)
 }
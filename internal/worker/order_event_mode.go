@@ -0,0 +1,30 @@
+package worker
+
+import (
+	"fmt"
+	"strings"
+)
+
+// EventMode — откуда consumer берёт статус заказа
+type EventMode string
+
+const (
+	// EventModeLookup — статус запрашивается у order-сервиса для каждого события
+	EventModeLookup EventMode = "lookup"
+	// EventModeTrust — статус берётся из события; order-сервис спрашивается, только если статуса в событии нет
+	EventModeTrust EventMode = "event"
+	// EventModeVerify — статус из события сверяется с order-сервисом; при его недоступности применяется статус события
+	EventModeVerify EventMode = "event_verify"
+)
+
+// ParseEventMode разбирает режим из конфига; пустое значение — lookup
+func ParseEventMode(raw string) (EventMode, error) {
+	switch m := EventMode(strings.ToLower(strings.TrimSpace(raw))); m {
+	case "":
+		return EventModeLookup, nil
+	case EventModeLookup, EventModeTrust, EventModeVerify:
+		return m, nil
+	default:
+		return "", fmt.Errorf("unknown order event mode %q", raw)
+	}
+}
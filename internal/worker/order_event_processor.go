@@ -3,19 +3,65 @@ package worker
 import (
 	"context"
 	"errors"
-	"strings"
+	"time"
 
 	"github.com/Avito-courses/course-go-avito-israpilovsha/internal/delivery/model"
 	deliveryRepo "github.com/Avito-courses/course-go-avito-israpilovsha/internal/delivery/repository"
 	deliveryUsecase "github.com/Avito-courses/course-go-avito-israpilovsha/internal/delivery/usecase"
 	"github.com/Avito-courses/course-go-avito-israpilovsha/internal/gateway/order"
+	"github.com/Avito-courses/course-go-avito-israpilovsha/internal/metrics"
+	"go.uber.org/zap"
 )
 
+// processedCleanupEvery — как часто удалять старые ключи обработанных событий
+const processedCleanupEvery = time.Hour
+
 type OrderEventProcessor struct {
-	assign   *deliveryUsecase.DeliveryService
-	unassign *deliveryUsecase.DeliveryService
-	complete *deliveryUsecase.CompleteService
-	orders   order.Gateway
+	assign    *deliveryUsecase.DeliveryService
+	unassign  *deliveryUsecase.DeliveryService
+	complete  *deliveryUsecase.CompleteService
+	orders    order.Gateway
+	processed deliveryRepo.ProcessedEventRepository
+	versions  deliveryRepo.OrderVersionRepository
+	mode      EventMode
+	log       *zap.SugaredLogger
+	nowFunc   func() time.Time
+}
+
+// statusInvalidator — gateway с кэшем статусов (order.CachingGateway)
+type statusInvalidator interface {
+	Invalidate(orderID string)
+}
+
+type ProcessorOption func(*OrderEventProcessor)
+
+// WithProcessedEvents включает дедупликацию: событие применяется в одной транзакции
+// с записью его ключа, повторно доставленное событие пропускается
+func WithProcessedEvents(repo deliveryRepo.ProcessedEventRepository) ProcessorOption {
+	return func(p *OrderEventProcessor) {
+		p.processed = repo
+	}
+}
+
+// WithEventMode задаёт источник статуса заказа; по умолчанию lookup
+func WithEventMode(mode EventMode) ProcessorOption {
+	return func(p *OrderEventProcessor) {
+		p.mode = mode
+	}
+}
+
+// WithOrderVersions включает отбрасывание событий со старой версией заказа
+// в режимах event и event_verify
+func WithOrderVersions(repo deliveryRepo.OrderVersionRepository) ProcessorOption {
+	return func(p *OrderEventProcessor) {
+		p.versions = repo
+	}
+}
+
+func WithProcessorLogger(log *zap.SugaredLogger) ProcessorOption {
+	return func(p *OrderEventProcessor) {
+		p.log = log
+	}
 }
 
 func NewOrderEventProcessor(
@@ -23,55 +69,203 @@ func NewOrderEventProcessor(
 	unassign *deliveryUsecase.DeliveryService,
 	complete *deliveryUsecase.CompleteService,
 	orders order.Gateway,
+	opts ...ProcessorOption,
 ) *OrderEventProcessor {
-	return &OrderEventProcessor{
+	p := &OrderEventProcessor{
 		assign:   assign,
 		unassign: unassign,
 		complete: complete,
 		orders:   orders,
+		mode:     EventModeLookup,
+		log:      zap.NewNop().Sugar(),
+		nowFunc:  time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(p)
 	}
+
+	return p
 }
 
-func (p *OrderEventProcessor) Process(ctx context.Context, ev OrderEvent) error {
-	rawStatus, err := p.orders.GetStatus(ctx, ev.OrderID)
+func (p *OrderEventProcessor) Process(ctx context.Context, ev OrderEvent) (Action, error) {
+	status, ok, err := p.resolveStatus(ctx, ev)
 	if err != nil {
-		// order-service: заказ может отсутствовать (дубли, out-of-order, in-memory, удалён)
-		if isOrderNotFound(err) {
-			return nil
+		return ActionFailed, err
+	}
+	if !ok {
+		return ActionSkipped, nil
+	}
+
+	action := ActionSkipped
+	// запрос к order-сервису остаётся вне транзакции, чтобы не держать её на время HTTP-вызова
+	err = p.withTx(ctx, func(txCtx context.Context) error {
+		if key := ev.DedupKey(); p.processed != nil && key != "" {
+			fresh, err := p.processed.MarkProcessed(txCtx, key, p.nowFunc())
+			if err != nil {
+				return err
+			}
+			if !fresh {
+				metrics.OrderEventsDuplicateTotal.Inc()
+				p.log.Infow("duplicate order event skipped", "order_id", ev.OrderID, "key", key)
+				return nil
+			}
+		}
+
+		if version := ev.OrderVersion(); p.mode != EventModeLookup && p.versions != nil && !version.IsZero() {
+			newer, err := p.versions.Advance(txCtx, ev.OrderID, version, p.nowFunc())
+			if err != nil {
+				return err
+			}
+			if !newer {
+				metrics.OrderEventsStaleTotal.Inc()
+				p.log.Infow("stale order event skipped",
+					"order_id", ev.OrderID,
+					"version", version.Number,
+					"changed_at", version.ChangedAt,
+				)
+				return nil
+			}
 		}
+
+		var err error
+		action, err = p.apply(txCtx, ev.OrderID, status)
 		return err
+	})
+	if err != nil {
+		return ActionFailed, err
 	}
 
-	status := model.ParseOrderStatus(rawStatus)
+	// следующее событие заказа означает смену статуса — кэшированный статус уже устарел.
+	// При ошибке кэш сохраняется, чтобы повторы не ходили в order-сервис.
+	if inv, ok := p.orders.(statusInvalidator); ok {
+		inv.Invalidate(ev.OrderID)
+	}
+	return action, nil
+}
+
+// resolveStatus определяет статус заказа по режиму обработки.
+// ok=false — событие применять не нужно (заказа нет в order-сервисе).
+func (p *OrderEventProcessor) resolveStatus(ctx context.Context, ev OrderEvent) (model.OrderStatus, bool, error) {
+	if p.mode == EventModeLookup || ev.Status == "" {
+		return p.lookupStatus(ctx, ev.OrderID)
+	}
+
+	status := model.ParseOrderStatus(ev.Status)
+	if p.mode != EventModeVerify {
+		return status, true, nil
+	}
+
+	actual, ok, err := p.lookupStatus(ctx, ev.OrderID)
+	switch {
+	case err != nil:
+		// order-сервис недоступен — не останавливаем consumer, доверяем событию
+		metrics.OrderStatusVerifyFailuresTotal.Inc()
+		p.log.Warnw("order status verification failed, using event status",
+			"order_id", ev.OrderID,
+			"status", status,
+			"err", err,
+		)
+		return status, true, nil
+	case !ok:
+		return "", false, nil
+	case actual != status:
+		p.log.Warnw("order event status differs from order service",
+			"order_id", ev.OrderID,
+			"event_status", status,
+			"actual_status", actual,
+		)
+		return actual, true, nil
+	default:
+		return status, true, nil
+	}
+}
+
+func (p *OrderEventProcessor) lookupStatus(ctx context.Context, orderID string) (model.OrderStatus, bool, error) {
+	rawStatus, err := p.orders.GetStatus(ctx, orderID)
+	switch {
+	case errors.Is(err, order.ErrOrderNotFound):
+		// order-service: заказ может отсутствовать (дубли, out-of-order, in-memory, удалён)
+		return "", false, nil
+	case errors.Is(err, order.ErrUnauthorized):
+		// повторы не помогут, пока не исправлены учётные данные сервиса
+		p.log.Errorw("order service rejected credentials", "order_id", orderID, "err", err)
+		return "", false, err
+	case err != nil:
+		return "", false, err
+	}
+
+	return model.ParseOrderStatus(rawStatus), true, nil
+}
 
+// withTx объединяет дедупликацию, проверку версии и изменение доставки в одну транзакцию
+func (p *OrderEventProcessor) withTx(ctx context.Context, fn func(txCtx context.Context) error) error {
+	switch {
+	case p.processed != nil:
+		return p.processed.WithTx(ctx, fn)
+	case p.versions != nil:
+		return p.versions.WithTx(ctx, fn)
+	default:
+		return fn(ctx)
+	}
+}
+
+// apply переводит доставку заказа в соответствии со статусом заказа
+func (p *OrderEventProcessor) apply(ctx context.Context, orderID string, status model.OrderStatus) (Action, error) {
 	switch status {
 	case model.OrderStatusCreated:
-		_, _, err := p.assign.Assign(ctx, ev.OrderID)
-		return err
+		_, _, err := p.assign.Assign(ctx, orderID)
+		if err != nil && errors.Is(err, model.ErrOrderQueued) {
+			// заказ дождётся курьера в очереди
+			return ActionAssign, nil
+		}
+		return ActionAssign, err
 
 	case model.OrderStatusCancelled:
-		_, err := p.unassign.Unassign(ctx, ev.OrderID)
+		_, err := p.unassign.Unassign(ctx, orderID)
 		if err != nil && errors.Is(err, deliveryRepo.ErrNotFound) {
-			return nil
+			return ActionIgnored, nil
 		}
-		return err
+		if err != nil && errors.Is(err, model.ErrOrderDequeued) {
+			// заказ ждал курьера в очереди и снят с неё
+			return ActionUnassign, nil
+		}
+		return ActionUnassign, err
 
 	case model.OrderStatusCompleted:
-		err := p.complete.Complete(ctx, ev.OrderID)
+		err := p.complete.Complete(ctx, orderID)
 		if err != nil && errors.Is(err, deliveryRepo.ErrNotFound) {
-			return nil
+			return ActionIgnored, nil
 		}
-		return err
+		return ActionComplete, err
 
 	default:
-		return nil
+		return ActionIgnored, nil
 	}
 }
 
-func isOrderNotFound(err error) bool {
-	if err == nil {
-		return false
+// StartCleanup — фоновая задача: удаляет ключи обработанных событий старше retention
+func (p *OrderEventProcessor) StartCleanup(ctx context.Context, retention time.Duration) {
+	if p.processed == nil || retention <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(processedCleanupEvery)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := p.processed.DeleteBefore(ctx, p.nowFunc().Add(-retention))
+			if err != nil {
+				p.log.Warnw("processed events cleanup failed", "err", err)
+				continue
+			}
+			if deleted > 0 {
+				p.log.Infow("processed events cleaned up", "deleted", deleted)
+			}
+		}
 	}
-	s := err.Error()
-	return strings.Contains(s, "status 404") || strings.Contains(s, "not_found")
 }
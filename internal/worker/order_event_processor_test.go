@@ -0,0 +1,179 @@
+package worker_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+
+	courierMock "github.com/Avito-courses/course-go-avito-israpilovsha/internal/courier/repository"
+	deliveryMock "github.com/Avito-courses/course-go-avito-israpilovsha/internal/delivery/repository"
+	deliveryUsecase "github.com/Avito-courses/course-go-avito-israpilovsha/internal/delivery/usecase"
+	"github.com/Avito-courses/course-go-avito-israpilovsha/internal/gateway/order"
+	"github.com/Avito-courses/course-go-avito-israpilovsha/internal/worker"
+)
+
+type stubGateway struct {
+	status string
+	err    error
+	calls  int
+}
+
+func (g *stubGateway) FetchOrders(context.Context, time.Time, string) (order.OrdersPage, error) {
+	return order.OrdersPage{}, nil
+}
+
+func (g *stubGateway) GetStatus(context.Context, string) (string, error) {
+	g.calls++
+	return g.status, g.err
+}
+
+func (g *stubGateway) GetStatuses(ctx context.Context, ids []string) (map[string]order.StatusResult, error) {
+	results := make(map[string]order.StatusResult, len(ids))
+	for _, id := range ids {
+		status, err := g.GetStatus(ctx, id)
+		results[id] = order.StatusResult{Status: status, Err: err}
+	}
+	return results, nil
+}
+
+func runInTx(ctx context.Context, fn func(context.Context) error) error { return fn(ctx) }
+
+func TestOrderEventProcessorModes(t *testing.T) {
+	tests := []struct {
+		name       string
+		mode       worker.EventMode
+		gateway    *stubGateway
+		ev         worker.OrderEvent
+		newer      bool
+		wantCancel bool
+		wantCalls  int
+		wantAction worker.Action
+	}{
+		{
+			name:       "lookup mode uses order service status",
+			mode:       worker.EventModeLookup,
+			gateway:    &stubGateway{status: "cancelled"},
+			ev:         worker.OrderEvent{OrderID: "o-1", Status: "created", Version: 3},
+			wantCancel: true,
+			wantCalls:  1,
+			wantAction: worker.ActionIgnored,
+		},
+		{
+			name:       "event mode does not call order service",
+			mode:       worker.EventModeTrust,
+			gateway:    &stubGateway{err: errors.New("order service down")},
+			ev:         worker.OrderEvent{OrderID: "o-1", Status: "cancelled", Version: 3},
+			newer:      true,
+			wantCancel: true,
+			wantAction: worker.ActionIgnored,
+		},
+		{
+			name:       "event mode skips stale version",
+			mode:       worker.EventModeTrust,
+			gateway:    &stubGateway{},
+			ev:         worker.OrderEvent{OrderID: "o-1", Status: "cancelled", Version: 2},
+			wantAction: worker.ActionSkipped,
+		},
+		{
+			name:       "event mode compares updated_at without version",
+			mode:       worker.EventModeTrust,
+			gateway:    &stubGateway{},
+			ev:         worker.OrderEvent{OrderID: "o-1", Status: "cancelled", UpdatedAt: time.Unix(1700000000, 0)},
+			newer:      true,
+			wantCancel: true,
+			wantAction: worker.ActionIgnored,
+		},
+		{
+			name:       "event mode falls back to lookup without status",
+			mode:       worker.EventModeTrust,
+			gateway:    &stubGateway{status: "cancelled"},
+			ev:         worker.OrderEvent{OrderID: "o-1", Version: 3},
+			newer:      true,
+			wantCancel: true,
+			wantCalls:  1,
+			wantAction: worker.ActionIgnored,
+		},
+		{
+			name:       "verify mode trusts event when order service fails",
+			mode:       worker.EventModeVerify,
+			gateway:    &stubGateway{err: errors.New("order service down")},
+			ev:         worker.OrderEvent{OrderID: "o-1", Status: "cancelled", Version: 3},
+			newer:      true,
+			wantCancel: true,
+			wantCalls:  1,
+			wantAction: worker.ActionIgnored,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+
+			cRepo := courierMock.NewMockCourierRepository(ctrl)
+			dRepo := deliveryMock.NewMockDeliveryRepository(ctrl)
+			versions := deliveryMock.NewMockOrderVersionRepository(ctrl)
+
+			versions.EXPECT().WithTx(gomock.Any(), gomock.Any()).DoAndReturn(runInTx)
+			if tt.mode != worker.EventModeLookup {
+				versions.EXPECT().Advance(gomock.Any(), "o-1", tt.ev.OrderVersion(), gomock.Any()).Return(tt.newer, nil)
+			}
+			if tt.wantCancel {
+				// активной доставки нет — отмена завершается без изменений
+				dRepo.EXPECT().WithTx(gomock.Any(), gomock.Any()).DoAndReturn(runInTx)
+				dRepo.EXPECT().GetActiveByOrderID(gomock.Any(), "o-1").Return(nil, deliveryMock.ErrNotFound)
+			}
+
+			svc := deliveryUsecase.NewDeliveryService(cRepo, dRepo)
+			complete := deliveryUsecase.NewCompleteService(dRepo, cRepo)
+			p := worker.NewOrderEventProcessor(svc, svc, complete, tt.gateway,
+				worker.WithEventMode(tt.mode),
+				worker.WithOrderVersions(versions),
+			)
+
+			action, err := p.Process(context.Background(), tt.ev)
+			if err != nil {
+				t.Fatalf("Process() error = %v", err)
+			}
+			if action != tt.wantAction {
+				t.Fatalf("Process() action = %q, want %q", action, tt.wantAction)
+			}
+			if tt.gateway.calls != tt.wantCalls {
+				t.Fatalf("order service calls = %d, want %d", tt.gateway.calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestOrderEventProcessorSkipsUnknownOrder(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	// заказа нет в order-сервисе — ни транзакции, ни изменений доставки
+	dRepo := deliveryMock.NewMockDeliveryRepository(ctrl)
+	svc := deliveryUsecase.NewDeliveryService(courierMock.NewMockCourierRepository(ctrl), dRepo)
+	gateway := &stubGateway{err: fmt.Errorf("get status: %w", &order.StatusError{Code: 404})}
+
+	p := worker.NewOrderEventProcessor(svc, svc, deliveryUsecase.NewCompleteService(dRepo, nil), gateway)
+
+	action, err := p.Process(context.Background(), worker.OrderEvent{OrderID: "o-1", Status: "created"})
+	if err != nil || action != worker.ActionSkipped {
+		t.Fatalf("Process() = %q, %v; want skipped without error", action, err)
+	}
+
+	gateway.err = &order.StatusError{Code: 503}
+	if _, err := p.Process(context.Background(), worker.OrderEvent{OrderID: "o-1"}); !errors.Is(err, order.ErrUpstreamUnavailable) {
+		t.Fatalf("expected ErrUpstreamUnavailable, got %v", err)
+	}
+}
+
+func TestParseEventMode(t *testing.T) {
+	if m, err := worker.ParseEventMode(""); err != nil || m != worker.EventModeLookup {
+		t.Fatalf("ParseEventMode(\"\") = %q, %v", m, err)
+	}
+	if _, err := worker.ParseEventMode("guess"); err == nil {
+		t.Fatal("expected error for unknown mode")
+	}
+}
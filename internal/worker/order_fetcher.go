@@ -2,13 +2,29 @@ package worker
 
 import (
 	"context"
+	"errors"
+	"sort"
+	"strings"
 	"time"
 
+	"github.com/jackc/pgx/v5/pgconn"
 	"go.uber.org/zap"
 
 	courierModel "github.com/Avito-courses/course-go-avito-israpilovsha/internal/courier/model"
 	deliveryModel "github.com/Avito-courses/course-go-avito-israpilovsha/internal/delivery/model"
+	deliveryRepo "github.com/Avito-courses/course-go-avito-israpilovsha/internal/delivery/repository"
 	orderGateway "github.com/Avito-courses/course-go-avito-israpilovsha/internal/gateway/order"
+	"github.com/Avito-courses/course-go-avito-israpilovsha/internal/metrics"
+)
+
+const (
+	defaultFetchPeriod = 5 * time.Second
+	fetchCursorName    = "orders"
+	// fetchOverlap — насколько раньше курсора запрашиваются заказы. FetchOrders отдаёт заказы,
+	// созданные строго после from, а заказы с временем курсора, которых нет в его OrderIDs,
+	// ещё не обработаны; запас покрывает и округление from на стороне order-сервиса.
+	// Повторно полученные заказы отбрасывает FetchCursor.Seen
+	fetchOverlap = time.Millisecond
 )
 
 type deliveryAssigner interface {
@@ -22,31 +38,76 @@ type deliveryAssigner interface {
 	)
 }
 
+// OrderFetcher опрашивает order-сервис и назначает новые заказы — запасной путь, когда Kafka недоступна
 type OrderFetcher struct {
-	gw     orderGateway.Gateway
-	svc    deliveryAssigner
-	log    *zap.SugaredLogger
-	period time.Duration
+	gw      orderGateway.Gateway
+	svc     deliveryAssigner
+	log     *zap.SugaredLogger
+	period  time.Duration
+	cursors deliveryRepo.FetchCursorRepository
+	// active решает, опрашивать ли order-сервис на этом тике; nil — всегда
+	active   func() bool
+	lookback time.Duration
+	nowFunc  func() time.Time
+
+	cursor *deliveryModel.FetchCursor
+}
+
+type FetcherOption func(*OrderFetcher)
+
+func WithFetchPeriod(period time.Duration) FetcherOption {
+	return func(p *OrderFetcher) {
+		if period > 0 {
+			p.period = period
+		}
+	}
+}
+
+// WithFetchCursor хранит курсор в репозитории, чтобы после перезапуска опрос продолжился с того же места
+func WithFetchCursor(repo deliveryRepo.FetchCursorRepository) FetcherOption {
+	return func(p *OrderFetcher) {
+		p.cursors = repo
+	}
+}
+
+// WithFetchCondition включает опрос только пока active возвращает true.
+// При каждом включении опрос продолжается с сохранённого курсора; если курсора нет
+// или он позже текущего времени за вычетом lookback, курсор отодвигается к этой границе,
+// чтобы не пропустить заказы, которые основной путь мог не успеть обработать.
+func WithFetchCondition(active func() bool, lookback time.Duration) FetcherOption {
+	return func(p *OrderFetcher) {
+		p.active = active
+		p.lookback = lookback
+	}
 }
 
 func NewOrderFetcher(
 	gw orderGateway.Gateway,
 	svc deliveryAssigner,
 	log *zap.SugaredLogger,
+	opts ...FetcherOption,
 ) *OrderFetcher {
-	return &OrderFetcher{
-		gw:     gw,
-		svc:    svc,
-		log:    log,
-		period: 5 * time.Second,
+	p := &OrderFetcher{
+		gw:      gw,
+		svc:     svc,
+		log:     log,
+		period:  defaultFetchPeriod,
+		nowFunc: time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(p)
 	}
+
+	return p
 }
 
+// Run опрашивает order-сервис раз в period до отмены ctx
 func (p *OrderFetcher) Run(ctx context.Context) {
 	ticker := time.NewTicker(p.period)
 	defer ticker.Stop()
 
-	cursor := time.Now().Add(-p.period).UTC()
+	wasActive := false
 
 	for {
 		select {
@@ -55,29 +116,174 @@ func (p *OrderFetcher) Run(ctx context.Context) {
 			return
 
 		case <-ticker.C:
-			orders, err := p.gw.FetchOrders(ctx, cursor)
-			if err != nil {
-				p.log.Warnw("fetch orders failed", "err", err)
-				continue
+			if p.active != nil {
+				active := p.active()
+				if active != wasActive {
+					p.log.Infow("order poller switched", "active", active)
+				}
+				if active && !wasActive {
+					if err := p.resetCursor(ctx); err != nil {
+						// включение повторится на следующем тике
+						metrics.OrderFetchErrorsTotal.Inc()
+						p.log.Warnw("load fetch cursor failed", "err", err)
+						continue
+					}
+				}
+				wasActive = active
+				if !active {
+					continue
+				}
 			}
 
-			if len(orders) == 0 {
-				cursor = time.Now().Add(-p.period).UTC()
-				continue
+			if err := p.FetchOnce(ctx); err != nil && ctx.Err() == nil {
+				metrics.OrderFetchErrorsTotal.Inc()
+				p.log.Warnw("fetch orders failed", "err", err)
 			}
+		}
+	}
+}
 
-			maxCreated := cursor
-			for _, o := range orders {
-				if o.CreatedAt.After(maxCreated) {
-					maxCreated = o.CreatedAt
-				}
+// FetchOnce забирает заказы, созданные после курсора, страницами, назначает их и сохраняет
+// курсор после каждой страницы: ошибка на следующей странице не теряет уже обработанные.
+// На первой временной ошибке назначения разбор останавливается: курсор сохраняется до упавшего
+// заказа, и следующий опрос начнёт с него. Заказ с постоянной ошибкой пропускается и учитывается
+// в courier_order_fetcher_skipped_total. Все ошибки учитываются в courier_order_fetcher_assign_failures_total.
+func (p *OrderFetcher) FetchOnce(ctx context.Context) error {
+	cursor, err := p.loadCursor(ctx)
+	if err != nil {
+		return err
+	}
 
-				if _, _, err := p.svc.Assign(ctx, o.ID); err != nil {
-					p.log.Warnw("assign failed", "order_id", o.ID, "err", err)
-				}
+	from, pageCursor := cursor.CreatedAt.Add(-fetchOverlap), ""
+	for {
+		page, err := p.gw.FetchOrders(ctx, from, pageCursor)
+		if err != nil {
+			return err
+		}
+
+		done, err := p.assignPage(ctx, page.Orders)
+		if err != nil || done || page.Next == "" {
+			return err
+		}
+		pageCursor = page.Next
+	}
+}
+
+// assignPage назначает заказы страницы и сохраняет курсор; done — разбор остановлен на ошибке назначения
+func (p *OrderFetcher) assignPage(ctx context.Context, orders []orderGateway.Order) (done bool, err error) {
+	cursor := p.cursor
+
+	// курсор двигается по времени создания, поэтому заказы обрабатываются в том же порядке
+	sort.SliceStable(orders, func(i, j int) bool {
+		if !orders[i].CreatedAt.Equal(orders[j].CreatedAt) {
+			return orders[i].CreatedAt.Before(orders[j].CreatedAt)
+		}
+		return orders[i].ID < orders[j].ID
+	})
+
+	next := &deliveryModel.FetchCursor{
+		CreatedAt: cursor.CreatedAt,
+		OrderIDs:  append([]string(nil), cursor.OrderIDs...),
+	}
+
+	fetched, advanced := 0, 0
+	for _, o := range orders {
+		if cursor.Seen(o.ID, o.CreatedAt) {
+			continue
+		}
+		fetched++
+
+		if _, _, err := p.svc.Assign(ctx, o.ID); err != nil && !errors.Is(err, deliveryModel.ErrOrderQueued) {
+			if ctx.Err() != nil {
+				done = true
+				break
 			}
+			metrics.OrderFetchAssignFailuresTotal.Inc()
+
+			if !permanentAssignError(err) {
+				p.log.Warnw("assign failed, fetch stopped", "order_id", o.ID, "err", err)
+				done = true
+				break
+			}
+			// повтор не поможет — пропускаем заказ, чтобы он не держал опрос на месте
+			metrics.OrderFetchSkippedTotal.Inc()
+			p.log.Errorw("assign failed permanently, order skipped", "order_id", o.ID, "err", err)
+		}
+
+		next.Advance(o.ID, o.CreatedAt)
+		advanced++
+	}
+
+	metrics.OrdersFetchedTotal.Add(float64(fetched))
+
+	if advanced == 0 {
+		return done, nil
+	}
+	return done, p.saveCursor(ctx, next)
+}
 
-			cursor = maxCreated.Add(time.Nanosecond)
+// permanentAssignError — ошибка назначения, которую повтор того же заказа не исправит:
+// заказа нет в order-сервисе, недопустимый переход статуса или данные, отвергнутые БД.
+// Остальные ошибки (order-сервис, соединение с БД, отмена контекста) считаются временными.
+func permanentAssignError(err error) bool {
+	switch {
+	case errors.Is(err, orderGateway.ErrOrderNotFound),
+		errors.Is(err, deliveryModel.ErrInvalidStatus),
+		errors.Is(err, deliveryModel.ErrInvalidTransition),
+		errors.Is(err, courierModel.ErrInvalidStatus),
+		errors.Is(err, courierModel.ErrInvalidTransition):
+		return true
+	}
+
+	// классы SQLSTATE 22 (некорректные данные) и 23 (нарушение ограничений)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return strings.HasPrefix(pgErr.Code, "22") || strings.HasPrefix(pgErr.Code, "23")
+	}
+	return false
+}
+
+func (p *OrderFetcher) loadCursor(ctx context.Context) (*deliveryModel.FetchCursor, error) {
+	if p.cursor != nil {
+		return p.cursor, nil
+	}
+
+	if p.cursors != nil {
+		c, err := p.cursors.Get(ctx, fetchCursorName)
+		switch {
+		case err == nil:
+			p.cursor = c
+			return c, nil
+		case !errors.Is(err, deliveryRepo.ErrNotFound):
+			return nil, err
 		}
 	}
+
+	p.cursor = &deliveryModel.FetchCursor{CreatedAt: p.nowFunc().Add(-p.period).UTC()}
+	return p.cursor, nil
+}
+
+func (p *OrderFetcher) saveCursor(ctx context.Context, c *deliveryModel.FetchCursor) error {
+	if p.cursors != nil {
+		if err := p.cursors.Save(ctx, fetchCursorName, c); err != nil {
+			return err
+		}
+	}
+	p.cursor = c
+	return nil
+}
+
+// resetCursor перечитывает курсор при включении опроса и не даёт ему оказаться позже now-lookback
+func (p *OrderFetcher) resetCursor(ctx context.Context) error {
+	p.cursor = nil
+	c, err := p.loadCursor(ctx)
+	if err != nil {
+		return err
+	}
+
+	floor := p.nowFunc().Add(-p.lookback - p.period).UTC()
+	if c.CreatedAt.After(floor) {
+		p.cursor = &deliveryModel.FetchCursor{CreatedAt: floor}
+	}
+	return nil
 }
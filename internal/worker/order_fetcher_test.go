@@ -0,0 +1,351 @@
+package worker_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
+
+	courierModel "github.com/Avito-courses/course-go-avito-israpilovsha/internal/courier/model"
+	deliveryModel "github.com/Avito-courses/course-go-avito-israpilovsha/internal/delivery/model"
+	deliveryMock "github.com/Avito-courses/course-go-avito-israpilovsha/internal/delivery/repository"
+	"github.com/Avito-courses/course-go-avito-israpilovsha/internal/gateway/order"
+	"github.com/Avito-courses/course-go-avito-israpilovsha/internal/worker"
+)
+
+// ordersGateway, как и order-сервис, отдаёт из orders только заказы, созданные строго после from
+type ordersGateway struct {
+	stubGateway
+	orders []order.Order
+	// pages — страницы по курсору вместо orders; errs — ошибки страниц по курсору
+	pages map[string]order.OrdersPage
+	errs  map[string]error
+	from  time.Time
+}
+
+func (g *ordersGateway) FetchOrders(_ context.Context, from time.Time, cursor string) (order.OrdersPage, error) {
+	g.from = from
+	if err := g.errs[cursor]; err != nil {
+		return order.OrdersPage{}, err
+	}
+	if g.pages != nil {
+		return g.pages[cursor], nil
+	}
+	var orders []order.Order
+	for _, o := range g.orders {
+		if o.CreatedAt.After(from) {
+			orders = append(orders, o)
+		}
+	}
+	return order.OrdersPage{Orders: orders}, nil
+}
+
+type recordingAssigner struct {
+	assigned []string
+	fail     map[string]error
+}
+
+func (a *recordingAssigner) Assign(
+	_ context.Context,
+	orderID string,
+) (*deliveryModel.Delivery, *courierModel.Courier, error) {
+	a.assigned = append(a.assigned, orderID)
+	return nil, nil, a.fail[orderID]
+}
+
+func TestOrderFetcherResumesFromStoredCursor(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	cursors := deliveryMock.NewMockFetchCursorRepository(ctrl)
+
+	t0 := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	t1 := t0.Add(time.Minute)
+
+	cursors.EXPECT().Get(gomock.Any(), "orders").
+		Return(&deliveryModel.FetchCursor{CreatedAt: t0, OrderIDs: []string{"a"}}, nil)
+
+	var saved []*deliveryModel.FetchCursor
+	cursors.EXPECT().Save(gomock.Any(), "orders", gomock.Any()).Times(2).
+		DoAndReturn(func(_ context.Context, _ string, c *deliveryModel.FetchCursor) error {
+			saved = append(saved, c)
+			return nil
+		})
+
+	gw := &ordersGateway{orders: []order.Order{
+		{ID: "d", CreatedAt: t1},
+		{ID: "a", CreatedAt: t0}, // уже обработан до перезапуска
+		{ID: "b", CreatedAt: t0},
+		{ID: "c", CreatedAt: t1},
+	}}
+	assigner := &recordingAssigner{fail: map[string]error{
+		"b": deliveryModel.ErrOrderQueued,
+		"c": errors.New("db down"),
+	}}
+
+	f := worker.NewOrderFetcher(gw, assigner, zap.NewNop().Sugar(), worker.WithFetchCursor(cursors))
+	if err := f.FetchOnce(context.Background()); err != nil {
+		t.Fatalf("FetchOnce() error = %v", err)
+	}
+
+	if !gw.from.Before(t0) || gw.from.Before(t0.Add(-time.Second)) {
+		t.Fatalf("fetched from %v, want just before %v", gw.from, t0)
+	}
+	// после ошибки на c разбор останавливается, курсор не уходит дальше b
+	if !slices.Equal(assigner.assigned, []string{"b", "c"}) {
+		t.Fatalf("assigned %v, want [b c]", assigner.assigned)
+	}
+	if len(saved) != 1 || !saved[0].CreatedAt.Equal(t0) || !slices.Equal(saved[0].OrderIDs, []string{"a", "b"}) {
+		t.Fatalf("unexpected cursor %+v", saved)
+	}
+
+	// следующий опрос повторяет упавший заказ
+	assigner.assigned = nil
+	delete(assigner.fail, "c")
+	if err := f.FetchOnce(context.Background()); err != nil {
+		t.Fatalf("FetchOnce() error = %v", err)
+	}
+	if !slices.Equal(assigner.assigned, []string{"c", "d"}) {
+		t.Fatalf("assigned %v, want [c d]", assigner.assigned)
+	}
+	if len(saved) != 2 || !saved[1].CreatedAt.Equal(t1) || !slices.Equal(saved[1].OrderIDs, []string{"c", "d"}) {
+		t.Fatalf("unexpected cursor %+v", saved)
+	}
+
+	// третий опрос с тем же ответом ничего не назначает и курсор не сохраняет
+	assigner.assigned = nil
+	if err := f.FetchOnce(context.Background()); err != nil {
+		t.Fatalf("FetchOnce() error = %v", err)
+	}
+	if len(assigner.assigned) != 0 {
+		t.Fatalf("expected no assignments, got %v", assigner.assigned)
+	}
+}
+
+// TestOrderFetcherRetriesOrderAtCursorTime — заказ с тем же временем создания, что и курсор,
+// после временной ошибки запрашивается снова
+func TestOrderFetcherRetriesOrderAtCursorTime(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	cursors := deliveryMock.NewMockFetchCursorRepository(ctrl)
+
+	t0 := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	cursors.EXPECT().Get(gomock.Any(), "orders").
+		Return(&deliveryModel.FetchCursor{CreatedAt: t0.Add(-time.Minute)}, nil)
+	cursors.EXPECT().Save(gomock.Any(), "orders", gomock.Any()).Times(2).Return(nil)
+
+	gw := &ordersGateway{orders: []order.Order{
+		{ID: "a", CreatedAt: t0},
+		{ID: "b", CreatedAt: t0},
+		{ID: "c", CreatedAt: t0},
+	}}
+	assigner := &recordingAssigner{fail: map[string]error{"b": errors.New("db down")}}
+
+	f := worker.NewOrderFetcher(gw, assigner, zap.NewNop().Sugar(), worker.WithFetchCursor(cursors))
+	if err := f.FetchOnce(context.Background()); err != nil {
+		t.Fatalf("FetchOnce() error = %v", err)
+	}
+	if !slices.Equal(assigner.assigned, []string{"a", "b"}) {
+		t.Fatalf("assigned %v, want [a b]", assigner.assigned)
+	}
+
+	// курсор теперь на t0 с заказом a; b и c созданы в то же время и не должны потеряться
+	assigner.assigned = nil
+	delete(assigner.fail, "b")
+	if err := f.FetchOnce(context.Background()); err != nil {
+		t.Fatalf("FetchOnce() error = %v", err)
+	}
+	if !slices.Equal(assigner.assigned, []string{"b", "c"}) {
+		t.Fatalf("assigned %v, want [b c]", assigner.assigned)
+	}
+}
+
+// TestOrderFetcherSavesCursorPerPage — ошибка на второй странице не теряет первую
+func TestOrderFetcherSavesCursorPerPage(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	cursors := deliveryMock.NewMockFetchCursorRepository(ctrl)
+
+	t0 := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	cursors.EXPECT().Get(gomock.Any(), "orders").Return(&deliveryModel.FetchCursor{CreatedAt: t0}, nil)
+
+	var saved []*deliveryModel.FetchCursor
+	cursors.EXPECT().Save(gomock.Any(), "orders", gomock.Any()).Times(2).
+		DoAndReturn(func(_ context.Context, _ string, c *deliveryModel.FetchCursor) error {
+			saved = append(saved, c)
+			return nil
+		})
+
+	gw := &ordersGateway{
+		pages: map[string]order.OrdersPage{
+			"":   {Orders: []order.Order{{ID: "a", CreatedAt: t0.Add(time.Second)}}, Next: "p2"},
+			"p2": {Orders: []order.Order{{ID: "b", CreatedAt: t0.Add(2 * time.Second)}}},
+		},
+		errs: map[string]error{"p2": order.ErrUpstreamUnavailable},
+	}
+	assigner := &recordingAssigner{}
+
+	f := worker.NewOrderFetcher(gw, assigner, zap.NewNop().Sugar(), worker.WithFetchCursor(cursors))
+	if err := f.FetchOnce(context.Background()); !errors.Is(err, order.ErrUpstreamUnavailable) {
+		t.Fatalf("FetchOnce() error = %v, want ErrUpstreamUnavailable", err)
+	}
+	if len(saved) != 1 || !saved[0].CreatedAt.Equal(t0.Add(time.Second)) {
+		t.Fatalf("first page cursor not saved: %+v", saved)
+	}
+
+	// следующий опрос начинает после первой страницы и не назначает a повторно
+	delete(gw.errs, "p2")
+	assigner.assigned = nil
+	if err := f.FetchOnce(context.Background()); err != nil {
+		t.Fatalf("FetchOnce() error = %v", err)
+	}
+	if from := t0.Add(time.Second); !gw.from.Before(from) || gw.from.Before(from.Add(-time.Second)) {
+		t.Fatalf("fetched from %v, want just before %v", gw.from, from)
+	}
+	if !slices.Equal(assigner.assigned, []string{"b"}) {
+		t.Fatalf("assigned %v, want [b]", assigner.assigned)
+	}
+	if len(saved) != 2 || !saved[1].CreatedAt.Equal(t0.Add(2*time.Second)) {
+		t.Fatalf("unexpected cursor %+v", saved)
+	}
+}
+
+// TestOrderFetcherSkipsPermanentFailure — заказ, который нельзя назначить никогда,
+// не держит курсор: его пропускают и разбор идёт дальше
+func TestOrderFetcherSkipsPermanentFailure(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	cursors := deliveryMock.NewMockFetchCursorRepository(ctrl)
+
+	t0 := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	cursors.EXPECT().Get(gomock.Any(), "orders").Return(&deliveryModel.FetchCursor{CreatedAt: t0}, nil)
+
+	var saved []*deliveryModel.FetchCursor
+	cursors.EXPECT().Save(gomock.Any(), "orders", gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ string, c *deliveryModel.FetchCursor) error {
+			saved = append(saved, c)
+			return nil
+		})
+
+	gw := &ordersGateway{orders: []order.Order{
+		{ID: "gone", CreatedAt: t0.Add(time.Second)},
+		{ID: "bad", CreatedAt: t0.Add(2 * time.Second)},
+		{ID: "ok", CreatedAt: t0.Add(3 * time.Second)},
+	}}
+	assigner := &recordingAssigner{fail: map[string]error{
+		"gone": fmt.Errorf("lookup: %w", order.ErrOrderNotFound),
+		"bad":  &pgconn.PgError{Code: "22P02"},
+	}}
+
+	f := worker.NewOrderFetcher(gw, assigner, zap.NewNop().Sugar(), worker.WithFetchCursor(cursors))
+	if err := f.FetchOnce(context.Background()); err != nil {
+		t.Fatalf("FetchOnce() error = %v", err)
+	}
+
+	if !slices.Equal(assigner.assigned, []string{"gone", "bad", "ok"}) {
+		t.Fatalf("assigned %v, want [gone bad ok]", assigner.assigned)
+	}
+	if len(saved) != 1 || !saved[0].CreatedAt.Equal(t0.Add(3*time.Second)) {
+		t.Fatalf("cursor did not move past skipped orders: %+v", saved)
+	}
+
+	// следующий опрос к пропущенным заказам не возвращается
+	assigner.assigned = nil
+	if err := f.FetchOnce(context.Background()); err != nil {
+		t.Fatalf("FetchOnce() error = %v", err)
+	}
+	if len(assigner.assigned) != 0 {
+		t.Fatalf("expected no assignments, got %v", assigner.assigned)
+	}
+}
+
+// signalGateway сообщает, с какого момента запрошены заказы
+type signalGateway struct {
+	stubGateway
+	from chan time.Time
+}
+
+func (g *signalGateway) FetchOrders(_ context.Context, from time.Time, _ string) (order.OrdersPage, error) {
+	select {
+	case g.from <- from:
+	default:
+	}
+	return order.OrdersPage{}, nil
+}
+
+func TestOrderFetcherSwitchOnKeepsStoredCursor(t *testing.T) {
+	const lookback = time.Hour
+	stored := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		cursor *deliveryModel.FetchCursor
+		// check проверяет границу опроса относительно момента, когда опрос уже прошёл
+		check func(t *testing.T, from, polled time.Time)
+	}{
+		{
+			name:   "stored cursor older than lookback",
+			cursor: &deliveryModel.FetchCursor{CreatedAt: stored},
+			check: func(t *testing.T, from, _ time.Time) {
+				if !from.Before(stored) || from.Before(stored.Add(-time.Second)) {
+					t.Fatalf("fetched from %v, want just before stored %v", from, stored)
+				}
+			},
+		},
+		{
+			name:   "stored cursor newer than lookback",
+			cursor: &deliveryModel.FetchCursor{CreatedAt: time.Now().UTC()},
+			check: func(t *testing.T, from, polled time.Time) {
+				if from.After(polled.Add(-lookback)) {
+					t.Fatalf("fetched from %v, want not after %v", from, polled.Add(-lookback))
+				}
+			},
+		},
+		{
+			name: "no stored cursor",
+			check: func(t *testing.T, from, polled time.Time) {
+				if from.After(polled.Add(-lookback)) {
+					t.Fatalf("fetched from %v, want not after %v", from, polled.Add(-lookback))
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			cursors := deliveryMock.NewMockFetchCursorRepository(ctrl)
+			if tt.cursor != nil {
+				cursors.EXPECT().Get(gomock.Any(), "orders").Return(tt.cursor, nil)
+			} else {
+				cursors.EXPECT().Get(gomock.Any(), "orders").Return(nil, deliveryMock.ErrNotFound)
+			}
+
+			gw := &signalGateway{from: make(chan time.Time, 1)}
+			f := worker.NewOrderFetcher(gw, &recordingAssigner{}, zap.NewNop().Sugar(),
+				worker.WithFetchPeriod(time.Millisecond),
+				worker.WithFetchCursor(cursors),
+				worker.WithFetchCondition(func() bool { return true }, lookback),
+			)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				f.Run(ctx)
+			}()
+
+			select {
+			case from := <-gw.from:
+				tt.check(t, from, time.Now())
+			case <-time.After(time.Second):
+				t.Fatal("fetcher did not poll")
+			}
+			cancel()
+			<-done
+		})
+	}
+}
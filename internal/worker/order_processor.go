@@ -2,6 +2,31 @@ package worker
 
 import "context"
 
+// Action — чем закончилась обработка события заказа
+type Action string
+
+const (
+	ActionAssign   Action = "assign"
+	ActionUnassign Action = "unassign"
+	ActionComplete Action = "complete"
+	// ActionIgnored — статус заказа не требует изменений доставки
+	ActionIgnored Action = "ignored"
+	// ActionSkipped — событие не применялось: дубль, устаревшая версия или заказа нет
+	ActionSkipped Action = "skipped"
+	// ActionFailed — обработка не удалась после всех попыток
+	ActionFailed Action = "failed"
+)
+
+// Applied сообщает, изменило ли событие доставку
+func (a Action) Applied() bool {
+	switch a {
+	case ActionAssign, ActionUnassign, ActionComplete:
+		return true
+	default:
+		return false
+	}
+}
+
 type OrderProcessor interface {
-	Process(ctx context.Context, ev OrderEvent) error
+	Process(ctx context.Context, ev OrderEvent) (Action, error)
 }
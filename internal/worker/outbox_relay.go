@@ -0,0 +1,181 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/IBM/sarama"
+	"go.uber.org/zap"
+
+	"github.com/Avito-courses/course-go-avito-israpilovsha/internal/metrics"
+	outboxModel "github.com/Avito-courses/course-go-avito-israpilovsha/internal/outbox/model"
+	outboxRepo "github.com/Avito-courses/course-go-avito-israpilovsha/internal/outbox/repository"
+)
+
+// Заголовки событий outbox
+const (
+	HeaderEventID   = "event-id"
+	HeaderEventType = "event-type"
+)
+
+// cleanupEvery — как часто удалять опубликованные события старше retention
+const cleanupEvery = time.Hour
+
+type OutboxRelayConfig struct {
+	Topic        string
+	BatchSize    int
+	PollInterval time.Duration
+	// Retention — сколько хранить опубликованные события; 0 — не удалять
+	Retention time.Duration
+}
+
+// OutboxRelay публикует события из outbox в Kafka.
+// Публикует только инстанс, взявший advisory-блокировку, события идут строго по id,
+// ключ сообщения — ключ события, поэтому порядок внутри заказа сохраняется.
+// Гарантия at-least-once: событие помечается опубликованным после подтверждения брокера,
+// при падении между ними оно уйдёт повторно — потребители дедуплицируют по event-id.
+type OutboxRelay struct {
+	repo     outboxRepo.OutboxRepository
+	producer sarama.SyncProducer
+	cfg      OutboxRelayConfig
+	log      *zap.SugaredLogger
+	nowFunc  func() time.Time
+
+	lastCleanup time.Time
+}
+
+func NewOutboxRelay(
+	repo outboxRepo.OutboxRepository,
+	producer sarama.SyncProducer,
+	cfg OutboxRelayConfig,
+	log *zap.SugaredLogger,
+) *OutboxRelay {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = time.Second
+	}
+
+	return &OutboxRelay{
+		repo:     repo,
+		producer: producer,
+		cfg:      cfg,
+		log:      log,
+		nowFunc:  time.Now,
+	}
+}
+
+func (r *OutboxRelay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		// разбираем накопившееся, не дожидаясь следующего тика
+		for ctx.Err() == nil {
+			n, err := r.RelayOnce(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					metrics.OutboxPublishErrorsTotal.Inc()
+					r.log.Warnw("outbox relay failed", "err", err)
+				}
+				break
+			}
+			if n < r.cfg.BatchSize {
+				break
+			}
+		}
+
+		r.cleanup(ctx)
+	}
+}
+
+// RelayOnce публикует одну пачку событий и возвращает, сколько ушло в Kafka.
+// При ошибке отправки пачка обрывается: более поздние события того же ключа
+// не должны обогнать неотправленное.
+func (r *OutboxRelay) RelayOnce(ctx context.Context) (int, error) {
+	var (
+		published []int64
+		sendErr   error
+	)
+
+	err := r.repo.WithTx(ctx, func(txCtx context.Context) error {
+		locked, err := r.repo.TryLock(txCtx)
+		if err != nil || !locked {
+			return err
+		}
+
+		events, err := r.repo.FetchUnpublished(txCtx, r.cfg.BatchSize)
+		if err != nil {
+			return err
+		}
+
+		for _, e := range events {
+			msg, err := r.message(e)
+			if err != nil {
+				return err
+			}
+
+			if _, _, err := r.producer.SendMessage(msg); err != nil {
+				sendErr = err
+				break
+			}
+			published = append(published, e.ID)
+		}
+
+		return r.repo.MarkPublished(txCtx, published, r.nowFunc())
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	metrics.OutboxPublishedTotal.Add(float64(len(published)))
+	return len(published), sendErr
+}
+
+func (r *OutboxRelay) message(e *outboxModel.Event) (*sarama.ProducerMessage, error) {
+	body, err := json.Marshal(outboxModel.Envelope{
+		ID:         e.ID,
+		Type:       e.Type,
+		OccurredAt: e.CreatedAt,
+		Data:       e.Payload,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &sarama.ProducerMessage{
+		Topic: r.cfg.Topic,
+		Key:   sarama.StringEncoder(e.Key),
+		Value: sarama.ByteEncoder(body),
+		Headers: []sarama.RecordHeader{
+			header(HeaderEventID, strconv.FormatInt(e.ID, 10)),
+			header(HeaderEventType, e.Type),
+		},
+	}, nil
+}
+
+func (r *OutboxRelay) cleanup(ctx context.Context) {
+	now := r.nowFunc()
+	if r.cfg.Retention <= 0 || now.Sub(r.lastCleanup) < cleanupEvery {
+		return
+	}
+	r.lastCleanup = now
+
+	deleted, err := r.repo.DeletePublishedBefore(ctx, now.Add(-r.cfg.Retention))
+	if err != nil {
+		r.log.Warnw("outbox cleanup failed", "err", err)
+		return
+	}
+	if deleted > 0 {
+		r.log.Infow("outbox cleaned up", "deleted", deleted)
+	}
+}
@@ -0,0 +1,128 @@
+package worker_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/IBM/sarama/mocks"
+	"github.com/golang/mock/gomock"
+	"go.uber.org/zap"
+
+	outboxModel "github.com/Avito-courses/course-go-avito-israpilovsha/internal/outbox/model"
+	outboxRepo "github.com/Avito-courses/course-go-avito-israpilovsha/internal/outbox/repository"
+	"github.com/Avito-courses/course-go-avito-israpilovsha/internal/worker"
+)
+
+func outboxEvents(n int) []*outboxModel.Event {
+	events := make([]*outboxModel.Event, 0, n)
+	for i := 1; i <= n; i++ {
+		events = append(events, &outboxModel.Event{
+			ID:        int64(i),
+			Type:      outboxModel.EventDeliveryAssigned,
+			Key:       "order-1",
+			Payload:   json.RawMessage(`{"order_id":"order-1"}`),
+			CreatedAt: time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC),
+		})
+	}
+	return events
+}
+
+func TestOutboxRelayOnce(t *testing.T) {
+	tests := []struct {
+		name          string
+		locked        bool
+		events        int
+		sendErrAt     int // номер сообщения (с 1), на котором producer вернёт ошибку; 0 — без ошибок
+		wantPublished []int64
+		wantErr       bool
+	}{
+		{
+			name:          "publishes batch in order",
+			locked:        true,
+			events:        3,
+			wantPublished: []int64{1, 2, 3},
+		},
+		{
+			name:          "stops at first send error and marks sent ones",
+			locked:        true,
+			events:        3,
+			sendErrAt:     2,
+			wantPublished: []int64{1},
+			wantErr:       true,
+		},
+		{
+			name:   "another instance holds the lock",
+			locked: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			repo := outboxRepo.NewMockOutboxRepository(ctrl)
+			producer := mocks.NewSyncProducer(t, nil)
+
+			repo.EXPECT().WithTx(gomock.Any(), gomock.Any()).
+				DoAndReturn(func(ctx context.Context, fn func(context.Context) error) error {
+					return fn(ctx)
+				})
+			repo.EXPECT().TryLock(gomock.Any()).Return(tt.locked, nil)
+
+			if tt.locked {
+				repo.EXPECT().FetchUnpublished(gomock.Any(), 10).Return(outboxEvents(tt.events), nil)
+
+				for i := 1; i <= tt.events; i++ {
+					if tt.sendErrAt != 0 && i > tt.sendErrAt {
+						break
+					}
+					if i == tt.sendErrAt {
+						producer.ExpectSendMessageAndFail(errors.New("broker down"))
+						continue
+					}
+
+					wantID := int64(i)
+					producer.ExpectSendMessageWithMessageCheckerFunctionAndSucceed(
+						func(msg *sarama.ProducerMessage) error {
+							if msg.Topic != "courier-events" {
+								return errors.New("unexpected topic " + msg.Topic)
+							}
+							key, _ := msg.Key.Encode()
+							if string(key) != "order-1" {
+								return errors.New("unexpected key " + string(key))
+							}
+
+							value, _ := msg.Value.Encode()
+							var env outboxModel.Envelope
+							if err := json.Unmarshal(value, &env); err != nil {
+								return err
+							}
+							if env.ID != wantID || env.Type != outboxModel.EventDeliveryAssigned {
+								return errors.New("unexpected envelope " + string(value))
+							}
+							return nil
+						},
+					)
+				}
+
+				repo.EXPECT().MarkPublished(gomock.Any(), tt.wantPublished, gomock.Any()).Return(nil)
+			}
+
+			relay := worker.NewOutboxRelay(repo, producer, worker.OutboxRelayConfig{
+				Topic:     "courier-events",
+				BatchSize: 10,
+			}, zap.NewNop().Sugar())
+
+			n, err := relay.RelayOnce(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RelayOnce() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if n != len(tt.wantPublished) {
+				t.Fatalf("RelayOnce() published %d, want %d", n, len(tt.wantPublished))
+			}
+		})
+	}
+}
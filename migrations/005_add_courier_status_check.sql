@@ -0,0 +1,18 @@
+-- +goose Up
+-- до этой миграции status был свободным текстом: приводим значения к допустимым,
+-- неизвестные считаем offline
+UPDATE couriers
+SET status = lower(trim(status))
+WHERE status <> lower(trim(status));
+
+UPDATE couriers
+SET status = 'offline'
+WHERE status NOT IN ('available', 'busy', 'paused', 'offline', 'deactivated');
+
+ALTER TABLE couriers
+ADD CONSTRAINT couriers_status_check
+CHECK (status IN ('available', 'busy', 'paused', 'offline', 'deactivated'));
+
+-- +goose Down
+ALTER TABLE couriers
+DROP CONSTRAINT IF EXISTS couriers_status_check;
@@ -0,0 +1,73 @@
+-- +goose Up
+ALTER TABLE delivery
+    ADD COLUMN status TEXT NOT NULL DEFAULT 'assigned',
+    ADD COLUMN completed_at TIMESTAMP,
+    ADD COLUMN cancelled_at TIMESTAMP,
+    ADD COLUMN updated_at TIMESTAMP NOT NULL DEFAULT now();
+
+-- раньше строки delivery не удалялись ни при завершении, ни по дедлайну, поэтому
+-- активной считается только последняя доставка курьера, который сейчас busy.
+-- Остальные уже закрыты: просроченные — expired, прочие — delivered
+UPDATE delivery d
+SET status = CASE WHEN d.deadline < now() THEN 'expired' ELSE 'delivered' END,
+    completed_at = LEAST(d.deadline, now())
+WHERE d.id NOT IN (
+    SELECT DISTINCT ON (l.courier_id) l.id
+    FROM delivery l
+    JOIN couriers c ON c.id = l.courier_id
+    WHERE c.status = 'busy'
+    ORDER BY l.courier_id, l.assigned_at DESC, l.id DESC
+);
+
+ALTER TABLE delivery
+ADD CONSTRAINT delivery_status_check
+CHECK (status IN ('assigned', 'picked_up', 'delivered', 'cancelled', 'expired'));
+
+-- история хранится в delivery, поэтому уникален только активный заказ
+DROP INDEX IF EXISTS ux_delivery_order_id;
+
+CREATE UNIQUE INDEX IF NOT EXISTS ux_delivery_active_order_id
+ON delivery(order_id)
+WHERE status IN ('assigned', 'picked_up');
+
+CREATE INDEX IF NOT EXISTS ix_delivery_order_id
+ON delivery(order_id);
+
+-- ReleaseExpired смотрит только на активные доставки
+CREATE INDEX IF NOT EXISTS ix_delivery_active_deadline
+ON delivery(deadline)
+WHERE status IN ('assigned', 'picked_up');
+
+CREATE TABLE delivery_events (
+    id          BIGSERIAL PRIMARY KEY,
+    delivery_id BIGINT NOT NULL REFERENCES delivery(id),
+    order_id    VARCHAR(255) NOT NULL,
+    courier_id  BIGINT NOT NULL,
+    from_status TEXT,
+    to_status   TEXT NOT NULL,
+    reason      TEXT NOT NULL DEFAULT '',
+    created_at  TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS ix_delivery_events_delivery_id
+ON delivery_events(delivery_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS delivery_events;
+
+DROP INDEX IF EXISTS ix_delivery_active_deadline;
+DROP INDEX IF EXISTS ix_delivery_order_id;
+DROP INDEX IF EXISTS ux_delivery_active_order_id;
+
+DELETE FROM delivery
+WHERE status NOT IN ('assigned', 'picked_up');
+
+CREATE UNIQUE INDEX IF NOT EXISTS ux_delivery_order_id
+ON delivery(order_id);
+
+ALTER TABLE delivery
+    DROP CONSTRAINT IF EXISTS delivery_status_check,
+    DROP COLUMN updated_at,
+    DROP COLUMN cancelled_at,
+    DROP COLUMN completed_at,
+    DROP COLUMN status;
@@ -0,0 +1,13 @@
+-- +goose Up
+CREATE TABLE pending_orders (
+    order_id    VARCHAR(255) PRIMARY KEY,
+    priority    INT NOT NULL DEFAULT 0,
+    enqueued_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+-- порядок выдачи: сначала приоритетные, внутри приоритета FIFO
+CREATE INDEX IF NOT EXISTS ix_pending_orders_queue
+ON pending_orders(priority DESC, enqueued_at ASC);
+
+-- +goose Down
+DROP TABLE IF EXISTS pending_orders;
@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE pending_orders
+ADD COLUMN IF NOT EXISTS size TEXT NOT NULL DEFAULT 'medium';
+
+-- +goose Down
+ALTER TABLE pending_orders
+DROP COLUMN IF EXISTS size;
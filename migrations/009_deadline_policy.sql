@@ -0,0 +1,13 @@
+-- +goose Up
+-- политика дедлайнов хранится одной строкой, чтобы её можно было менять без деплоя
+CREATE TABLE deadline_policy (
+    id         INT PRIMARY KEY CHECK (id = 1),
+    policy     JSONB NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+INSERT INTO deadline_policy (id, policy)
+VALUES (1, '{"transports": {"car": "5m", "scooter": "15m"}, "default": "30m"}');
+
+-- +goose Down
+DROP TABLE IF EXISTS deadline_policy;
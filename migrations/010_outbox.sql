@@ -0,0 +1,21 @@
+-- +goose Up
+CREATE TABLE outbox (
+    id           BIGSERIAL PRIMARY KEY,
+    event_type   TEXT NOT NULL,
+    event_key    TEXT NOT NULL,
+    payload      JSONB NOT NULL,
+    created_at   TIMESTAMP NOT NULL DEFAULT now(),
+    published_at TIMESTAMP
+);
+
+-- релей читает только неопубликованные события в порядке id
+CREATE INDEX IF NOT EXISTS ix_outbox_unpublished
+ON outbox(id)
+WHERE published_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS ix_outbox_published_at
+ON outbox(published_at)
+WHERE published_at IS NOT NULL;
+
+-- +goose Down
+DROP TABLE IF EXISTS outbox;
@@ -0,0 +1,12 @@
+-- +goose Up
+-- ключи событий заказов, уже применённых consumer-ом: event_id или topic/partition/offset
+CREATE TABLE processed_events (
+    event_key    TEXT PRIMARY KEY,
+    processed_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS ix_processed_events_processed_at
+ON processed_events(processed_at);
+
+-- +goose Down
+DROP TABLE IF EXISTS processed_events;
@@ -0,0 +1,12 @@
+-- +goose Up
+-- последняя применённая версия заказа: события со старой версией отбрасываются.
+-- Номер версии и время изменения заказа хранятся раздельно и сравниваются каждое со своим
+CREATE TABLE order_versions (
+    order_id   VARCHAR(255) PRIMARY KEY,
+    version    BIGINT,
+    changed_at TIMESTAMPTZ,
+    updated_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS order_versions;
@@ -0,0 +1,11 @@
+-- +goose Up
+-- позиция опроса order-сервиса: переживает перезапуски сервиса
+CREATE TABLE order_fetch_cursor (
+    name       TEXT PRIMARY KEY,
+    created_at TIMESTAMPTZ NOT NULL,
+    order_ids  TEXT[] NOT NULL DEFAULT '{}',
+    updated_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS order_fetch_cursor;
@@ -0,0 +1,21 @@
+-- +goose Up
+-- параметры заказа нужны при переназначении просроченной доставки
+ALTER TABLE delivery
+ADD COLUMN IF NOT EXISTS priority INT NOT NULL DEFAULT 0,
+ADD COLUMN IF NOT EXISTS size TEXT NOT NULL DEFAULT 'medium',
+ADD COLUMN IF NOT EXISTS zone TEXT NOT NULL DEFAULT '';
+
+-- зона заказа в очереди и курьеры, которым заказ из очереди назначать нельзя
+ALTER TABLE pending_orders
+ADD COLUMN IF NOT EXISTS zone TEXT NOT NULL DEFAULT '',
+ADD COLUMN IF NOT EXISTS exclude_courier_ids BIGINT[] NOT NULL DEFAULT '{}';
+
+-- +goose Down
+ALTER TABLE pending_orders
+DROP COLUMN IF EXISTS exclude_courier_ids,
+DROP COLUMN IF EXISTS zone;
+
+ALTER TABLE delivery
+DROP COLUMN IF EXISTS zone,
+DROP COLUMN IF EXISTS size,
+DROP COLUMN IF EXISTS priority;